@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/etcdrestore"
+)
+
+var (
+	etcdRestoreCmd = &cobra.Command{
+		Use:   "etcdrestore",
+		Short: "Restores an etcd snapshot into a new data directory",
+		Long:  "Restores a snapshot created by etcdsnapshot into a new data directory to start a new member or cluster from",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoRestoreForKind(ctx, etcdrestore.Kind, cleanup, useRestic, force)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(etcdRestoreCmd)
+}
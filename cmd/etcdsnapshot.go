@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/etcdsnapshot"
+)
+
+var (
+	etcdSnapshotCmd = &cobra.Command{
+		Use:   "etcdsnapshot",
+		Short: "Saves a snapshot of your desired etcd member",
+		Long:  "Saves a snapshot of a given etcd member and verifies its hash, revision and key count",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoBackupForKind(ctx, etcdsnapshot.Kind, cleanup, useRestic, useResticForget, useResticPrune)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(etcdSnapshotCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/exec"
+)
+
+var (
+	execCmd = &cobra.Command{
+		Use:   "exec",
+		Short: "Backs up the output of your desired command",
+		Long:  "Runs a given command and backs up everything it writes to stdout",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoBackupForKind(ctx, exec.Kind, cleanup, useRestic, useResticForget, useResticPrune)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+}
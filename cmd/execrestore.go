@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/execrestore"
+)
+
+var (
+	execRestoreCmd = &cobra.Command{
+		Use:   "execrestore",
+		Short: "Restores a backup of exec by feeding it into your desired command",
+		Long:  "Passes a backup created by exec to the stdin of a given command",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoRestoreForKind(ctx, execrestore.Kind, cleanup, useRestic, force)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(execRestoreCmd)
+}
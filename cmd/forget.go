@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/mittwald/brudi/pkg/source"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	forgetKind   string
+	forgetDryRun bool
+	forgetOutput string
+
+	forgetCmd = &cobra.Command{
+		Use:   "forget",
+		Short: "Applies the restic forget policy and reports kept and removed snapshots",
+		Long: "Runs 'restic forget' with the configured policy and reports which snapshots are kept or removed per group, " +
+			"along with an estimate of the space 'restic prune' will reclaim",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoForgetForKind(ctx, forgetKind, forgetDryRun, useResticPrune, forgetOutput, os.Stdout)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	forgetCmd.Flags().StringVar(&forgetKind, "kind", "", "apply the forget policy of the given kind, scoped to its host and paths")
+	forgetCmd.Flags().BoolVar(&forgetDryRun, "dry-run", false, "only report which snapshots would be removed")
+	forgetCmd.Flags().StringVarP(&forgetOutput, "output", "o", source.ReportFormatTable, "report format, either 'table' or 'json'")
+
+	rootCmd.AddCommand(forgetCmd)
+}
@@ -13,7 +13,7 @@ var (
 	fsbackupCmd = &cobra.Command{
 		Use:   "fsbackup",
 		Short: "Backs up directories directly with restic",
-		Long:  "Backs up configured directories using restic without creating intermediate archives.",
+		Long:  "Backs up the configured paths into a single restic snapshot without creating intermediate archives.",
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
@@ -13,12 +13,12 @@ var (
 	fsrestoreCmd = &cobra.Command{
 		Use:   "fsrestore",
 		Short: "Restores directories directly from restic",
-		Long:  "Restores directories from restic snapshots without additional processing.",
+		Long:  "Restores directories from restic snapshots, optionally into a target directory which is mirrored and verified afterwards.",
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			if err := source.DoRestoreForKind(ctx, fsrestore.Kind, cleanup, useRestic); err != nil {
+			if err := source.DoRestoreForKind(ctx, fsrestore.Kind, cleanup, useRestic, force); err != nil {
 				panic(err)
 			}
 		},
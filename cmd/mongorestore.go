@@ -19,7 +19,7 @@ var (
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			err := source.DoRestoreForKind(ctx, mongorestore.Kind, cleanup, useRestic)
+			err := source.DoRestoreForKind(ctx, mongorestore.Kind, cleanup, useRestic, force)
 			if err != nil {
 				panic(err)
 			}
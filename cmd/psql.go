@@ -18,7 +18,7 @@ var (
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			err := source.DoRestoreForKind(ctx, psql.Kind, cleanup, useRestic)
+			err := source.DoRestoreForKind(ctx, psql.Kind, cleanup, useRestic, force)
 			if err != nil {
 				panic(err)
 			}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/redisrestore"
+)
+
+var (
+	redisRestoreCmd = &cobra.Command{
+		Use:   "redisrestore",
+		Short: "Restores a redis server from an rdb dump",
+		Long:  "Replays the keys of an rdb dump into a running server or places it into the data directory of a stopped one",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoRestoreForKind(ctx, redisrestore.Kind, cleanup, useRestic, force)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(redisRestoreCmd)
+}
@@ -20,6 +20,7 @@ var (
 	useResticForget bool
 	useResticPrune  bool
 	cleanup         bool
+	force           bool
 
 	rootCmd = &cobra.Command{
 		Use:   "brudi",
@@ -40,6 +41,8 @@ func init() {
 
 	rootCmd.PersistentFlags().BoolVar(&cleanup, "cleanup", false, "cleanup backup files afterwards")
 
+	rootCmd.PersistentFlags().BoolVar(&force, "force", false, "restore backups even if they don't match their manifest")
+
 	rootCmd.PersistentFlags().StringSliceVarP(&cfgFiles, "config", "c", []string{}, "config file (default is ${HOME}/.brudi.yaml)")
 }
 
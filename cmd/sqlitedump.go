@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/sqlitedump"
+)
+
+var (
+	sqliteDumpCmd = &cobra.Command{
+		Use:   "sqlitedump",
+		Short: "Creates a consistent copy or SQL export of your desired SQLite database",
+		Long:  "Backs up a live SQLite database with the online backup API, 'VACUUM INTO' or '.dump'",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoBackupForKind(ctx, sqlitedump.Kind, cleanup, useRestic, useResticForget, useResticPrune)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(sqliteDumpCmd)
+}
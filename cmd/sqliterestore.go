@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/sqliterestore"
+)
+
+var (
+	sqliteRestoreCmd = &cobra.Command{
+		Use:   "sqliterestore",
+		Short: "Restores a SQLite database from a sqlitedump",
+		Long:  "Replaces a SQLite database with a copy or SQL export created by sqlitedump",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := source.DoRestoreForKind(ctx, sqliterestore.Kind, cleanup, useRestic, force)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(sqliteRestoreCmd)
+}
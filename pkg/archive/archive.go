@@ -0,0 +1,72 @@
+// Package archive creates and extracts tar archives without depending on the flavour of the system's tar
+package archive
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsafePath is returned for members which would be extracted outside of the target directory
+var ErrUnsafePath = errors.New("unsafe path in archive")
+
+// xattrPrefix of the PAX records holding extended attributes, POSIX ACLs are stored as extended attributes as well
+const xattrPrefix = "SCHILY.xattr."
+
+// MemberName returns the name a path is archived as, which is the cleaned path without leading "/" and "../",
+// like GNU tar names its members
+func MemberName(fsPath string) string {
+	name := strings.TrimLeft(path.Clean(strings.ReplaceAll(fsPath, "\\", "/")), "/")
+	for name == ".." || strings.HasPrefix(name, "../") {
+		name = strings.TrimLeft(strings.TrimPrefix(name, ".."), "/")
+	}
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+// Excluded returns whether the given member name matches one of the glob patterns. Like GNU tar, a pattern matches
+// the whole name or any of its trailing parts, e.g. "*.log" matches "var/log/app.log" and "cache" matches "srv/cache".
+func Excluded(name string, patterns []string) bool {
+	components := strings.Split(strings.Trim(name, "/"), "/")
+	for _, pattern := range patterns {
+		pattern = strings.Trim(pattern, "/")
+		for i := range components {
+			if matched, err := path.Match(pattern, strings.Join(components[i:], "/")); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// safeName returns the cleaned name of a member relative to the target directory, or ErrUnsafePath
+// if the name is absolute or contains ".." components
+func safeName(name string) (string, error) {
+	if path.IsAbs(name) || strings.HasPrefix(name, "\\") {
+		return "", errors.WithStack(fmt.Errorf("%w: absolute path %q", ErrUnsafePath, name))
+	}
+	for _, component := range strings.Split(name, "/") {
+		if component == ".." {
+			return "", errors.WithStack(fmt.Errorf("%w: path traversal in %q", ErrUnsafePath, name))
+		}
+	}
+	return path.Clean(name), nil
+}
+
+// stripComponents removes the given number of leading components from the name, it returns false if the name
+// has no components left
+func stripComponents(name string, count int) (string, bool) {
+	name = strings.Trim(name, "/")
+	for i := 0; i < count; i++ {
+		idx := strings.Index(name, "/")
+		if idx < 0 {
+			return "", false
+		}
+		name = strings.TrimLeft(name[idx+1:], "/")
+	}
+	return name, name != ""
+}
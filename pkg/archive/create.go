@@ -0,0 +1,248 @@
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/compress"
+)
+
+// CreateOptions of an archive
+type CreateOptions struct {
+	// Dir relative paths are resolved against, the working directory if empty
+	Dir string
+	// Exclude holds glob patterns of members to leave out, see Excluded
+	Exclude []string
+	// Previous holds the files of the previous archive of an incremental chain. Files whose state didn't change
+	// are left out and the members deleted since then are recorded in the archive. All files are archived if nil.
+	Previous map[string]FileState
+	// State is filled with the state of the archived files, if it isn't nil
+	State map[string]FileState
+}
+
+// fileID identifies a file across its hardlinks
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+type archiver struct {
+	ctx  context.Context
+	w    io.Writer
+	tw   *tar.Writer
+	opts *CreateOptions
+	// links maps files with multiple hardlinks to the name they were archived as first
+	links map[fileID]string
+}
+
+// Create writes a tar archive of the given paths into w, their members are named by MemberName.
+// Symlinks and hardlinks, extended attributes including POSIX ACLs, and the holes of sparse files are preserved.
+func Create(ctx context.Context, w io.Writer, paths []string, opts *CreateOptions) error {
+	a := &archiver{
+		ctx:   ctx,
+		w:     w,
+		tw:    tar.NewWriter(w),
+		opts:  opts,
+		links: map[fileID]string{},
+	}
+
+	for _, fsPath := range paths {
+		if err := a.addPath(fsPath); err != nil {
+			return err
+		}
+	}
+
+	if err := a.writeDeleted(); err != nil {
+		return err
+	}
+	return errors.WithStack(a.tw.Close())
+}
+
+// addPath adds the given path and everything below it
+func (a *archiver) addPath(fsPath string) error {
+	root := fsPath
+	if a.opts.Dir != "" && !filepath.IsAbs(fsPath) {
+		root = filepath.Join(a.opts.Dir, fsPath)
+	}
+	prefix := MemberName(fsPath)
+
+	return filepath.WalkDir(root, func(walkPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err = a.ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		rel, err := filepath.Rel(root, walkPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		if name == "." {
+			// the working directory itself has no name
+			return nil
+		}
+		if Excluded(name, a.opts.Exclude) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return a.add(walkPath, name)
+	})
+}
+
+// add writes a single member
+func (a *archiver) add(fsPath, name string) error {
+	info, err := os.Lstat(fsPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if info.Mode()&os.ModeSocket != 0 {
+		log.WithField("path", fsPath).Warn("skipping socket, it can't be archived")
+		return nil
+	}
+
+	var linkTarget string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if linkTarget, err = os.Readlink(fsPath); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	state := fileState(info)
+	if a.opts.State != nil {
+		a.opts.State[name] = state
+	}
+	// directories are always archived, they are created before their changed content
+	if previous, ok := a.opts.Previous[name]; ok && previous == state && !info.IsDir() {
+		return nil
+	}
+
+	hdr, err := tar.FileInfoHeader(info, linkTarget)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	hdr.Format = tar.FormatPAX
+	hdr.PAXRecords = map[string]string{}
+
+	xattrs, err := listXattrs(fsPath)
+	if err != nil {
+		return err
+	}
+	for attr, value := range xattrs {
+		hdr.PAXRecords[xattrPrefix+attr] = value
+	}
+
+	if !info.Mode().IsRegular() {
+		return errors.WithStack(a.tw.WriteHeader(hdr))
+	}
+
+	if firstName, ok := a.hardlink(info, name); ok {
+		hdr.Typeflag = tar.TypeLink
+		hdr.Linkname = firstName
+		hdr.Size = 0
+		return errors.WithStack(a.tw.WriteHeader(hdr))
+	}
+
+	return a.addFile(fsPath, hdr)
+}
+
+// writeDeleted records the members of the previous archive which don't exist anymore
+// in a global header, thus they are deleted when the chain is extracted
+func (a *archiver) writeDeleted() error {
+	if a.opts.Previous == nil {
+		return nil
+	}
+
+	deleted := []string{}
+	for name := range a.opts.Previous {
+		if _, exists := a.opts.State[name]; !exists {
+			deleted = append(deleted, name)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	sort.Strings(deleted)
+
+	data, err := json.Marshal(deleted)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(a.tw.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeXGlobalHeader,
+		Format:     tar.FormatPAX,
+		PAXRecords: map[string]string{deletedRecord: string(data)},
+	}))
+}
+
+// hardlink returns the name a file was archived as before, if it has multiple hardlinks.
+// Otherwise, the given name is remembered for its other hardlinks.
+func (a *archiver) hardlink(info os.FileInfo, name string) (string, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return "", false
+	}
+
+	//nolint:unconvert // the types of the fields differ between platforms
+	id := fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+	if firstName, seen := a.links[id]; seen {
+		return firstName, true
+	}
+	a.links[id] = name
+	return "", false
+}
+
+// addFile writes a regular file, the data of sparse files is written without their holes
+func (a *archiver) addFile(fsPath string, hdr *tar.Header) error {
+	file, err := os.Open(fsPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.WithError(closeErr).Errorf("failed to close %s", fsPath)
+		}
+	}()
+
+	segments, err := dataSegments(file, hdr.Size)
+	if err != nil {
+		return err
+	}
+	if segments != nil {
+		return a.writeSparse(file, hdr, segments)
+	}
+
+	if err = a.tw.WriteHeader(hdr); err != nil {
+		return errors.WithStack(err)
+	}
+	return a.copyData(a.tw, file, hdr.Size, fsPath)
+}
+
+// copyData copies size bytes of the file into w, it fails if the file shrank while it was read
+func (a *archiver) copyData(w io.Writer, file io.Reader, size int64, fsPath string) error {
+	_, err := io.CopyN(w, compress.NewContextReader(a.ctx, file), size)
+	if errors.Is(err, io.EOF) {
+		return errors.WithStack(fmt.Errorf("%s shrank while it was read", fsPath))
+	}
+	return errors.WithStack(err)
+}
@@ -0,0 +1,298 @@
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/compress"
+)
+
+// ExtractOptions of an archive
+type ExtractOptions struct {
+	// Target directory the members are extracted into, the working directory if empty
+	Target string
+	// StripComponents removes the given number of leading components from the member names, members without
+	// components left are skipped
+	StripComponents int
+	// Exclude holds glob patterns of members to skip, see Excluded
+	Exclude []string
+	// Members to extract including everything below them, all if empty
+	Members []string
+	// KeepOldFiles skips members which already exist instead of replacing them
+	KeepOldFiles bool
+}
+
+type extractor struct {
+	ctx  context.Context
+	opts *ExtractOptions
+	// dirs are extracted before their content, thus their mode and times are set last
+	dirs []*tar.Header
+}
+
+// Extract extracts the tar archive read from r. Members whose names are absolute, contain ".." or would be written
+// through a symlink fail the extraction with ErrUnsafePath. Owners are restored when running as root.
+func Extract(ctx context.Context, r io.Reader, opts *ExtractOptions) error {
+	e := &extractor{ctx: ctx, opts: opts}
+	if e.opts.Target == "" {
+		e.opts.Target = "."
+	}
+	tr := tar.NewReader(compress.NewContextReader(ctx, r))
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			if err = e.removeDeleted(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+		if !e.selected(hdr.Name) {
+			continue
+		}
+		name, err := safeName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		name, ok := stripComponents(name, e.opts.StripComponents)
+		if !ok {
+			continue
+		}
+
+		if err = e.extract(tr, hdr, name); err != nil {
+			return err
+		}
+	}
+
+	// the innermost directories come last, their times would be changed by setting those of their parents first
+	for i := len(e.dirs) - 1; i >= 0; i-- {
+		if err := e.setAttributes(e.dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDeleted removes the members an incremental archive records as deleted since the previous archive of its chain
+func (e *extractor) removeDeleted(hdr *tar.Header) error {
+	record, ok := hdr.PAXRecords[deletedRecord]
+	if !ok {
+		return nil
+	}
+	var deleted []string
+	if err := json.Unmarshal([]byte(record), &deleted); err != nil {
+		return errors.WithStack(fmt.Errorf("invalid list of deleted members: %w", err))
+	}
+
+	for _, member := range deleted {
+		if !e.selected(member) {
+			continue
+		}
+		name, err := safeName(member)
+		if err != nil {
+			return err
+		}
+		name, ok := stripComponents(name, e.opts.StripComponents)
+		if !ok {
+			continue
+		}
+		fsPath, err := e.path(name)
+		if err != nil {
+			return err
+		}
+
+		log.WithField("path", fsPath).Debug("removing deleted member")
+		if err = os.RemoveAll(fsPath); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// selected returns whether the member is one of the requested members and not excluded
+func (e *extractor) selected(name string) bool {
+	if Excluded(name, e.opts.Exclude) {
+		return false
+	}
+	if len(e.opts.Members) == 0 {
+		return true
+	}
+
+	name = strings.Trim(name, "/")
+	for _, member := range e.opts.Members {
+		member = strings.Trim(member, "/")
+		if name == member || strings.HasPrefix(name, member+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// extract writes a single member
+func (e *extractor) extract(tr *tar.Reader, hdr *tar.Header, name string) error {
+	fsPath, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	hdr.Name = fsPath
+
+	if hdr.Typeflag == tar.TypeDir {
+		return e.extractDir(hdr)
+	}
+
+	existing, err := os.Lstat(fsPath)
+	switch {
+	case err == nil && e.opts.KeepOldFiles:
+		log.WithField("path", fsPath).Debug("keeping existing file")
+		return nil
+	case err == nil && existing.IsDir():
+		return errors.WithStack(fmt.Errorf("unable to replace directory %s by %s", fsPath, name))
+	case err == nil:
+		if err = os.Remove(fsPath); err != nil {
+			return errors.WithStack(err)
+		}
+	case !os.IsNotExist(err):
+		return errors.WithStack(err)
+	}
+	if err = os.MkdirAll(filepath.Dir(fsPath), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeGNUSparse:
+		err = e.extractFile(tr, hdr)
+	case tar.TypeSymlink:
+		err = errors.WithStack(os.Symlink(hdr.Linkname, fsPath))
+	case tar.TypeLink:
+		err = e.extractHardlink(hdr)
+		// hardlinks share the attributes of their target
+		return err
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		err = mknod(fsPath, hdr)
+	default:
+		log.WithFields(log.Fields{"path": fsPath, "type": string(hdr.Typeflag)}).Warn("skipping member of unsupported type")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.setAttributes(hdr)
+}
+
+// path returns the path of the member below the target directory, it fails if an existing parent is no directory
+func (e *extractor) path(name string) (string, error) {
+	fsPath := e.opts.Target
+	components := strings.Split(name, "/")
+	for _, component := range components[:len(components)-1] {
+		fsPath = filepath.Join(fsPath, component)
+		info, err := os.Lstat(fsPath)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if !info.IsDir() {
+			return "", errors.WithStack(fmt.Errorf("%w: %s of %s is no directory", ErrUnsafePath, fsPath, name))
+		}
+	}
+	return filepath.Join(e.opts.Target, name), nil
+}
+
+func (e *extractor) extractDir(hdr *tar.Header) error {
+	info, err := os.Lstat(hdr.Name)
+	if err == nil && !info.IsDir() {
+		if err = os.Remove(hdr.Name); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err = os.MkdirAll(hdr.Name, 0o700); err != nil {
+		return errors.WithStack(err)
+	}
+	e.dirs = append(e.dirs, hdr)
+	return nil
+}
+
+func (e *extractor) extractFile(tr *tar.Reader, hdr *tar.Header) error {
+	file, err := os.OpenFile(hdr.Name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if isSparse(hdr) {
+		err = writeSparseData(file, tr, hdr.Size)
+	} else {
+		_, err = io.Copy(file, tr)
+		err = errors.WithStack(err)
+	}
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = errors.WithStack(closeErr)
+	}
+	return err
+}
+
+func (e *extractor) extractHardlink(hdr *tar.Header) error {
+	name, err := safeName(hdr.Linkname)
+	if err != nil {
+		return err
+	}
+	name, ok := stripComponents(name, e.opts.StripComponents)
+	if !ok {
+		return errors.WithStack(fmt.Errorf("target %s of hardlink %s was stripped", hdr.Linkname, hdr.Name))
+	}
+	target, err := e.path(name)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(os.Link(target, hdr.Name))
+}
+
+// setAttributes restores the owner, mode, extended attributes and times of an extracted member
+func (e *extractor) setAttributes(hdr *tar.Header) error {
+	isSymlink := hdr.Typeflag == tar.TypeSymlink
+
+	// the owner is restored first, changing it clears the setuid and setgid bits
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(hdr.Name, hdr.Uid, hdr.Gid); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if !isSymlink {
+		if err := os.Chmod(hdr.Name, hdr.FileInfo().Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for key, value := range hdr.PAXRecords {
+		if !strings.HasPrefix(key, xattrPrefix) {
+			continue
+		}
+		attr := strings.TrimPrefix(key, xattrPrefix)
+		if err := setXattr(hdr.Name, attr, value); err != nil {
+			// e.g. trusted and security attributes require privileges
+			log.WithError(err).WithFields(log.Fields{"path": hdr.Name, "attr": attr}).Warn("failed to restore extended attribute")
+		}
+	}
+
+	atime := hdr.AccessTime
+	if atime.IsZero() {
+		atime = hdr.ModTime
+	}
+	return lchtimes(hdr.Name, atime, hdr.ModTime)
+}
@@ -0,0 +1,154 @@
+//go:build linux
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs returns the extended attributes of the path, symlinks are not followed
+func listXattrs(fsPath string) (map[string]string, error) {
+	names, err := readXattr(func(dest []byte) (int, error) { return unix.Llistxattr(fsPath, dest) })
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	xattrs := map[string]string{}
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		value, getErr := readXattr(func(dest []byte) (int, error) { return unix.Lgetxattr(fsPath, attr, dest) })
+		if errors.Is(getErr, unix.ENODATA) {
+			// removed in the meantime
+			continue
+		}
+		if getErr != nil {
+			return nil, errors.WithStack(getErr)
+		}
+		xattrs[attr] = string(value)
+	}
+	return xattrs, nil
+}
+
+// readXattr calls read with a buffer large enough for the result, whose size is queried first
+func readXattr(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := read(nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		size, err = read(buf)
+		if errors.Is(err, unix.ERANGE) {
+			// grew in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	}
+}
+
+// setXattr sets an extended attribute, symlinks are not followed
+func setXattr(fsPath, attr, value string) error {
+	return errors.WithStack(unix.Lsetxattr(fsPath, attr, []byte(value), 0))
+}
+
+// dataSegments returns the segments of a sparse file which contain data, or nil if the file has no holes
+func dataSegments(file *os.File, size int64) ([]segment, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Blocks*512 >= size {
+		return nil, nil
+	}
+
+	var segments []segment
+	var offset int64
+	for offset < size {
+		data, seekErr := file.Seek(offset, unix.SEEK_DATA)
+		if errors.Is(seekErr, unix.ENXIO) {
+			// only a hole is left
+			break
+		}
+		if errors.Is(seekErr, unix.EINVAL) {
+			// the file system doesn't report holes
+			return nil, nil
+		}
+		if seekErr != nil {
+			return nil, errors.WithStack(seekErr)
+		}
+		hole, seekErr := file.Seek(data, unix.SEEK_HOLE)
+		if seekErr != nil {
+			return nil, errors.WithStack(seekErr)
+		}
+		if hole > size {
+			hole = size
+		}
+		if data >= hole {
+			break
+		}
+		segments = append(segments, segment{offset: data, length: hole - data})
+		offset = hole
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// a trailing hole is marked by an empty segment at the end, like GNU tar does it
+	if len(segments) == 0 || segments[len(segments)-1].offset+segments[len(segments)-1].length < size {
+		segments = append(segments, segment{offset: size})
+	}
+	return segments, nil
+}
+
+// mknod creates a device or fifo
+func mknod(fsPath string, hdr *tar.Header) error {
+	mode := uint32(hdr.Mode & 07777)
+	switch hdr.Typeflag {
+	case tar.TypeChar:
+		mode |= unix.S_IFCHR
+	case tar.TypeBlock:
+		mode |= unix.S_IFBLK
+	case tar.TypeFifo:
+		mode |= unix.S_IFIFO
+	}
+	dev := int(unix.Mkdev(uint32(hdr.Devmajor), uint32(hdr.Devminor)))
+	return errors.WithStack(unix.Mknod(fsPath, mode, dev))
+}
+
+// lchtimes sets the access and modification time, symlinks are not followed
+func lchtimes(fsPath string, atime, mtime time.Time) error {
+	times := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(mtime.UnixNano())}
+	return errors.WithStack(unix.UtimesNanoAt(unix.AT_FDCWD, fsPath, times, unix.AT_SYMLINK_NOFOLLOW))
+}
+
+// fileState returns the state of a file, which changes with its content, mode, owner, or extended attributes
+func fileState(info os.FileInfo) FileState {
+	state := FileState{
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		Mode:    uint32(info.Mode()),
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		state.ChangeTime = stat.Ctim.Nano()
+		state.Inode = stat.Ino
+	}
+	return state
+}
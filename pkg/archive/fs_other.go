@@ -0,0 +1,50 @@
+//go:build !linux
+
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// listXattrs is only supported on linux
+func listXattrs(_ string) (map[string]string, error) {
+	return nil, nil
+}
+
+// setXattr is only supported on linux
+func setXattr(fsPath, attr, _ string) error {
+	return errors.WithStack(fmt.Errorf("unable to set %s of %s: extended attributes are only supported on linux", attr, fsPath))
+}
+
+// dataSegments is only supported on linux, sparse files are archived with their holes as zeros elsewhere
+func dataSegments(_ *os.File, _ int64) ([]segment, error) {
+	return nil, nil
+}
+
+// mknod is only supported on linux
+func mknod(fsPath string, _ *tar.Header) error {
+	return errors.WithStack(fmt.Errorf("unable to create %s: devices and fifos are only extracted on linux", fsPath))
+}
+
+// lchtimes sets the access and modification time, the times of symlinks are kept
+func lchtimes(fsPath string, atime, mtime time.Time) error {
+	info, err := os.Lstat(fsPath)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Chtimes(fsPath, atime, mtime))
+}
+
+// fileState returns the state of a file, the change time and inode are only considered on linux
+func fileState(info os.FileInfo) FileState {
+	return FileState{
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		Mode:    uint32(info.Mode()),
+	}
+}
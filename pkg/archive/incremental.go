@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/compress"
+)
+
+const (
+	// deletedRecord of the global header of an incremental archive holds the json list of members
+	// which were deleted since the previous archive of the chain
+	deletedRecord = "BRUDI.deleted"
+	// chainTimeFormat of the id of a chain, which is the time it was started
+	chainTimeFormat = "20060102T150405Z"
+	tarSuffix       = ".tar"
+)
+
+// FileState identifies the version of an archived file, files whose state didn't change since the previous
+// archive of an incremental chain are left out
+type FileState struct {
+	ModTime    int64  `json:"mtime"`
+	ChangeTime int64  `json:"ctime"`
+	Size       int64  `json:"size"`
+	Inode      uint64 `json:"inode"`
+	Mode       uint32 `json:"mode"`
+}
+
+// Snapshot is the state of an incremental chain after its latest archive, like the snapshot file of
+// "tar --listed-incremental"
+type Snapshot struct {
+	// Chain is the id of the chain, which is the time its full archive was created
+	Chain   string    `json:"chain"`
+	Started time.Time `json:"started"`
+	// Level of the latest archive, the full archive has level 0
+	Level int                  `json:"level"`
+	Files map[string]FileState `json:"files"`
+}
+
+// NewChain returns the snapshot of a new chain started at the given time, before its full archive is created
+func NewChain(started time.Time) *Snapshot {
+	started = started.UTC().Truncate(time.Second)
+	return &Snapshot{
+		Chain:   started.Format(chainTimeFormat),
+		Started: started,
+		Level:   -1,
+		Files:   map[string]FileState{},
+	}
+}
+
+// ReadSnapshot reads the snapshot file, it returns nil if it doesn't exist
+func ReadSnapshot(fileName string) (*Snapshot, error) {
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	snapshot := &Snapshot{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		return nil, errors.WithStack(fmt.Errorf("invalid snapshot file %s: %w", fileName, err))
+	}
+	if snapshot.Files == nil {
+		snapshot.Files = map[string]FileState{}
+	}
+	return snapshot, nil
+}
+
+// Write writes the snapshot file atomically
+func (s *Snapshot) Write(fileName string) error {
+	return compress.WriteFileAtomic(fileName, func(w io.Writer) error {
+		return errors.WithStack(json.NewEncoder(w).Encode(s))
+	})
+}
+
+// IncrementalArchive of a chain
+type IncrementalArchive struct {
+	Path  string
+	Chain string
+	Level int
+}
+
+// IncrementalName returns the name of the archive of the given chain and level, which is the given name
+// with chain and level inserted before ".tar", e.g. "app.20261019T080000Z.002.tar.gz" for "app.tar.gz"
+func IncrementalName(fileName, chain string, level int) string {
+	stem, suffix := splitTarSuffix(fileName)
+	return fmt.Sprintf("%s.%s.%03d%s", stem, chain, level, suffix)
+}
+
+// IncrementalArchives returns the archives of all chains of the given name, ordered by chain and level
+func IncrementalArchives(fileName string) ([]IncrementalArchive, error) {
+	stem, suffix := splitTarSuffix(fileName)
+	matches, err := filepath.Glob(escapeGlob(stem) + ".*.*" + escapeGlob(suffix))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var archives []IncrementalArchive
+	for _, match := range matches {
+		parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(match, stem+"."), suffix), ".")
+		if len(parts) != 2 {
+			continue
+		}
+		if _, parseErr := time.Parse(chainTimeFormat, parts[0]); parseErr != nil {
+			continue
+		}
+		level, parseErr := strconv.Atoi(parts[1])
+		if parseErr != nil {
+			continue
+		}
+		archives = append(archives, IncrementalArchive{Path: match, Chain: parts[0], Level: level})
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		if archives[i].Chain != archives[j].Chain {
+			return archives[i].Chain < archives[j].Chain
+		}
+		return archives[i].Level < archives[j].Level
+	})
+	return archives, nil
+}
+
+// splitTarSuffix splits the name before its last ".tar", or before the suffixes of compression and encryption
+func splitTarSuffix(fileName string) (stem, suffix string) {
+	base := filepath.Base(fileName)
+	idx := strings.LastIndex(base, tarSuffix)
+	if idx <= 0 {
+		idx = strings.Index(base[1:], ".") + 1
+		if idx <= 0 {
+			idx = len(base)
+		}
+	}
+	cut := len(fileName) - len(base) + idx
+	return fileName[:cut], fileName[cut:]
+}
+
+// escapeGlob escapes the meta characters of filepath.Match
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(s)
+}
@@ -0,0 +1,208 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	blockSize = 512
+	// max values of the octal fields of a ustar header, larger values are stored in PAX records only
+	maxOctal7  = 1<<21 - 1
+	maxOctal11 = 1<<33 - 1
+	// holeBlockSize is the size of the zero blocks which are skipped instead of written when extracting sparse files
+	holeBlockSize = 4096
+)
+
+// segment of a sparse file which contains data
+type segment struct {
+	offset int64
+	length int64
+}
+
+// writeSparse writes a sparse file in the PAX format 1.0 of GNU tar, whose data is preceded by a map of the data
+// segments. archive/tar reads those archives, but doesn't write them, thus the headers are encoded here.
+func (a *archiver) writeSparse(file *os.File, hdr *tar.Header, segments []segment) error {
+	var sparseMap bytes.Buffer
+	var dataSize int64
+	fmt.Fprintf(&sparseMap, "%d\n", len(segments))
+	for _, s := range segments {
+		fmt.Fprintf(&sparseMap, "%d\n%d\n", s.offset, s.length)
+		dataSize += s.length
+	}
+	sparseMap.Write(padding(int64(sparseMap.Len())))
+
+	records := map[string]string{
+		"GNU.sparse.major":    "1",
+		"GNU.sparse.minor":    "0",
+		"GNU.sparse.name":     hdr.Name,
+		"GNU.sparse.realsize": strconv.FormatInt(hdr.Size, 10),
+		"size":                strconv.FormatInt(int64(sparseMap.Len())+dataSize, 10),
+		"mtime":               formatPAXTime(hdr.ModTime.UnixNano()),
+		"uid":                 strconv.Itoa(hdr.Uid),
+		"gid":                 strconv.Itoa(hdr.Gid),
+	}
+	if hdr.Uname != "" {
+		records["uname"] = hdr.Uname
+	}
+	if hdr.Gname != "" {
+		records["gname"] = hdr.Gname
+	}
+	for key, value := range hdr.PAXRecords {
+		records[key] = value
+	}
+
+	// the member name is a placeholder for readers which don't know the sparse format
+	sparseName := path.Join(path.Dir(hdr.Name), "GNUSparseFile.0", path.Base(hdr.Name))
+	// the headers are written directly, thus the previous member has to be padded first
+	if err := a.tw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	paxData := encodePAXRecords(records)
+	paxHdr := &tar.Header{Name: path.Join(path.Dir(hdr.Name), "PaxHeaders.0", path.Base(hdr.Name)),
+		Typeflag: tar.TypeXHeader, Size: int64(len(paxData)), Mode: 0o644, ModTime: hdr.ModTime}
+	fileHdr := *hdr
+	fileHdr.Name = sparseName
+	fileHdr.Size = int64(sparseMap.Len()) + dataSize
+
+	for _, block := range [][]byte{encodeUSTARHeader(paxHdr), paxData, padding(int64(len(paxData))),
+		encodeUSTARHeader(&fileHdr), sparseMap.Bytes()} {
+		if _, err := a.w.Write(block); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, s := range segments {
+		if _, err := file.Seek(s.offset, io.SeekStart); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := a.copyData(a.w, file, s.length, file.Name()); err != nil {
+			return err
+		}
+	}
+	_, err := a.w.Write(padding(dataSize))
+	return errors.WithStack(err)
+}
+
+// padding returns the zeros filling up the last block of data of the given size
+func padding(size int64) []byte {
+	return make([]byte, (blockSize-size%blockSize)%blockSize)
+}
+
+// formatPAXTime formats a timestamp with nanoseconds as PAX record
+func formatPAXTime(nanos int64) string {
+	secs, frac := nanos/1e9, nanos%1e9
+	if frac < 0 {
+		secs--
+		frac += 1e9
+	}
+	return fmt.Sprintf("%d.%09d", secs, frac)
+}
+
+// encodePAXRecords encodes the records as "<length> <key>=<value>\n", whose length includes itself
+func encodePAXRecords(records map[string]string) []byte {
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		record := " " + key + "=" + records[key] + "\n"
+		size := len(record)
+		for {
+			encoded := strconv.Itoa(size) + record
+			if len(encoded) == size {
+				buf.WriteString(encoded)
+				break
+			}
+			size = len(encoded)
+		}
+	}
+	return buf.Bytes()
+}
+
+// encodeUSTARHeader encodes the header block of a member, values which don't fit are left to the PAX records
+func encodeUSTARHeader(hdr *tar.Header) []byte {
+	block := make([]byte, blockSize)
+	copy(block[0:100], truncate(hdr.Name, 100))
+	putOctal(block[100:108], hdr.Mode&07777, maxOctal7)
+	putOctal(block[108:116], int64(hdr.Uid), maxOctal7)
+	putOctal(block[116:124], int64(hdr.Gid), maxOctal7)
+	putOctal(block[124:136], hdr.Size, maxOctal11)
+	putOctal(block[136:148], hdr.ModTime.Unix(), maxOctal11)
+	block[156] = hdr.Typeflag
+	copy(block[157:257], truncate(hdr.Linkname, 100))
+	copy(block[257:263], "ustar\x00")
+	copy(block[263:265], "00")
+	copy(block[265:297], truncate(hdr.Uname, 32))
+	copy(block[297:329], truncate(hdr.Gname, 32))
+	putOctal(block[329:337], hdr.Devmajor, maxOctal7)
+	putOctal(block[337:345], hdr.Devminor, maxOctal7)
+
+	// the checksum is calculated with the checksum field set to spaces
+	copy(block[148:156], "        ")
+	var sum int64
+	for _, b := range block {
+		sum += int64(b)
+	}
+	copy(block[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	return block
+}
+
+// putOctal writes a NUL-terminated octal number into the field, or zero if it doesn't fit
+func putOctal(field []byte, value, maxValue int64) {
+	if value < 0 || value > maxValue {
+		value = 0
+	}
+	copy(field, fmt.Sprintf("%0*o\x00", len(field)-1, value))
+}
+
+func truncate(s string, size int) string {
+	if len(s) > size {
+		return s[:size]
+	}
+	return s
+}
+
+// isSparse returns whether the member was archived as sparse file
+func isSparse(hdr *tar.Header) bool {
+	_, ok := hdr.PAXRecords["GNU.sparse.major"]
+	return ok || hdr.Typeflag == tar.TypeGNUSparse
+}
+
+// writeSparseData writes the data of a sparse file, blocks of zeros are skipped to recreate the holes
+func writeSparseData(file *os.File, r io.Reader, size int64) error {
+	buf := make([]byte, holeBlockSize)
+	zeros := make([]byte, holeBlockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zeros[:n]) {
+				if _, seekErr := file.Seek(int64(n), io.SeekCurrent); seekErr != nil {
+					return errors.WithStack(seekErr)
+				}
+			} else if _, writeErr := file.Write(buf[:n]); writeErr != nil {
+				return errors.WithStack(writeErr)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// a trailing hole only extends the file by truncating it
+	return errors.WithStack(file.Truncate(size))
+}
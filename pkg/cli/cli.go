@@ -2,23 +2,31 @@ package cli
 
 import (
 	"bufio"
-	"compress/gzip"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"reflect"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/compress"
+	"github.com/mittwald/brudi/pkg/dumpfile"
 )
 
 const flagTag = "flag"
-const gzipType = "application/x-gzip"
+
+// decompressedSuffix is appended to the name of extracted files if their name can't be derived from the archive's name
+const decompressedSuffix = ".decompressed"
+
+// maxLineSize limits the length of a single line handled by RunWithLineHandler
+const maxLineSize = 1024 * 1024
 
 // includeFlag returns an string slice of [<flag>, <val>], or [<val>]
 func includeFlag(flag, val string) []string {
@@ -187,6 +195,16 @@ func ParseCommandLine(cmd CommandType) []string {
 	return commandLine
 }
 
+// newExecCommand creates the exec.Cmd of the given command line, which inherits the environment of brudi
+// extended by the environment of the command
+func newExecCommand(ctx context.Context, cmd CommandType, commandLine []string) *exec.Cmd {
+	execCmd := exec.CommandContext(ctx, commandLine[0], commandLine[1:]...) //nolint: gosec
+	if len(cmd.Env) > 0 {
+		execCmd.Env = append(os.Environ(), cmd.Env...)
+	}
+	return execCmd
+}
+
 // RunWithTimeout executes the given binary within a max execution time
 func RunWithTimeout(runContext context.Context, cmd CommandType, timeout time.Duration) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(runContext, timeout)
@@ -202,149 +220,185 @@ func Run(ctx context.Context, cmd CommandType) ([]byte, error) {
 	commandLine := ParseCommandLine(cmd)
 	log.WithField("command", strings.Join(commandLine, " ")).Debug("executing command")
 	if ctx != nil {
-		out, err = exec.CommandContext(ctx, commandLine[0], commandLine[1:]...).CombinedOutput() //nolint: gosec
+		out, err = newExecCommand(ctx, cmd, commandLine).CombinedOutput()
 		if ctx.Err() != nil {
 			return out, fmt.Errorf("failed to execute command: timed out or canceled")
 		}
 	} else {
-		out, err = exec.Command(commandLine[0], commandLine[1:]...).CombinedOutput() //nolint: gosec
+		out, err = newExecCommand(context.Background(), cmd, commandLine).CombinedOutput()
 	}
 	if err != nil {
-		return out, fmt.Errorf("failed to execute command: %s", err)
+		return out, fmt.Errorf("failed to execute command: %w", err)
 	}
 
 	log.WithField("command", strings.Join(commandLine, " ")).Debug("successfully executed command")
 	return out, nil
 }
 
-// GzipFile compresses a file with gzip and returns the path of the created archive
-func GzipFile(fileName string) (string, error) {
-	var err error
-
-	// open input file
-	var inFile *os.File
-	inFile, err = os.Open(fileName)
+// ToolVersion returns the first line printed by "<binary> --version", or an empty string if it can't be determined
+func ToolVersion(ctx context.Context, binary string) string {
+	out, err := Run(ctx, CommandType{Binary: binary, Args: []string{"--version"}})
 	if err != nil {
-		return "", errors.WithStack(err)
+		log.WithError(err).WithField("binary", binary).Debug("unable to determine tool version")
+		return ""
 	}
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0])
+}
 
-	// read file content
-	var content []byte
-	reader := bufio.NewReader(inFile)
-	content, err = io.ReadAll(reader)
-	if err != nil {
-		return "", errors.WithStack(err)
+// RunWithLineHandler executes the given binary and passes every line written to stdout to handleLine
+// as soon as it is available. Output written to stderr is collected and returned.
+func RunWithLineHandler(ctx context.Context, cmd CommandType, handleLine func(line []byte)) ([]byte, error) {
+	return RunWithInputAndLineHandler(ctx, cmd, nil, handleLine)
+}
+
+// RunWithInputAndLineHandler executes the given binary like RunWithLineHandler and streams everything written by
+// writeInput into its stdin. If writeInput fails, the binary is killed before its stdin is closed,
+// thus it never mistakes a truncated input for a complete one.
+func RunWithInputAndLineHandler(
+	ctx context.Context, cmd CommandType, writeInput func(w io.Writer) error, handleLine func(line []byte),
+) ([]byte, error) {
+	commandLine := ParseCommandLine(cmd)
+	log.WithField("command", strings.Join(commandLine, " ")).Debug("executing command")
+
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	execCmd := newExecCommand(ctx, cmd, commandLine)
+
+	var stderr bytes.Buffer
+	execCmd.Stderr = &stderr
 
-	// open output file
-	var outFile *os.File
-	outName := fmt.Sprintf("%s%s", fileName, GzipSuffix)
-	outFile, err = os.Create(outName)
+	stdout, err := execCmd.StdoutPipe()
 	if err != nil {
-		return "", errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
-	defer func() {
-		outErr := outFile.Close()
-		if outErr != nil {
-			log.WithError(outErr).Errorf("failed to close output file %s", outName)
+	var stdin io.WriteCloser
+	if writeInput != nil {
+		if stdin, err = execCmd.StdinPipe(); err != nil {
+			return nil, errors.WithStack(err)
 		}
-	}()
+	}
 
-	// write compressed content to file
-	archiveWriter := gzip.NewWriter(outFile)
-	archiveWriter.Name = fileName
-	_, err = archiveWriter.Write(content)
-	if err != nil {
-		return "", errors.WithStack(err)
+	if err = execCmd.Start(); err != nil {
+		return stderr.Bytes(), fmt.Errorf("failed to execute command: %w", err)
 	}
-	err = archiveWriter.Close()
-	if err != nil {
-		log.WithError(err).Error("failed to close archive reader")
+
+	var inputErr error
+	inputDone := make(chan struct{})
+	if writeInput != nil {
+		go func() {
+			defer close(inputDone)
+			if inputErr = writeInput(stdin); inputErr != nil {
+				_ = execCmd.Process.Kill()
+			}
+			_ = stdin.Close()
+		}()
+	} else {
+		close(inputDone)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		handleLine(scanner.Bytes())
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// drain remaining output so the process doesn't block on a full pipe
+		_, _ = io.Copy(io.Discard, stdout)
 	}
 
-	// remove uncompressed source backup
-	err = os.Remove(fileName)
+	<-inputDone
+	err = execCmd.Wait()
+	if ctx.Err() != nil {
+		return stderr.Bytes(), fmt.Errorf("failed to execute command: timed out or canceled")
+	}
+	// the input fails with a broken pipe if the binary exits early, its own error is more telling then
+	if inputErr != nil && (err == nil || !errors.Is(inputErr, syscall.EPIPE)) {
+		return stderr.Bytes(), errors.WithStack(fmt.Errorf("failed to write input of command: %w", inputErr))
+	}
 	if err != nil {
-		log.WithError(err).Error("failed to remove uncompressed backup file")
+		return stderr.Bytes(), fmt.Errorf("failed to execute command: %w", err)
+	}
+	if scanErr != nil {
+		return stderr.Bytes(), errors.WithStack(scanErr)
 	}
 
-	return outName, nil
+	log.WithField("command", strings.Join(commandLine, " ")).Debug("successfully executed command")
+	return stderr.Bytes(), nil
 }
 
-// CheckAndGunzipFile checks if a file is gzipped and extracts it in that case...
-// ... it also returns the name of the unzipped file
-func CheckAndGunzipFile(fileName string) (string, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return "", errors.WithStack(err)
-	}
-	defer func() {
-		fileErr := file.Close()
-		if fileErr != nil {
-			log.WithError(fileErr).Errorf("failed to close source file %s", fileName)
-		}
-	}()
+// RunWithStdout executes the given binary and streams everything written to stdout into the given writer.
+// Output written to stderr is collected and returned.
+func RunWithStdout(ctx context.Context, cmd CommandType, stdout io.Writer) ([]byte, error) {
+	commandLine := ParseCommandLine(cmd)
+	log.WithField("command", strings.Join(commandLine, " ")).Debug("executing command")
 
-	// read first 512 bytes for http.DetectContentType
-	headerBytes := make([]byte, 512)
-	_, err = file.Read(headerBytes)
-	if err != nil {
-		return "", errors.WithStack(err)
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	execCmd := newExecCommand(ctx, cmd, commandLine)
 
-	// check if file is gzipped
-	fileType := http.DetectContentType(headerBytes)
-	if fileType != gzipType {
-		return fileName, nil
+	var stderr bytes.Buffer
+	execCmd.Stderr = &stderr
+	execCmd.Stdout = stdout
+
+	err := execCmd.Run()
+	if ctx.Err() != nil {
+		return stderr.Bytes(), fmt.Errorf("failed to execute command: timed out or canceled")
 	}
-	// open gzipped file
-	archive, archErr := os.Open(fileName)
-	if archErr != nil {
-		return "", errors.WithStack(err)
+	if err != nil {
+		return stderr.Bytes(), fmt.Errorf("failed to execute command: %w", err)
 	}
-	defer func() {
-		archDeferredErr := archive.Close()
-		if archDeferredErr != nil {
-			log.WithError(archDeferredErr).Errorf("failed to close archive file %s", fileName)
-		}
-	}()
 
-	// unzip gzipped file
-	var archiveReader *gzip.Reader
-	archiveReader, err = gzip.NewReader(archive)
-	if err != nil {
-		return "", errors.WithStack(err)
+	log.WithField("command", strings.Join(commandLine, " ")).Debug("successfully executed command")
+	return stderr.Bytes(), nil
+}
+
+// RunWithStdin executes the given binary with the given reader as stdin and returns its combined output
+func RunWithStdin(ctx context.Context, cmd CommandType, stdin io.Reader) ([]byte, error) {
+	commandLine := ParseCommandLine(cmd)
+	log.WithField("command", strings.Join(commandLine, " ")).Debug("executing command")
+
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	defer func() {
-		readerErr := archiveReader.Close()
-		if readerErr != nil {
-			log.WithError(readerErr).Error("failed to close archive reader")
-		}
-	}()
-
-	// open output file
-	var outFile *os.File
-	outName := archiveReader.Name
-	// if archive header isn't set properly attempt to salvage by using filename without '.gz'
-	if outName == "" {
-		outName = strings.TrimRight(fileName, GzipSuffix)
+	execCmd := newExecCommand(ctx, cmd, commandLine)
+	execCmd.Stdin = stdin
+
+	out, err := execCmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return out, fmt.Errorf("failed to execute command: timed out or canceled")
 	}
-	outFile, err = os.Create(outName)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return out, fmt.Errorf("failed to execute command: %w", err)
 	}
-	defer func() {
-		outErr := outFile.Close()
-		if outErr != nil {
-			log.WithError(outErr).Errorf("failed to close output file %s", outName)
-		}
-	}()
 
-	// write unzipped file to file system
-	_, err = io.Copy(outFile, archiveReader) //nolint: gosec // we work with potentially large backups
-	if err != nil {
+	log.WithField("command", strings.Join(commandLine, " ")).Debug("successfully executed command")
+	return out, nil
+}
+
+// GzipFile compresses a file with gzip, removes it afterwards and returns the path of the created archive
+func GzipFile(fileName string) (string, error) {
+	outName := fileName + compress.GzipSuffix
+	return outName, dumpfile.WriteFile(context.Background(), fileName, outName)
+}
+
+// CheckAndGunzipFile checks if a file is compressed with gzip, zstd or xz and extracts it in that case...
+// ... it also returns the name of the extracted file
+func CheckAndGunzipFile(fileName string) (string, error) {
+	plainName, removePlain, err := dumpfile.Open(context.Background(), fileName)
+	if err != nil || plainName == fileName {
+		return plainName, err
+	}
+
+	outName := dumpfile.PlainName(fileName)
+	if outName == fileName {
+		outName += decompressedSuffix
+	}
+	if err = os.Rename(plainName, outName); err != nil {
+		removePlain()
 		return "", errors.WithStack(err)
 	}
-	extractedName := archiveReader.Name
-	return extractedName, nil
+	return outName, nil
 }
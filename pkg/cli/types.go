@@ -1,13 +1,16 @@
 package cli
 
-const GzipSuffix = ".gz"
+import "github.com/mittwald/brudi/pkg/compress"
+
+const GzipSuffix = compress.GzipSuffix
 
 type CommandType struct {
 	Binary  string
 	Command string
 	Args    []string
-	Nice    *int // https://linux.die.net/man/1/nice
-	IONice  *int // https://linux.die.net/man/1/ionice
+	Env     []string // additional environment variables as KEY=value, the environment of brudi is inherited
+	Nice    *int     // https://linux.die.net/man/1/nice
+	IONice  *int     // https://linux.die.net/man/1/ionice
 }
 
 type PipedCommandsPids struct {
@@ -0,0 +1,141 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
+)
+
+// Algorithm used to compress a backup
+type Algorithm string
+
+const (
+	None Algorithm = ""
+	Gzip Algorithm = "gzip"
+	Zstd Algorithm = "zstd"
+	Xz   Algorithm = "xz"
+)
+
+const (
+	GzipSuffix = ".gz"
+	ZstdSuffix = ".zst"
+	XzSuffix   = ".xz"
+)
+
+// suffixes maps the file suffix of each algorithm
+var suffixes = map[Algorithm]string{
+	Gzip: GzipSuffix,
+	Zstd: ZstdSuffix,
+	Xz:   XzSuffix,
+}
+
+// magicBytes at the beginning of files compressed with each algorithm
+var magicBytes = map[Algorithm][]byte{
+	Gzip: {0x1f, 0x8b},
+	Zstd: {0x28, 0xb5, 0x2f, 0xfd},
+	Xz:   {0xfd, '7', 'z', 'X', 'Z', 0x00},
+}
+
+// HeaderSize is the number of bytes DetectAlgorithm needs at most
+const HeaderSize = 6
+
+// AlgorithmForFile returns the algorithm matching the suffix of the given file name, None if there is no match
+func AlgorithmForFile(fileName string) Algorithm {
+	for algorithm, suffix := range suffixes {
+		if strings.HasSuffix(fileName, suffix) {
+			return algorithm
+		}
+	}
+	return None
+}
+
+// TrimSuffix removes the suffix of the given algorithm from the file name
+func TrimSuffix(fileName string, algorithm Algorithm) string {
+	return strings.TrimSuffix(fileName, suffixes[algorithm])
+}
+
+// DetectAlgorithm returns the algorithm the given header was compressed with, None if it isn't compressed
+func DetectAlgorithm(header []byte) Algorithm {
+	for algorithm, magic := range magicBytes {
+		if bytes.HasPrefix(header, magic) {
+			return algorithm
+		}
+	}
+	return None
+}
+
+// NewWriter returns a writer compressing with the given algorithm. name is stored in the gzip header and used
+// as file name on decompression.
+func NewWriter(w io.Writer, algorithm Algorithm, name string, cfg *Config) (io.WriteCloser, error) {
+	switch algorithm {
+	case Gzip:
+		return NewGzipWriter(w, name, cfg)
+	case Zstd:
+		level := zstd.SpeedDefault
+		if cfg.Level > 0 {
+			level = zstd.EncoderLevelFromZstd(cfg.Level)
+		}
+		zstdWriter, err := zstd.NewWriter(w, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(cfg.Concurrency))
+		return zstdWriter, errors.WithStack(err)
+	case Xz:
+		xzWriter, err := xz.NewWriter(w)
+		return xzWriter, errors.WithStack(err)
+	default:
+		return nil, errors.Errorf("unsupported compression algorithm '%s'", algorithm)
+	}
+}
+
+// NewGzipWriter returns a gzip writer for the given config, compressing in parallel if concurrency is greater than 1.
+// name is stored in the gzip header and used as file name on decompression.
+func NewGzipWriter(w io.Writer, name string, cfg *Config) (io.WriteCloser, error) {
+	if cfg.Concurrency <= 1 {
+		gzipWriter, err := gzip.NewWriterLevel(w, cfg.Level)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		gzipWriter.Name = name
+		return gzipWriter, nil
+	}
+
+	gzipWriter, err := pgzip.NewWriterLevel(w, cfg.Level)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gzipWriter.Name = name
+	if err = gzipWriter.SetConcurrency(cfg.BlockSize, cfg.Concurrency); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gzipWriter, nil
+}
+
+// NewReader returns a reader decompressing the given algorithm and the file name stored in its header, if any
+func NewReader(r io.Reader, algorithm Algorithm) (io.ReadCloser, string, error) {
+	switch algorithm {
+	case Gzip:
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		return gzipReader, gzipReader.Name, nil
+	case Zstd:
+		zstdReader, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		return zstdReader.IOReadCloser(), "", nil
+	case Xz:
+		xzReader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		return io.NopCloser(xzReader), "", nil
+	default:
+		return nil, "", errors.Errorf("unsupported compression algorithm '%s'", algorithm)
+	}
+}
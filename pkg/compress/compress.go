@@ -0,0 +1,64 @@
+package compress
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewContextReader returns a reader which aborts reading as soon as the given context is done
+func NewContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+// contextReader aborts reading as soon as its context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// WriteFileAtomic passes a temporary file next to fileName to write and renames it to fileName
+// if write succeeds. Otherwise, the temporary file is removed and fileName stays untouched.
+func WriteFileAtomic(fileName string, write func(w io.Writer) error) (err error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(fileName), fmt.Sprintf(".%s.*.tmp", filepath.Base(fileName)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmpFile.Close()
+			if removeErr := os.Remove(tmpFile.Name()); removeErr != nil {
+				log.WithError(removeErr).Errorf("failed to remove temporary file %s", tmpFile.Name())
+			}
+		}
+	}()
+
+	bufferedWriter := bufio.NewWriter(tmpFile)
+	if err = write(bufferedWriter); err != nil {
+		return err
+	}
+	if err = bufferedWriter.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = tmpFile.Sync(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmpFile.Name(), fileName))
+}
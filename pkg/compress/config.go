@@ -0,0 +1,72 @@
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/mittwald/brudi/pkg/config"
+)
+
+const (
+	Kind = "compression"
+
+	// defaultBlockSize of parallel compression, each goroutine compresses one block at a time
+	defaultBlockSize = 1 << 20
+)
+
+// Config of the compression of backups
+type Config struct {
+	// Level from 1 (fastest) to 9 (best compression) for gzip or 22 for zstd, the default level of the algorithm
+	// is used if it isn't configured. xz always uses its default level.
+	Level int
+	// Concurrency is the number of goroutines compressing in parallel, 1 disables parallel compression
+	Concurrency int `validate:"min=1"`
+	// BlockSize in bytes compressed by each goroutine in parallel mode, memory usage is about
+	// two times concurrency times block size
+	BlockSize int `validate:"min=65536"`
+}
+
+// levelRange is the lowest and highest level of an algorithm
+type levelRange struct {
+	min, max int
+}
+
+// levelRanges of the algorithms whose level is configurable, -1 is the default level of gzip
+var levelRanges = map[Algorithm]levelRange{
+	Gzip: {min: gzip.DefaultCompression, max: gzip.BestCompression},
+	Zstd: {min: 1, max: 22},
+}
+
+// DefaultConfig returns a config for single-threaded compression with the default level
+func DefaultConfig() *Config {
+	return &Config{
+		Level:       gzip.DefaultCompression,
+		Concurrency: 1,
+		BlockSize:   defaultBlockSize,
+	}
+}
+
+// NewConfig returns the "compression" config for the given algorithm, a configured level has to be valid for it
+func NewConfig(algorithm Algorithm) (*Config, error) {
+	cfg := DefaultConfig()
+
+	err := config.InitializeStructFromViper(Kind, cfg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = config.Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	levels, ok := levelRanges[algorithm]
+	if ok && viper.IsSet(Kind+".level") && (cfg.Level < levels.min || cfg.Level > levels.max) {
+		return nil, errors.WithStack(fmt.Errorf(
+			"invalid %s.level %d: %s supports levels from %d to %d", Kind, cfg.Level, algorithm, levels.min, levels.max,
+		))
+	}
+	return cfg, nil
+}
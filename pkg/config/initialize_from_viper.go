@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -16,6 +17,8 @@ const (
 	flagTag = "viper"
 )
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
 // Viper itself is sadly not capable of resolving childs env when fetching the parent key
 // Therefore we have to workaround the env-resolving
 // https://github.com/spf13/viper/issues/696
@@ -119,6 +122,16 @@ func reflectSetValueFromConfigKey(viperConfigKey string, fieldToBeSet reflect.Va
 
 	fieldLogger.Debug("loading viper value into struct")
 
+	// durations are given as strings like "30s" or "1h30m"
+	if fieldToBeSet.Type() == durationType {
+		if v, err := time.ParseDuration(fmt.Sprint(viperVal)); err == nil {
+			fieldToBeSet.SetInt(int64(v))
+		} else {
+			fieldLogger.WithError(err).Errorf("unable to parse duration: %v", viperVal)
+		}
+		return
+	}
+
 	switch fieldToBeSet.Kind() {
 	case reflect.Int:
 		switch viperVal.(type) {
@@ -0,0 +1,149 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+// Select returns the databases matching one of the include patterns, all if there are none, but none of the
+// exclude patterns. The patterns are matched like path.Match, invalid patterns match nothing.
+func Select(databases, include, exclude []string) []string {
+	selected := []string{}
+	for _, database := range databases {
+		if len(include) > 0 && !matchesAny(database, include) {
+			continue
+		}
+		if matchesAny(database, exclude) {
+			continue
+		}
+		selected = append(selected, database)
+	}
+	return selected
+}
+
+// ValidatePatterns returns an error for the first pattern which is invalid
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.WithStack(fmt.Errorf("invalid pattern %q: %w", pattern, err))
+		}
+	}
+	return nil
+}
+
+func matchesAny(database string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, database); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Run calls fn for every database with at most concurrency calls at the same time. The context passed to fn
+// is canceled once a call failed, the first error is returned after all calls returned.
+func Run(ctx context.Context, concurrency int, databases []string, fn func(ctx context.Context, database string) error) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	if concurrency > 0 {
+		group.SetLimit(concurrency)
+	}
+	for _, database := range databases {
+		database := database
+		group.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				return errors.WithStack(err)
+			}
+			if err := fn(groupCtx, database); err != nil {
+				return errors.WithStack(fmt.Errorf("database %s: %w", database, err))
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// FileName returns the dump file of the database within dir. suffix is appended to the name of the database,
+// e.g. ".sql.gz", thus its compression and encryption suffixes apply to the dump. The name is escaped like a URL
+// path segment, since database names may contain "/" and thus must not be used as path.
+func FileName(dir, database, suffix string) string {
+	return filepath.Join(dir, url.PathEscape(database)+suffix)
+}
+
+// ReplaceDirectory lets write fill a staging directory next to dir, which replaces dir once write succeeded. Thus dir
+// holds the dumps of the latest run only, the dumps of databases which were dropped or excluded since an earlier run,
+// or which were written in another format, don't remain in it. dir stays untouched if write fails.
+func ReplaceDirectory(dir string, write func(staging string) error) error {
+	dir = filepath.Clean(dir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".brudi-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(staging); removeErr != nil {
+			log.WithError(removeErr).Warnf("failed to remove staging directory %s", staging)
+		}
+	}()
+	if err = os.Chmod(staging, 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = write(staging); err != nil {
+		return err
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(staging, dir))
+}
+
+// Dumps returns the dump files within dir by their database, which is the unescaped file name without plainSuffix,
+// e.g. ".sql", and the suffixes of compression and encryption, see FileName
+func Dumps(dir, plainSuffix string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	dumps := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		plainName := dumpfile.PlainName(entry.Name())
+		if !strings.HasSuffix(plainName, plainSuffix) || plainName == plainSuffix {
+			continue
+		}
+		database := strings.TrimSuffix(plainName, plainSuffix)
+		if unescaped, unescapeErr := url.PathUnescape(database); unescapeErr == nil {
+			database = unescaped
+		}
+		dumps[database] = filepath.Join(dir, entry.Name())
+	}
+	return dumps, nil
+}
+
+// Names returns the sorted databases of the given dumps
+func Names(dumps map[string]string) []string {
+	names := make([]string, 0, len(dumps))
+	for name := range dumps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,177 @@
+package databases
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/cli"
+)
+
+const (
+	psqlBinary     = "psql"
+	createdbBinary = "createdb"
+
+	// PostgresMaintenanceDatabase is connected to when no database is configured
+	PostgresMaintenanceDatabase = "postgres"
+
+	// listPostgresQuery selects the databases which can be connected to, the templates are part of the server
+	listPostgresQuery = "SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate ORDER BY datname"
+)
+
+// PostgresConnection are the flags the PostgreSQL client tools need to connect to the server,
+// the password is passed by the PGPASSWORD environment variable
+type PostgresConnection struct {
+	Host       string `flag:"--host="`
+	Port       int    `flag:"--port="`
+	Username   string `flag:"--username="`
+	NoPassword bool   `flag:"--no-password"`
+}
+
+// PostgresDatabases returns the databases of the server, connecting to the given maintenance database
+func PostgresDatabases(ctx context.Context, conn *PostgresConnection, maintenanceDatabase string) ([]string, error) {
+	args := append(cli.StructToCLI(conn),
+		"--dbname="+maintenanceDatabase,
+		"--no-align",
+		"--tuples-only",
+		"--command="+listPostgresQuery,
+	)
+	cmd := cli.CommandType{
+		Binary: psqlBinary,
+		Args:   args,
+	}
+
+	var stdout bytes.Buffer
+	out, err := cli.RunWithStdout(ctx, cmd, &stdout)
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("failed to list databases: %+v - %s", err, out))
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if name := scanner.Text(); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, errors.WithStack(scanner.Err())
+}
+
+// CreatePostgresDatabase creates the given database with createdb, which takes care of quoting its name
+func CreatePostgresDatabase(ctx context.Context, conn *PostgresConnection, maintenanceDatabase, database string) error {
+	args := append(cli.StructToCLI(conn), "--maintenance-db="+maintenanceDatabase, database)
+	cmd := cli.CommandType{
+		Binary: createdbBinary,
+		Args:   args,
+	}
+
+	out, err := cli.Run(ctx, cmd)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("failed to create database %s: %+v - %s", database, err, out))
+	}
+	return nil
+}
+
+// PostgresGlobalsFile is the dump of the globals within the directory of the dumps
+const PostgresGlobalsFile = "globals.sql"
+
+// PostgresDatabasesDirectory contains the dumps of the databases within the directory of the dumps,
+// thus they can't collide with the dump of the globals
+const PostgresDatabasesDirectory = "databases"
+
+// postgresDumpSuffixes are the suffixes of the dumps in the formats of pg_dump writing a single file
+var postgresDumpSuffixes = map[string]string{
+	"":       ".sql",
+	"p":      ".sql",
+	"plain":  ".sql",
+	"c":      ".dump",
+	"custom": ".dump",
+	"t":      ".tar",
+	"tar":    ".tar",
+}
+
+// PostgresDumpSuffix returns the suffix of dumps written by pg_dump in the given format, or an empty string
+// if the format isn't written to a single file
+func PostgresDumpSuffix(format string) string {
+	return postgresDumpSuffixes[format]
+}
+
+// PostgresDumps returns the dumps within the databases directory of dir by their database, regardless of their format
+func PostgresDumps(dir string) (map[string]string, error) {
+	dumps := map[string]string{}
+	for _, suffix := range []string{".sql", ".dump", ".tar"} {
+		found, err := Dumps(filepath.Join(dir, PostgresDatabasesDirectory), suffix)
+		if err != nil {
+			return nil, err
+		}
+		for database, dump := range found {
+			if other, ok := dumps[database]; ok {
+				return nil, errors.WithStack(fmt.Errorf("database %s has several dumps: %s and %s", database, other, dump))
+			}
+			dumps[database] = dump
+		}
+	}
+	return dumps, nil
+}
+
+// availablePostgresConnectionsQuery returns the number of connections which can be opened by the current role to the
+// current database, considering max_connections without the reserved ones as well as the connection limits of the role
+// and the database. The connection running the query is closed afterwards, thus it is counted as available.
+const availablePostgresConnectionsQuery = `SELECT least(
+  current_setting('max_connections')::int - current_setting('superuser_reserved_connections')::int
+    - (SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend'),
+  CASE WHEN r.rolconnlimit < 0 THEN NULL ELSE r.rolconnlimit
+    - (SELECT count(*) FROM pg_stat_activity WHERE usename = current_user AND backend_type = 'client backend') END,
+  CASE WHEN d.datconnlimit < 0 THEN NULL ELSE d.datconnlimit
+    - (SELECT count(*) FROM pg_stat_activity WHERE datname = current_database() AND backend_type = 'client backend') END
+) + 1
+FROM pg_roles r, pg_database d WHERE r.rolname = current_user AND d.datname = current_database()`
+
+// AvailablePostgresConnections returns the number of connections which can be opened to the given database,
+// the default database of libpq is used if it is empty
+func AvailablePostgresConnections(ctx context.Context, conn *PostgresConnection, database string) (int, error) {
+	args := cli.StructToCLI(conn)
+	if database != "" {
+		args = append(args, "--dbname="+database)
+	}
+	cmd := cli.CommandType{
+		Binary: psqlBinary,
+		Args:   append(args, "--no-align", "--tuples-only", "--command="+availablePostgresConnectionsQuery),
+	}
+
+	var stdout bytes.Buffer
+	out, err := cli.RunWithStdout(ctx, cmd, &stdout)
+	if err != nil {
+		return 0, errors.WithStack(fmt.Errorf("failed to query available connections: %+v - %s", err, out))
+	}
+
+	available, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return 0, errors.WithStack(fmt.Errorf("unexpected number of available connections %q: %w", stdout.String(), err))
+	}
+	return available, nil
+}
+
+// CheckPostgresJobs returns an error if the given number of parallel jobs of pg_dump or pg_restore exceeds
+// the available connections. Both open a connection per job in addition to the one of the leader.
+func CheckPostgresJobs(ctx context.Context, conn *PostgresConnection, database string, jobs int) error {
+	if jobs <= 1 {
+		return nil
+	}
+	available, err := AvailablePostgresConnections(ctx, conn, database)
+	if err != nil {
+		return err
+	}
+	if jobs+1 > available {
+		return errors.WithStack(fmt.Errorf(
+			"%d jobs need %d connections, but only %d are available on %s", jobs, jobs+1, available, conn.Host,
+		))
+	}
+	return nil
+}
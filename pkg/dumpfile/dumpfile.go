@@ -0,0 +1,264 @@
+package dumpfile
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/compress"
+	"github.com/mittwald/brudi/pkg/encrypt"
+)
+
+// transformation of a dump file, derived from the suffixes of its name, e.g. ".sql.zst.age"
+type transformation struct {
+	compression compress.Algorithm
+	encryption  encrypt.Algorithm
+	// plainName is the file name without the suffixes of compression and encryption
+	plainName string
+}
+
+func transformationForFile(fileName string) transformation {
+	encryption := encrypt.AlgorithmForFile(fileName)
+	compressedName := encrypt.TrimSuffix(fileName, encryption)
+	compression := compress.AlgorithmForFile(compressedName)
+
+	return transformation{
+		compression: compression,
+		encryption:  encryption,
+		plainName:   compress.TrimSuffix(compressedName, compression),
+	}
+}
+
+// IsPlain returns whether dumps written to the given file are neither compressed nor encrypted
+func IsPlain(fileName string) bool {
+	return PlainName(fileName) == fileName
+}
+
+// Compression returns the algorithm dumps written to the given file are compressed with
+func Compression(fileName string) compress.Algorithm {
+	return transformationForFile(fileName).compression
+}
+
+// PlainName returns the given file name without the suffixes of compression and encryption
+func PlainName(fileName string) string {
+	return transformationForFile(fileName).plainName
+}
+
+// Write writes a dump atomically to fileName, first compressing and then encrypting it according to the suffixes
+// of fileName, e.g. ".sql.gz.age" or ".rdb.zst.gpg". The dump is streamed with constant memory usage.
+func Write(fileName string, write func(w io.Writer) error) error {
+	return compress.WriteFileAtomic(fileName, func(w io.Writer) error {
+		return Stream(w, fileName, write)
+	})
+}
+
+// Stream writes a dump into w, first compressing and then encrypting it according to the suffixes of fileName,
+// like Write, but without writing fileName itself, e.g. to stream the dump to restic
+func Stream(w io.Writer, fileName string, write func(w io.Writer) error) error {
+	t := transformationForFile(fileName)
+
+	compressConfig, err := compress.NewConfig(t.compression)
+	if err != nil {
+		return err
+	}
+	var encryptConfig *encrypt.Config
+	if t.encryption != encrypt.None {
+		if encryptConfig, err = encrypt.NewConfig(); err != nil {
+			return err
+		}
+	}
+
+	var closers []io.Closer
+	if t.encryption != encrypt.None {
+		encryptWriter, encryptErr := encrypt.NewWriter(w, t.encryption, encryptConfig)
+		if encryptErr != nil {
+			return encryptErr
+		}
+		w = encryptWriter
+		closers = append(closers, encryptWriter)
+	}
+	if t.compression != compress.None {
+		compressWriter, compressErr := compress.NewWriter(w, t.compression, t.plainName, compressConfig)
+		if compressErr != nil {
+			return compressErr
+		}
+		w = compressWriter
+		closers = append(closers, compressWriter)
+	}
+
+	writeErr := write(w)
+	// close the innermost writer first to flush it into the outer ones
+	for i := len(closers) - 1; i >= 0; i-- {
+		if closeErr := closers[i].Close(); closeErr != nil && writeErr == nil {
+			writeErr = errors.WithStack(closeErr)
+		}
+	}
+	return writeErr
+}
+
+// WriteFile compresses and encrypts the given plain file into fileName, see Write, and removes it afterwards
+func WriteFile(ctx context.Context, plainFile, fileName string) error {
+	inFile, err := os.Open(plainFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if inErr := inFile.Close(); inErr != nil {
+			log.WithError(inErr).Errorf("failed to close input file %s", plainFile)
+		}
+	}()
+
+	err = Write(fileName, func(w io.Writer) error {
+		_, copyErr := io.Copy(w, compress.NewContextReader(ctx, inFile))
+		return errors.WithStack(copyErr)
+	})
+	if err != nil {
+		return err
+	}
+
+	// remove uncompressed source backup
+	if err = os.Remove(plainFile); err != nil {
+		log.WithError(err).Error("failed to remove uncompressed backup file")
+	}
+	return nil
+}
+
+// plainStream is the plain content of a dump file, which is decrypted and uncompressed while it is read
+type plainStream struct {
+	io.Reader
+	file        *os.File
+	closers     []io.Closer
+	encryption  encrypt.Algorithm
+	compression compress.Algorithm
+}
+
+func (s *plainStream) Close() error {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].Close(); err != nil {
+			log.WithError(err).Errorf("failed to close reader of %s", s.file.Name())
+		}
+	}
+	return errors.WithStack(s.file.Close())
+}
+
+// openPlain detects by its content whether the given dump is encrypted and/or compressed
+// and returns a stream of the plain dump
+func openPlain(fileName string) (*plainStream, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	stream := &plainStream{file: file}
+	err = stream.init()
+	if err != nil {
+		_ = stream.Close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *plainStream) init() error {
+	reader := bufio.NewReader(s.file)
+
+	header, err := peekHeader(reader, encrypt.HeaderSize)
+	if err != nil {
+		return err
+	}
+	s.encryption = encrypt.DetectAlgorithm(header)
+	if s.encryption != encrypt.None {
+		encryptConfig, configErr := encrypt.NewConfig()
+		if configErr != nil {
+			return configErr
+		}
+		decryptReader, readerErr := encrypt.NewReader(reader, s.encryption, encryptConfig)
+		if readerErr != nil {
+			return readerErr
+		}
+		reader = bufio.NewReader(decryptReader)
+	}
+
+	header, err = peekHeader(reader, compress.HeaderSize)
+	if err != nil {
+		return err
+	}
+	s.compression = compress.DetectAlgorithm(header)
+	s.Reader = reader
+	if s.compression != compress.None {
+		decompressReader, _, readerErr := compress.NewReader(reader, s.compression)
+		if readerErr != nil {
+			return readerErr
+		}
+		s.closers = append(s.closers, decompressReader)
+		s.Reader = decompressReader
+	}
+	return nil
+}
+
+// NewReader returns a reader of the plain content of the given dump, which is decrypted and uncompressed
+// on the fly if the dump is encrypted and/or compressed
+func NewReader(ctx context.Context, fileName string) (io.ReadCloser, error) {
+	stream, err := openPlain(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{compress.NewContextReader(ctx, stream), stream}, nil
+}
+
+// Open returns the name of a file holding the plain content of the given dump, for tools which can't read the dump
+// from stdin. Whether the dump is encrypted and/or compressed is detected by its content. If it is neither,
+// that's the dump itself. Otherwise, it is a temporary file next to the dump, which is removed by the returned function.
+func Open(ctx context.Context, fileName string) (string, func(), error) {
+	stream, err := openPlain(fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if streamErr := stream.Close(); streamErr != nil {
+			log.WithError(streamErr).Errorf("failed to close source file %s", fileName)
+		}
+	}()
+
+	if stream.encryption == encrypt.None && stream.compression == compress.None {
+		return fileName, func() {}, nil
+	}
+
+	plain, err := os.CreateTemp(filepath.Dir(fileName), fmt.Sprintf(".%s.*.tmp", filepath.Base(PlainName(fileName))))
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	remove := func() {
+		if removeErr := os.Remove(plain.Name()); removeErr != nil {
+			log.WithError(removeErr).Warnf("failed to remove %s", plain.Name())
+		}
+	}
+
+	//nolint: gosec // we work with potentially large backups
+	_, err = io.Copy(plain, compress.NewContextReader(ctx, stream))
+	if closeErr := plain.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return "", nil, errors.WithStack(err)
+	}
+	return plain.Name(), remove, nil
+}
+
+// peekHeader returns up to size bytes from the beginning of the reader without consuming them
+func peekHeader(reader *bufio.Reader, size int) ([]byte, error) {
+	header, err := reader.Peek(size)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.WithStack(err)
+	}
+	return header, nil
+}
@@ -0,0 +1,51 @@
+package encrypt
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/config"
+)
+
+const (
+	Kind = "encryption"
+)
+
+// AgeConfig contains the keys to encrypt and decrypt dumps with age, see https://age-encryption.org
+type AgeConfig struct {
+	// Recipients are the public keys dumps are encrypted for, e.g. "age1..."
+	Recipients []string
+	// RecipientsFile contains further recipients, one per line
+	RecipientsFile string
+	// IdentityFile contains the private keys to decrypt dumps with
+	IdentityFile string
+}
+
+// PGPConfig contains the keys to encrypt and decrypt dumps with OpenPGP
+type PGPConfig struct {
+	// PublicKeyFile is an armored or binary keyring of the public keys dumps are encrypted for
+	PublicKeyFile string
+	// PrivateKeyFile is an armored or binary keyring of the private keys to decrypt dumps with
+	PrivateKeyFile string
+	// Passphrase of the private keys, if they are protected
+	Passphrase string
+}
+
+type Config struct {
+	Age *AgeConfig
+	PGP *PGPConfig
+}
+
+// NewConfig returns the "encryption" config
+func NewConfig() (*Config, error) {
+	cfg := &Config{
+		Age: &AgeConfig{},
+		PGP: &PGPConfig{},
+	}
+
+	err := config.InitializeStructFromViper(Kind, cfg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return cfg, config.Validate(cfg)
+}
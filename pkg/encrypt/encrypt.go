@@ -0,0 +1,248 @@
+package encrypt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// Algorithm used to encrypt a dump
+type Algorithm string
+
+const (
+	None Algorithm = ""
+	Age  Algorithm = "age"
+	PGP  Algorithm = "pgp"
+)
+
+const (
+	AgeSuffix = ".age"
+	PGPSuffix = ".gpg"
+)
+
+const (
+	ageHeader        = "age-encryption.org/v1"
+	pgpArmorHeader   = "-----BEGIN PGP MESSAGE-----"
+	pgpPacketNewTag  = 0x40
+	pgpPacketTagMask = 0x3f
+	// HeaderSize is the number of bytes DetectAlgorithm needs at most
+	HeaderSize = len(pgpArmorHeader)
+)
+
+// AlgorithmForFile returns the algorithm matching the suffix of the given file name, None if there is no match
+func AlgorithmForFile(fileName string) Algorithm {
+	switch {
+	case strings.HasSuffix(fileName, AgeSuffix):
+		return Age
+	case strings.HasSuffix(fileName, PGPSuffix):
+		return PGP
+	default:
+		return None
+	}
+}
+
+// TrimSuffix removes the suffix of the given algorithm from the file name
+func TrimSuffix(fileName string, algorithm Algorithm) string {
+	switch algorithm {
+	case Age:
+		return strings.TrimSuffix(fileName, AgeSuffix)
+	case PGP:
+		return strings.TrimSuffix(fileName, PGPSuffix)
+	default:
+		return fileName
+	}
+}
+
+// DetectAlgorithm returns the algorithm the given header was encrypted with, None if it isn't encrypted
+func DetectAlgorithm(header []byte) Algorithm {
+	if bytes.HasPrefix(header, []byte(ageHeader)) {
+		return Age
+	}
+	if bytes.HasPrefix(header, []byte(pgpArmorHeader)) || isPGPEncryptedPacket(header) {
+		return PGP
+	}
+	return None
+}
+
+// isPGPEncryptedPacket returns whether the header starts with a public-key or symmetric-key encrypted session key
+// packet, which precede the encrypted data of OpenPGP messages
+func isPGPEncryptedPacket(header []byte) bool {
+	const (
+		tagPublicKeyEncrypted = 1
+		tagSymmetricEncrypted = 3
+	)
+	if len(header) == 0 || header[0]&0x80 == 0 {
+		return false
+	}
+
+	var tag byte
+	if header[0]&pgpPacketNewTag != 0 {
+		tag = header[0] & pgpPacketTagMask
+	} else {
+		tag = (header[0] & pgpPacketTagMask) >> 2
+	}
+	return tag == tagPublicKeyEncrypted || tag == tagSymmetricEncrypted
+}
+
+// NewWriter returns a writer encrypting with the given algorithm for the recipients of the config
+func NewWriter(w io.Writer, algorithm Algorithm, cfg *Config) (io.WriteCloser, error) {
+	switch algorithm {
+	case Age:
+		recipients, err := cfg.Age.recipients()
+		if err != nil {
+			return nil, err
+		}
+		ageWriter, err := age.Encrypt(w, recipients...)
+		return ageWriter, errors.WithStack(err)
+	case PGP:
+		keyRing, err := readKeyRing(cfg.PGP.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pgpWriter, err := openpgp.Encrypt(w, keyRing, nil, &openpgp.FileHints{IsBinary: true}, nil)
+		return pgpWriter, errors.WithStack(err)
+	default:
+		return nil, errors.Errorf("unsupported encryption algorithm '%s'", algorithm)
+	}
+}
+
+// NewReader returns a reader decrypting the given algorithm with the identities of the config
+func NewReader(r io.Reader, algorithm Algorithm, cfg *Config) (io.Reader, error) {
+	switch algorithm {
+	case Age:
+		identities, err := cfg.Age.identities()
+		if err != nil {
+			return nil, err
+		}
+		ageReader, err := age.Decrypt(r, identities...)
+		return ageReader, errors.WithStack(err)
+	case PGP:
+		keyRing, err := cfg.PGP.privateKeyRing()
+		if err != nil {
+			return nil, err
+		}
+
+		bufferedReader := bufio.NewReader(r)
+		header, _ := bufferedReader.Peek(len(pgpArmorHeader))
+		r = bufferedReader
+		if bytes.Equal(header, []byte(pgpArmorHeader)) {
+			block, armorErr := armor.Decode(bufferedReader)
+			if armorErr != nil {
+				return nil, errors.WithStack(armorErr)
+			}
+			r = block.Body
+		}
+
+		message, err := openpgp.ReadMessage(r, keyRing, nil, &packet.Config{})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return message.UnverifiedBody, nil
+	default:
+		return nil, errors.Errorf("unsupported encryption algorithm '%s'", algorithm)
+	}
+}
+
+func (c *AgeConfig) recipients() ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, recipient := range c.Recipients {
+		parsed, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		recipients = append(recipients, parsed)
+	}
+
+	if c.RecipientsFile != "" {
+		file, err := os.Open(c.RecipientsFile)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer file.Close()
+
+		parsed, err := age.ParseRecipients(file)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.New("no age recipients configured")
+	}
+	return recipients, nil
+}
+
+func (c *AgeConfig) identities() ([]age.Identity, error) {
+	if c.IdentityFile == "" {
+		return nil, errors.New("no age identity file configured")
+	}
+
+	file, err := os.Open(c.IdentityFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	return identities, errors.WithStack(err)
+}
+
+// privateKeyRing reads the private keys and decrypts them with the passphrase, if they are protected
+func (c *PGPConfig) privateKeyRing() (openpgp.EntityList, error) {
+	keyRing, err := readKeyRing(c.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase := []byte(c.Passphrase)
+	for _, entity := range keyRing {
+		if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+			if err = entity.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		for _, subkey := range entity.Subkeys {
+			if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+				if err = subkey.PrivateKey.Decrypt(passphrase); err != nil {
+					return nil, errors.WithStack(err)
+				}
+			}
+		}
+	}
+	return keyRing, nil
+}
+
+// readKeyRing reads an armored or binary OpenPGP keyring
+func readKeyRing(fileName string) (openpgp.EntityList, error) {
+	if fileName == "" {
+		return nil, errors.New("no OpenPGP key file configured")
+	}
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var keyRing openpgp.EntityList
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN PGP")) {
+		keyRing, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	} else {
+		keyRing, err = openpgp.ReadKeyRing(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(keyRing) == 0 {
+		return nil, errors.Errorf("no OpenPGP keys found in %s", fileName)
+	}
+	return keyRing, nil
+}
@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/compress"
+)
+
+// Suffix appended to the name of a dump to get the name of its manifest
+const Suffix = ".manifest.json"
+
+var (
+	ErrNoManifest = fmt.Errorf("no manifest found")
+	ErrMismatch   = fmt.Errorf("dump doesn't match its manifest")
+)
+
+// Manifest is an integrity record written next to each dump
+type Manifest struct {
+	// File is the base name of the dump
+	File        string    `json:"file"`
+	SHA256      string    `json:"sha256"`
+	Size        int64     `json:"size"`
+	Kind        string    `json:"kind"`
+	ToolVersion string    `json:"toolVersion"`
+	SourceHost  string    `json:"sourceHost"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// FileName returns the name of the manifest of the given dump
+func FileName(dumpPath string) string {
+	return dumpPath + Suffix
+}
+
+// IsRegularFile returns whether the given path is a regular file, manifests are only written for those
+func IsRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Write creates the manifest of the given dump and writes it next to it
+func Write(dumpPath, kind, toolVersion, sourceHost string) (*Manifest, error) {
+	checksum, size, err := checksumFile(dumpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		File:        filepath.Base(dumpPath),
+		SHA256:      checksum,
+		Size:        size,
+		Kind:        kind,
+		ToolVersion: toolVersion,
+		SourceHost:  sourceHost,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	err = compress.WriteFileAtomic(FileName(dumpPath), func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return errors.WithStack(encoder.Encode(m))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Read reads the manifest of the given dump, the error wraps ErrNoManifest if there is none
+func Read(dumpPath string) (*Manifest, error) {
+	content, err := os.ReadFile(FileName(dumpPath))
+	if os.IsNotExist(err) {
+		return nil, errors.WithStack(fmt.Errorf("%w for %s", ErrNoManifest, dumpPath))
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	m := &Manifest{}
+	if err = json.Unmarshal(content, m); err != nil {
+		return nil, errors.WithStack(fmt.Errorf("invalid manifest %s: %w", FileName(dumpPath), err))
+	}
+	return m, nil
+}
+
+// Verify checks the size and checksum of the given dump against its manifest,
+// the error wraps ErrMismatch if they differ and ErrNoManifest if there is no manifest
+func Verify(dumpPath string) (*Manifest, error) {
+	m, err := Read(dumpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, size, err := checksumFile(dumpPath)
+	if err != nil {
+		return m, err
+	}
+
+	if size != m.Size {
+		return m, errors.WithStack(fmt.Errorf("%w: %s has %d bytes, expected %d", ErrMismatch, dumpPath, size, m.Size))
+	}
+	if checksum != m.SHA256 {
+		return m, errors.WithStack(fmt.Errorf("%w: %s has checksum %s, expected %s", ErrMismatch, dumpPath, checksum, m.SHA256))
+	}
+	return m, nil
+}
+
+// checksumFile returns the hex encoded SHA-256 checksum and the size of the given file
+func checksumFile(fileName string) (checksum string, size int64, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", 0, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err = io.Copy(hash, file)
+	if err != nil {
+		return "", 0, errors.WithStack(err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
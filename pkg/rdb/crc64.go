@@ -0,0 +1,27 @@
+package rdb
+
+// crc64JonesReflected is the reflected form of the Jones polynomial 0xad93d23594c935a9 used by redis
+const crc64JonesReflected = 0x95ac9329ac4bc9b5
+
+var crc64Table = func() (table [256]uint64) {
+	for i := range table {
+		crc := uint64(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ crc64JonesReflected
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC64 updates the CRC64 of redis with p, which in contrast to hash/crc64 neither inverts the initial nor the final value
+func CRC64(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crc64Table[byte(crc)^b] ^ crc>>8
+	}
+	return crc
+}
@@ -0,0 +1,52 @@
+package rdb
+
+import (
+	"fmt"
+)
+
+// lzfDecompress decompresses the LZF compressed strings of rdb files
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		// literal run of ctrl+1 bytes
+		if ctrl < 1<<5 {
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, fmt.Errorf("%w: lzf literal exceeds input", ErrInvalidFormat)
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		// back reference of length+2 bytes
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return nil, fmt.Errorf("%w: truncated lzf back reference", ErrInvalidFormat)
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, fmt.Errorf("%w: truncated lzf back reference", ErrInvalidFormat)
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, fmt.Errorf("%w: lzf back reference out of bounds", ErrInvalidFormat)
+		}
+		// copy byte by byte, as the reference may overlap the output
+		for j := 0; j < length+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+
+	if len(out) != outLen {
+		return nil, fmt.Errorf("%w: lzf string has %d bytes, expected %d", ErrInvalidFormat, len(out), outLen)
+	}
+	return out, nil
+}
@@ -0,0 +1,599 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidFormat = fmt.Errorf("invalid rdb format")
+	ErrUnsupported   = fmt.Errorf("unsupported rdb content")
+)
+
+const (
+	Magic = "REDIS"
+	// HeaderSize is the size of the magic string followed by the four digit version
+	HeaderSize = len(Magic) + 4
+	// ChecksumSize is the size of the CRC64 checksum following the EOF opcode
+	ChecksumSize = 8
+	// ChecksumVersion is the first rdb version with checksum
+	ChecksumVersion = 5
+)
+
+// opcodes preceding the keys and values of rdb files
+const (
+	opcodeSlotInfo      = 0xF4
+	opcodeFunction2     = 0xF5
+	opcodeFunctionPreGA = 0xF6
+	opcodeModuleAux     = 0xF7
+	opcodeIdle          = 0xF8
+	opcodeFreq          = 0xF9
+	opcodeAux           = 0xFA
+	opcodeResizeDB      = 0xFB
+	opcodeExpireTimeMS  = 0xFC
+	opcodeExpireTime    = 0xFD
+	opcodeSelectDB      = 0xFE
+	OpcodeEOF           = 0xFF
+)
+
+// types of values
+const (
+	typeString              = 0
+	typeList                = 1
+	typeSet                 = 2
+	typeZset                = 3
+	typeHash                = 4
+	typeZset2               = 5
+	typeModulePreGA         = 6
+	typeModule2             = 7
+	typeHashZipmap          = 9
+	typeListZiplist         = 10
+	typeSetIntset           = 11
+	typeZsetZiplist         = 12
+	typeHashZiplist         = 13
+	typeListQuicklist       = 14
+	typeStreamListpacks     = 15
+	typeHashListpack        = 16
+	typeZsetListpack        = 17
+	typeListQuicklist2      = 18
+	typeStreamListpacks2    = 19
+	typeSetListpack         = 20
+	typeStreamListpacks3    = 21
+	typeHashMetadataPreGA   = 22
+	typeHashListpackExPreGA = 23
+	typeHashMetadata        = 24
+	typeHashListpackEx      = 25
+)
+
+// opcodes of values of modules
+const (
+	moduleOpcodeEOF    = 0
+	moduleOpcodeSInt   = 1
+	moduleOpcodeUInt   = 2
+	moduleOpcodeFloat  = 3
+	moduleOpcodeDouble = 4
+	moduleOpcodeString = 5
+)
+
+// encodings of strings, flagged by the two most significant bits of their length
+const (
+	encodingInt8  = 0
+	encodingInt16 = 1
+	encodingInt32 = 2
+	encodingLZF   = 3
+)
+
+// streamIDSize is the size of the raw stream IDs of pending entries
+const streamIDSize = 16
+
+// Entry is a key of an rdb file
+type Entry struct {
+	DB  int
+	Key []byte
+	// ExpireAt is the absolute expiry in unix milliseconds, 0 if the key doesn't expire
+	ExpireAt int64
+	// Payload is the value in the serialization format of DUMP, thus it can be loaded with RESTORE
+	Payload []byte
+}
+
+// Reader reads the keys of an rdb file one by one
+type Reader struct {
+	r       *bufio.Reader
+	version int
+	db      int
+	done    bool
+	// capture records the bytes of the value currently read
+	capture *bytes.Buffer
+	// Functions is the number of skipped function libraries
+	Functions int
+}
+
+// NewReader reads the header of the given rdb file
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	header, err := reader.readFull(HeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(header, []byte(Magic)) {
+		return nil, errors.WithStack(fmt.Errorf("%w: file doesn't start with '%s'", ErrInvalidFormat, Magic))
+	}
+	reader.version, err = strconv.Atoi(string(header[len(Magic):]))
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%w: invalid version '%s'", ErrInvalidFormat, header[len(Magic):]))
+	}
+	return reader, nil
+}
+
+// Version returns the rdb version of the file
+func (r *Reader) Version() int {
+	return r.version
+}
+
+// Next returns the next key, io.EOF is returned at the end of the file
+//
+//nolint:cyclop // one case per opcode
+func (r *Reader) Next() (*Entry, error) {
+	var expireAt int64
+	for !r.done {
+		opcode, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case OpcodeEOF:
+			r.done = true
+		case opcodeSelectDB:
+			db, dbErr := r.readLength()
+			if dbErr != nil {
+				return nil, dbErr
+			}
+			r.db = int(db) //nolint: gosec // db numbers are small
+		case opcodeExpireTime:
+			seconds, expireErr := r.readFull(4)
+			if expireErr != nil {
+				return nil, expireErr
+			}
+			expireAt = int64(int32(binary.LittleEndian.Uint32(seconds))) * 1000 //nolint: gosec // signed by definition
+		case opcodeExpireTimeMS:
+			millis, expireErr := r.readFull(8)
+			if expireErr != nil {
+				return nil, expireErr
+			}
+			expireAt = int64(binary.LittleEndian.Uint64(millis)) //nolint: gosec // signed by definition
+		case opcodeResizeDB:
+			err = r.skipLengths(2)
+		case opcodeSlotInfo:
+			err = r.skipLengths(3)
+		case opcodeAux:
+			if err = r.skipString(); err == nil {
+				err = r.skipString()
+			}
+		case opcodeFreq:
+			_, err = r.readByte()
+		case opcodeIdle:
+			err = r.skipLengths(1)
+		case opcodeModuleAux:
+			// module id, when opcode and when, followed by the module's data
+			if err = r.skipLengths(3); err == nil {
+				err = r.skipModuleValue()
+			}
+		case opcodeFunction2:
+			r.Functions++
+			err = r.skipString()
+		case opcodeFunctionPreGA:
+			return nil, errors.WithStack(fmt.Errorf("%w: functions of pre-GA versions of redis 7", ErrUnsupported))
+		default:
+			return r.readEntry(opcode, expireAt)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, io.EOF
+}
+
+// readEntry reads the key and value of the given type and serializes the value like DUMP
+func (r *Reader) readEntry(valueType byte, expireAt int64) (*Entry, error) {
+	key, err := r.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	r.capture = bytes.NewBuffer([]byte{valueType})
+	err = r.skipValue(valueType)
+	payload := r.capture
+	r.capture = nil
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("key '%s': %w", key, err))
+	}
+
+	// DUMP appends the rdb version and the checksum of the payload
+	payload.Write(binary.LittleEndian.AppendUint16(nil, uint16(r.version))) //nolint: gosec // versions are four digits
+	payload.Write(binary.LittleEndian.AppendUint64(nil, CRC64(0, payload.Bytes())))
+
+	return &Entry{
+		DB:       r.db,
+		Key:      key,
+		ExpireAt: expireAt,
+		Payload:  payload.Bytes(),
+	}, nil
+}
+
+// skipValue reads the value of the given type
+//
+//nolint:cyclop // one case per type
+func (r *Reader) skipValue(valueType byte) error {
+	switch valueType {
+	case typeString, typeHashZipmap, typeListZiplist, typeSetIntset, typeZsetZiplist, typeHashZiplist,
+		typeHashListpack, typeZsetListpack, typeSetListpack:
+		return r.skipString()
+	case typeList, typeSet, typeListQuicklist:
+		return r.skipElements(1, r.skipString)
+	case typeHash:
+		return r.skipElements(2, r.skipString)
+	case typeZset:
+		return r.skipElements(1, func() error {
+			if err := r.skipString(); err != nil {
+				return err
+			}
+			return r.skipDoubleString()
+		})
+	case typeZset2:
+		return r.skipElements(1, func() error {
+			if err := r.skipString(); err != nil {
+				return err
+			}
+			return r.skip(8)
+		})
+	case typeListQuicklist2:
+		// container type followed by the node
+		return r.skipElements(1, func() error {
+			if err := r.skipLengths(1); err != nil {
+				return err
+			}
+			return r.skipString()
+		})
+	case typeModule2:
+		if err := r.skipLengths(1); err != nil {
+			return err
+		}
+		return r.skipModuleValue()
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		return r.skipStream(valueType)
+	case typeHashMetadata:
+		// min expiry followed by ttl, field and value of each field
+		if err := r.skip(8); err != nil {
+			return err
+		}
+		return r.skipElements(1, func() error {
+			if err := r.skipLengths(1); err != nil {
+				return err
+			}
+			if err := r.skipString(); err != nil {
+				return err
+			}
+			return r.skipString()
+		})
+	case typeHashListpackEx:
+		// min expiry followed by the listpack
+		if err := r.skip(8); err != nil {
+			return err
+		}
+		return r.skipString()
+	case typeModulePreGA, typeHashMetadataPreGA, typeHashListpackExPreGA:
+		return errors.WithStack(fmt.Errorf("%w: value type %d of pre-GA versions of redis", ErrUnsupported, valueType))
+	default:
+		return errors.WithStack(fmt.Errorf("%w: unknown value type %d", ErrUnsupported, valueType))
+	}
+}
+
+// skipStream reads a stream of the given type
+func (r *Reader) skipStream(valueType byte) error {
+	// listpacks, each with its master ID and its entries
+	if err := r.skipElements(2, r.skipString); err != nil {
+		return err
+	}
+	// length and last ID, newer versions add the first ID, the max deleted ID and the number of added entries
+	metadata := 3
+	if valueType >= typeStreamListpacks2 {
+		metadata += 5
+	}
+	if err := r.skipLengths(metadata); err != nil {
+		return err
+	}
+
+	return r.skipElements(1, func() error {
+		return r.skipConsumerGroup(valueType)
+	})
+}
+
+// skipConsumerGroup reads a consumer group of a stream of the given type
+func (r *Reader) skipConsumerGroup(valueType byte) error {
+	if err := r.skipString(); err != nil {
+		return err
+	}
+	// last delivered ID, newer versions add the number of read entries
+	metadata := 2
+	if valueType >= typeStreamListpacks2 {
+		metadata++
+	}
+	if err := r.skipLengths(metadata); err != nil {
+		return err
+	}
+
+	// pending entries with their ID, delivery time and delivery count
+	err := r.skipElements(1, func() error {
+		if err := r.skip(streamIDSize + 8); err != nil {
+			return err
+		}
+		return r.skipLengths(1)
+	})
+	if err != nil {
+		return err
+	}
+
+	// consumers with their name, seen time, active time in newer versions, and pending entry IDs
+	return r.skipElements(1, func() error {
+		if err := r.skipString(); err != nil {
+			return err
+		}
+		times := 8
+		if valueType >= typeStreamListpacks3 {
+			times += 8
+		}
+		if err := r.skip(times); err != nil {
+			return err
+		}
+		return r.skipElements(1, func() error {
+			return r.skip(streamIDSize)
+		})
+	})
+}
+
+// skipModuleValue reads the data of a module up to its EOF opcode
+func (r *Reader) skipModuleValue() error {
+	for {
+		opcode, err := r.readLength()
+		if err != nil {
+			return err
+		}
+
+		switch opcode {
+		case moduleOpcodeEOF:
+			return nil
+		case moduleOpcodeSInt, moduleOpcodeUInt:
+			err = r.skipLengths(1)
+		case moduleOpcodeFloat:
+			err = r.skip(4)
+		case moduleOpcodeDouble:
+			err = r.skip(8)
+		case moduleOpcodeString:
+			err = r.skipString()
+		default:
+			return errors.WithStack(fmt.Errorf("%w: unknown module opcode %d", ErrInvalidFormat, opcode))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// skipElements reads a length and calls skipElement multiplier times for each of them
+func (r *Reader) skipElements(multiplier uint64, skipElement func() error) error {
+	length, err := r.readLength()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < length*multiplier; i++ {
+		if err = skipElement(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipLengths reads the given number of lengths
+func (r *Reader) skipLengths(count int) error {
+	for i := 0; i < count; i++ {
+		if _, err := r.readLength(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipDoubleString reads a double stored as string of at most 255 bytes
+func (r *Reader) skipDoubleString() error {
+	length, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	// 253 to 255 are NaN and infinities without any further bytes
+	if length >= 253 {
+		return nil
+	}
+	return r.skip(int(length))
+}
+
+// readLength reads a length, which mustn't be an encoded string
+func (r *Reader) readLength() (uint64, error) {
+	length, encoded, err := r.readLengthOrEncoding()
+	if err != nil {
+		return 0, err
+	}
+	if encoded {
+		return 0, errors.WithStack(fmt.Errorf("%w: expected length, got encoded string", ErrInvalidFormat))
+	}
+	return length, nil
+}
+
+// readLengthOrEncoding reads a length or the encoding of a string
+func (r *Reader) readLengthOrEncoding() (length uint64, encoded bool, err error) {
+	first, err := r.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch first >> 6 {
+	case 0:
+		return uint64(first & 0x3f), false, nil
+	case 1:
+		second, secondErr := r.readByte()
+		if secondErr != nil {
+			return 0, false, secondErr
+		}
+		return uint64(first&0x3f)<<8 | uint64(second), false, nil
+	case 2:
+		switch first {
+		case 0x80:
+			b, readErr := r.readFull(4)
+			if readErr != nil {
+				return 0, false, readErr
+			}
+			return uint64(binary.BigEndian.Uint32(b)), false, nil
+		case 0x81:
+			b, readErr := r.readFull(8)
+			if readErr != nil {
+				return 0, false, readErr
+			}
+			return binary.BigEndian.Uint64(b), false, nil
+		default:
+			return 0, false, errors.WithStack(fmt.Errorf("%w: unknown length encoding %x", ErrInvalidFormat, first))
+		}
+	default:
+		return uint64(first & 0x3f), true, nil
+	}
+}
+
+// readString reads and decodes a string
+func (r *Reader) readString() ([]byte, error) {
+	length, encoded, err := r.readLengthOrEncoding()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return r.readFull(int(length)) //nolint: gosec // bounded by the size of the file
+	}
+
+	switch length {
+	case encodingInt8:
+		b, readErr := r.readFull(1)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return []byte(strconv.Itoa(int(int8(b[0])))), nil //nolint: gosec // signed by definition
+	case encodingInt16:
+		b, readErr := r.readFull(2)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return []byte(strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b))))), nil //nolint: gosec // signed by definition
+	case encodingInt32:
+		b, readErr := r.readFull(4)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return []byte(strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b))))), nil //nolint: gosec // signed by definition
+	case encodingLZF:
+		compressedLength, lengthErr := r.readLength()
+		if lengthErr != nil {
+			return nil, lengthErr
+		}
+		plainLength, lengthErr := r.readLength()
+		if lengthErr != nil {
+			return nil, lengthErr
+		}
+		compressed, readErr := r.readFull(int(compressedLength)) //nolint: gosec // bounded by the size of the file
+		if readErr != nil {
+			return nil, readErr
+		}
+		plain, lzfErr := lzfDecompress(compressed, int(plainLength)) //nolint: gosec // bounded by the size of the file
+		return plain, errors.WithStack(lzfErr)
+	default:
+		return nil, errors.WithStack(fmt.Errorf("%w: unknown string encoding %d", ErrInvalidFormat, length))
+	}
+}
+
+// skipString reads a string without decoding it
+func (r *Reader) skipString() error {
+	length, encoded, err := r.readLengthOrEncoding()
+	if err != nil {
+		return err
+	}
+	if !encoded {
+		return r.skip(int(length)) //nolint: gosec // bounded by the size of the file
+	}
+
+	switch length {
+	case encodingInt8:
+		return r.skip(1)
+	case encodingInt16:
+		return r.skip(2)
+	case encodingInt32:
+		return r.skip(4)
+	case encodingLZF:
+		compressedLength, lengthErr := r.readLength()
+		if lengthErr != nil {
+			return lengthErr
+		}
+		if lengthErr = r.skipLengths(1); lengthErr != nil {
+			return lengthErr
+		}
+		return r.skip(int(compressedLength)) //nolint: gosec // bounded by the size of the file
+	default:
+		return errors.WithStack(fmt.Errorf("%w: unknown string encoding %d", ErrInvalidFormat, length))
+	}
+}
+
+// readByte reads a single byte, which is captured if a value is read
+func (r *Reader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return 0, truncated(err)
+	}
+	if r.capture != nil {
+		r.capture.WriteByte(b)
+	}
+	return b, nil
+}
+
+// readFull reads n bytes, which are captured if a value is read
+func (r *Reader) readFull(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, truncated(err)
+	}
+	if r.capture != nil {
+		r.capture.Write(b)
+	}
+	return b, nil
+}
+
+// skip reads n bytes without keeping them, unless a value is read
+func (r *Reader) skip(n int) error {
+	var w io.Writer = io.Discard
+	if r.capture != nil {
+		w = r.capture
+	}
+	if _, err := io.CopyN(w, r.r, int64(n)); err != nil {
+		return truncated(err)
+	}
+	return nil
+}
+
+// truncated reports unexpected ends of the file as invalid format
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errors.WithStack(fmt.Errorf("%w: unexpected end of file", ErrInvalidFormat))
+	}
+	return errors.WithStack(err)
+}
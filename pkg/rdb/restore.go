@@ -0,0 +1,94 @@
+package rdb
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// skipExistingScript restores a key only if it doesn't exist yet, as RESTORE without REPLACE fails for existing keys
+const skipExistingScript = "if redis.call('EXISTS', KEYS[1]) == 0 then " +
+	"return redis.call('RESTORE', KEYS[1], ARGV[1], ARGV[2], 'ABSTTL') end return 0"
+
+// RestoreOptions of the commands written by WriteRestoreCommands
+type RestoreOptions struct {
+	// Flush removes the keys of all databases before restoring. FLUSHALL is written before the first key is read,
+	// thus the whole file should have been read successfully before.
+	Flush bool
+	// SkipExisting keeps existing keys instead of replacing them
+	SkipExisting bool
+	// Now is used to skip keys which are already expired
+	Now time.Time
+}
+
+// RestoreStats counts the keys of WriteRestoreCommands
+type RestoreStats struct {
+	// Keys is the number of restored keys
+	Keys int
+	// Expired is the number of skipped keys, which are already expired
+	Expired int
+}
+
+// WriteRestoreCommands writes the commands restoring all keys of the rdb file in the redis protocol,
+// as expected by "redis-cli --pipe"
+func WriteRestoreCommands(w io.Writer, r *Reader, opts RestoreOptions) (*RestoreStats, error) {
+	buffered := bufio.NewWriter(w)
+	stats := &RestoreStats{}
+
+	if opts.Flush {
+		if err := writeCommand(buffered, "FLUSHALL"); err != nil {
+			return stats, err
+		}
+	}
+
+	db := -1
+	for {
+		entry, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		if entry.ExpireAt > 0 && entry.ExpireAt <= opts.Now.UnixMilli() {
+			stats.Expired++
+			continue
+		}
+
+		if entry.DB != db {
+			db = entry.DB
+			if err = writeCommand(buffered, "SELECT", strconv.Itoa(db)); err != nil {
+				return stats, err
+			}
+		}
+
+		ttl := strconv.FormatInt(entry.ExpireAt, 10)
+		if opts.SkipExisting {
+			err = writeCommand(buffered, "EVAL", skipExistingScript, "1", string(entry.Key), ttl, string(entry.Payload))
+		} else {
+			err = writeCommand(buffered, "RESTORE", string(entry.Key), ttl, string(entry.Payload), "REPLACE", "ABSTTL")
+		}
+		if err != nil {
+			return stats, err
+		}
+		stats.Keys++
+	}
+
+	return stats, errors.WithStack(buffered.Flush())
+}
+
+// writeCommand writes the given command as array of bulk strings
+func writeCommand(w *bufio.Writer, args ...string) error {
+	_, err := w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		if err != nil {
+			break
+		}
+		_, err = w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	return errors.WithStack(err)
+}
@@ -3,17 +3,29 @@ package restic
 import (
 	"context"
 	"fmt"
+	"io"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/retry"
+	"github.com/mittwald/brudi/pkg/timeout"
 )
 
 type Client struct {
 	Logger *log.Entry
 	Config *Config
+	// Retrier retries the restic stages on transient errors
+	Retrier *retry.Retrier
+	// ProgressHandlers are called with the status of "restic backup" in the configured progress interval
+	ProgressHandlers []ProgressFunc
+	// SummaryHandlers are called with the summary of every successful "restic backup"
+	SummaryHandlers []SummaryFunc
 }
 
-func NewResticClient(logger *log.Entry, hostname string, backupPaths ...string) (*Client, error) {
+// NewResticClient creates a Client for the given kind, whose forget policy is scoped to the given host and paths
+func NewResticClient(logger *log.Entry, kind, hostname string, backupPaths ...string) (*Client, error) {
 	conf := &Config{
 		Global: &GlobalOptions{
 			Flags: &GlobalFlags{},
@@ -30,6 +42,14 @@ func NewResticClient(logger *log.Entry, hostname string, backupPaths ...string)
 			Flags: &RestoreFlags{},
 			ID:    "",
 		},
+		Progress: &ProgressOptions{
+			Interval: defaultProgressInterval,
+		},
+		Locks: &LockOptions{
+			MaxAge:       defaultLockMaxAge,
+			WaitTimeout:  defaultLockWaitTimeout,
+			PollInterval: defaultLockPollInterval,
+		},
 	}
 
 	err := conf.InitFromViper()
@@ -37,58 +57,246 @@ func NewResticClient(logger *log.Entry, hostname string, backupPaths ...string)
 		return nil, errors.WithStack(err)
 	}
 
+	err = conf.InitForgetForKind(kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	if (conf.Backup.Flags.Host) == "" {
 		conf.Backup.Flags.Host = hostname
 	}
 
 	conf.Backup.Paths = append(conf.Backup.Paths, backupPaths...)
+	conf.ScopeForget()
 	resticLogger := logger.WithField("cmd", "restic")
 	// obtain backup path for restic
-	conf.Restore.Flags.Path = backupPaths[0]
+	if len(backupPaths) > 0 {
+		conf.Restore.Flags.Path = backupPaths[0]
+	}
+	retrier, err := retry.NewRetrier()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	return &Client{
-		Logger: resticLogger,
-		Config: conf,
+		Logger:  resticLogger,
+		Config:  conf,
+		Retrier: retrier,
 	}, nil
 }
 
+// runStage runs fn with the stage's timeout for each attempt and retries it on transient errors
+func (c *Client) runStage(ctx context.Context, stage string, fn func(ctx context.Context) error) error {
+	return c.Retrier.Do(ctx, c.Logger, stage, IsTransient, func() error {
+		return timeout.Run(ctx, stage, fn)
+	}, nil)
+}
+
 func (c *Client) DoResticBackup(ctx context.Context) error {
 	c.Logger.Info("running 'restic backup'")
 
-	_, err := initBackup(ctx, c.Config.Global)
-	if errors.Is(err, ErrRepoAlreadyInitialized) {
-		c.Logger.Info("restic repo is already initialized")
-	} else if err != nil {
-		return errors.WithStack(fmt.Errorf("error while initializing restic repository: %s", err.Error()))
-	} else {
-		c.Logger.Info("restic repo initialized successfully")
+	err := c.ensureRepository(ctx)
+	if err != nil {
+		return err
+	}
+
+	progress := c.newProgressReporter()
+
+	var out []byte
+	var result BackupResult
+	err = c.runStage(ctx, retry.StageBackup, func(ctx context.Context) error {
+		return c.retryLocked(ctx, false, func() (err error) {
+			result, out, err = CreateBackup(ctx, c.Config.Global, c.Config.Backup, progress)
+			return err
+		})
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("error while running restic backup: %w - %s", err, out))
+	}
+	c.reportSummary(result.Summary)
+
+	c.Logger.Info("successfully saved restic stuff")
+
+	return nil
+}
+
+// DoResticBackupFromStdin executes "restic backup --stdin" and saves everything written by write as the given file.
+// The configured backup paths are ignored. write is called once per attempt, thus it has to be repeatable.
+func (c *Client) DoResticBackupFromStdin(ctx context.Context, fileName string, write func(w io.Writer) error) error {
+	c.Logger.WithField("stdinFilename", fileName).Info("running 'restic backup --stdin'")
+
+	err := c.ensureRepository(ctx)
+	if err != nil {
+		return err
 	}
 
+	progress := c.newProgressReporter()
+
+	flags := *c.Config.Backup.Flags
+	flags.StdinFilename = fileName
+	backupOpts := &BackupOptions{Flags: &flags}
+
 	var out []byte
-	_, out, err = CreateBackup(ctx, c.Config.Global, c.Config.Backup, true)
+	var result BackupResult
+	err = c.runStage(ctx, retry.StageBackup, func(ctx context.Context) error {
+		return c.retryLocked(ctx, false, func() (err error) {
+			result, out, err = CreateBackupFromStdin(ctx, c.Config.Global, backupOpts, progress, write)
+			return err
+		})
+	})
 	if err != nil {
-		return errors.WithStack(fmt.Errorf("error while running restic backup: %s - %s", err.Error(), out))
+		return errors.WithStack(fmt.Errorf("error while running restic backup: %w - %s", err, out))
 	}
+	c.reportSummary(result.Summary)
 
 	c.Logger.Info("successfully saved restic stuff")
 
 	return nil
 }
 
+// newProgressReporter returns the ProgressReporter of "restic backup", or nil if progress reporting is disabled
+func (c *Client) newProgressReporter() *ProgressReporter {
+	if c.Config.Progress.Interval <= 0 {
+		return nil
+	}
+	handlers := append([]ProgressFunc{LogProgress(c.Logger)}, c.ProgressHandlers...)
+	return NewProgressReporter(c.Config.Progress.Interval, handlers...)
+}
+
+// reportSummary logs the summary of "restic backup" and passes it on to the SummaryHandlers
+func (c *Client) reportSummary(summary *BackupSummary) {
+	if summary == nil {
+		return
+	}
+	for _, handler := range append([]SummaryFunc{LogSummary(c.Logger)}, c.SummaryHandlers...) {
+		handler(summary)
+	}
+}
+
+// ensureRepository initializes the restic repository, but only if it doesn't exist yet
+func (c *Client) ensureRepository(ctx context.Context) error {
+	var out []byte
+	err := c.runStage(ctx, retry.StageInit, func(ctx context.Context) (err error) {
+		out, err = catConfig(ctx, c.Config.Global)
+		return err
+	})
+	if err == nil {
+		c.Logger.Info("restic repo is already initialized")
+		return nil
+	}
+	if !errors.Is(err, ErrRepoNotExist) {
+		return errors.WithStack(fmt.Errorf("error while opening restic repository: %w - %s", err, out))
+	}
+
+	err = c.runStage(ctx, retry.StageInit, func(ctx context.Context) (err error) {
+		_, err = initBackup(ctx, c.Config.Global)
+		return err
+	})
+	if errors.Is(err, ErrRepoAlreadyInitialized) {
+		// another job initialized the repository in the meantime
+		c.Logger.Info("restic repo is already initialized")
+		return nil
+	} else if err != nil {
+		return errors.WithStack(fmt.Errorf("error while initializing restic repository: %w", err))
+	}
+
+	c.Logger.Info("restic repo initialized successfully")
+	return nil
+}
+
 func (c *Client) DoResticRestore(ctx context.Context, backupPath string) error {
-	c.Logger.Info("running 'restic restore'")
-	out, err := RestoreBackup(ctx, c.Config.Global, c.Config.Restore, false)
+	return c.restore(ctx, c.Config.Restore)
+}
+
+// DoResticRestoreInto restores the given snapshot into target instead of the configured target, limited to the
+// given paths unless includes are configured. The files keep their path of the snapshot below target,
+// see RestoredPath.
+func (c *Client) DoResticRestoreInto(ctx context.Context, id, target string, include ...string) error {
+	flags := *c.Config.Restore.Flags
+	flags.Target = target
+	if len(flags.Include) == 0 {
+		flags.Include = include
+	}
+	return c.restore(ctx, &RestoreOptions{Flags: &flags, ID: id})
+}
+
+// RestoredPath returns the path restic restores the given path of a snapshot to, when restoring into target.
+// Snapshots hold absolute paths, e.g. "/tmp/dump.sql" is restored to "<target>/tmp/dump.sql".
+func RestoredPath(target, path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return filepath.Join(target, absPath), nil
+}
+
+func (c *Client) restore(ctx context.Context, opts *RestoreOptions) error {
+	c.Logger.WithField("target", opts.Flags.Target).Info("running 'restic restore'")
+	var out []byte
+	err := c.runStage(ctx, retry.StageRestore, func(ctx context.Context) error {
+		return c.retryLocked(ctx, false, func() (err error) {
+			out, err = RestoreBackup(ctx, c.Config.Global, opts)
+			return err
+		})
+	})
 	if err != nil {
-		return errors.WithStack(fmt.Errorf("error while running restic restore: %s - %s", err.Error(), out))
+		return errors.WithStack(fmt.Errorf("error while running restic restore: %w - %s", err, out))
 	}
 	return nil
 }
 
+// DoResticLs lists the files of the snapshot which is restored by DoResticRestore, including their size,
+// mode and owner
+func (c *Client) DoResticLs(ctx context.Context) (*LsResult, error) {
+	id := c.Config.Restore.ID
+	if id == "" {
+		id = "latest"
+	}
+	opts := &LsOptions{
+		Flags: &LsFlags{
+			Host: c.Config.Restore.Flags.Host,
+			Long: true,
+			Path: c.Config.Restore.Flags.Path,
+			Tag:  c.Config.Restore.Flags.Tags,
+		},
+		SnapshotIDs: []string{id},
+	}
+
+	var results []LsResult
+	err := c.runStage(ctx, retry.StageRestore, func(ctx context.Context) error {
+		return c.retryLocked(ctx, false, func() (err error) {
+			results, err = Ls(ctx, c.Config.Global, opts)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("error while running restic ls: %w", err))
+	}
+	if len(results) == 0 || results[0].SnapshotID == "" {
+		return nil, errors.WithStack(fmt.Errorf("snapshot %s not found", id))
+	}
+	return &results[0], nil
+}
+
 func (c *Client) DoResticForget(ctx context.Context) error {
-	c.Logger.Info("running 'restic forget'")
+	c.Logger.WithFields(
+		log.Fields{
+			"host":  c.Config.Forget.Flags.Host,
+			"paths": c.Config.Forget.Flags.Paths,
+			"tags":  c.Config.Forget.Flags.Tags,
+		},
+	).Info("running 'restic forget'")
 
-	removedSnapshots, output, err := Forget(ctx, c.Config.Global, c.Config.Forget)
+	var removedSnapshots []string
+	var output []byte
+	err := c.runStage(ctx, retry.StageForget, func(ctx context.Context) error {
+		return c.retryLocked(ctx, true, func() (err error) {
+			removedSnapshots, output, err = Forget(ctx, c.Config.Global, c.Config.Forget)
+			return err
+		})
+	})
 	if err != nil {
-		return errors.WithStack(fmt.Errorf("%s - %s", err.Error(), output))
+		return errors.WithStack(fmt.Errorf("%w - %s", err, output))
 	}
 
 	c.Logger.WithFields(
@@ -100,12 +308,73 @@ func (c *Client) DoResticForget(ctx context.Context) error {
 	return nil
 }
 
+// DoResticForgetReport executes "restic forget" and reports the kept and removed snapshots per group,
+// including an estimate of the space "restic prune" will reclaim afterwards
+func (c *Client) DoResticForgetReport(ctx context.Context) (*ForgetReport, error) {
+	c.Logger.Info("running 'restic forget' with report")
+
+	// the size of all snapshots has to be determined beforehand, removed snapshots are gone afterwards
+	snapshots, err := ListSnapshots(ctx, c.Config.Global, &SnapshotOptions{Flags: &SnapshotFlags{}})
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("error while listing restic snapshots: %w", err))
+	}
+	var snapshotIDs []string
+	for idx := range snapshots {
+		if snapshots[idx].ID != nil {
+			snapshotIDs = append(snapshotIDs, *snapshots[idx].ID)
+		}
+	}
+
+	totalSize, err := rawDataSize(ctx, c.Config.Global, snapshotIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var forgetTags []*ForgetTag
+	var output []byte
+	err = c.runStage(ctx, retry.StageForget, func(ctx context.Context) error {
+		return c.retryLocked(ctx, !c.Config.Forget.Flags.DryRun, func() (err error) {
+			forgetTags, output, err = ForgetGroups(ctx, c.Config.Global, c.Config.Forget)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%w - %s", err, output))
+	}
+
+	removed := removedIDs(forgetTags)
+	var keptIDs []string
+	for _, id := range snapshotIDs {
+		if _, ok := removed[id]; !ok {
+			keptIDs = append(keptIDs, id)
+		}
+	}
+
+	keptSize, err := rawDataSize(ctx, c.Config.Global, keptIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := NewForgetReport(forgetTags, c.Config.Forget.Flags.DryRun)
+	if totalSize > keptSize {
+		report.ReclaimableSize = totalSize - keptSize
+	}
+
+	return report, nil
+}
+
 func (c *Client) DoResticPrune(ctx context.Context) error {
 	c.Logger.Info("running 'restic prune'")
 
-	output, err := Prune(ctx, c.Config.Global)
+	var output []byte
+	err := c.runStage(ctx, retry.StagePrune, func(ctx context.Context) error {
+		return c.retryLocked(ctx, true, func() (err error) {
+			output, err = Prune(ctx, c.Config.Global)
+			return err
+		})
+	})
 	if err != nil {
-		return errors.WithStack(fmt.Errorf("%s - %s", err.Error(), output))
+		return errors.WithStack(fmt.Errorf("%w - %s", err, output))
 	}
 
 	// as of now (16.06.2023) there is no JSON-output for prune
@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
 
@@ -23,29 +23,48 @@ const (
 
 var (
 	ErrRepoAlreadyInitialized = fmt.Errorf("repo already initialized")
-
-	cmdTimeout = 6 * time.Hour
 )
 
-// InitBackup executes "restic init"
+// runCommand executes the given restic command and classifies the error, if any
+func runCommand(ctx context.Context, cmd cli.CommandType) ([]byte, error) {
+	out, err := cli.Run(ctx, cmd)
+	return out, ClassifyError(err, out)
+}
+
+// initBackup executes "restic init". If it fails because another job initialized the repository in the meantime,
+// ErrRepoAlreadyInitialized is returned.
 func initBackup(ctx context.Context, globalOpts *GlobalOptions) ([]byte, error) {
 	cmd := newCommand("init", cli.StructToCLI(globalOpts)...)
 
-	out, err := cli.RunWithTimeout(ctx, cmd, cmdTimeout)
-	if err != nil {
-		// s3 init-check
-		if strings.Contains(string(out), "config already initialized") {
-			return out, ErrRepoAlreadyInitialized
-		}
-		// file init-check
-		if strings.Contains(string(out), "file already exists") {
+	out, err := runCommand(ctx, cmd)
+	if err == nil {
+		return out, nil
+	}
+
+	if !SupportsExitCodes(ctx) {
+		// older versions exit with 1 for every error, the messages are the only hint
+		if strings.Contains(string(out), "config already initialized") || // s3
+			strings.Contains(string(out), "file already exists") { // local
 			return out, ErrRepoAlreadyInitialized
 		}
 		return out, err
 	}
+
+	// restic has no exit code for an existing repository, but opening it exits with 0 then instead of 10
+	if _, catErr := catConfig(ctx, globalOpts); catErr == nil {
+		return out, ErrRepoAlreadyInitialized
+	}
 	return out, err
 }
 
+// catConfig executes "restic cat config", which fails if the repository can't be opened
+func catConfig(ctx context.Context, globalOpts *GlobalOptions) ([]byte, error) {
+	args := append(cli.StructToCLI(globalOpts), "config")
+	cmd := newCommand("cat", args...)
+
+	return runCommand(ctx, cmd)
+}
+
 // parseSnapshotOut retrieves snapshot-id and, if available, parent-id from json logs
 func parseSnapshotOut(jsonLog []byte) (BackupResult, error) {
 	var result BackupResult
@@ -61,6 +80,7 @@ func parseSnapshotOut(jsonLog []byte) (BackupResult, error) {
 	for idx := range responseList {
 		v := responseList[idx]
 		if v[messageType] != nil && *v[messageType] == messageTypeSummary {
+			result.Summary = parseSummary(v)
 			if v[snapshotID] != nil {
 				curSnapshotID = (*v[snapshotID]).(string)
 			}
@@ -79,41 +99,65 @@ func parseSnapshotOut(jsonLog []byte) (BackupResult, error) {
 	return result, nil
 }
 
-// CreateBackup executes "restic backup" and returns the parent snapshot id (if available) and the snapshot id
-func CreateBackup(ctx context.Context, globalOpts *GlobalOptions, backupOpts *BackupOptions, unlock bool) (BackupResult, []byte, error) {
-	var out []byte
-	var err error
+// parseSummary converts the given summary message into a BackupSummary, or returns nil if it doesn't fit
+func parseSummary(message map[string]*interface{}) *BackupSummary {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return nil
+	}
+	var summary BackupSummary
+	if err = json.Unmarshal(raw, &summary); err != nil {
+		return nil
+	}
+	return &summary
+}
 
-	if unlock {
-		unlockOpts := UnlockOptions{
-			Flags: &UnlockFlags{
-				RemoveAll: false,
-			},
-		}
-		out, err = Unlock(ctx, globalOpts, &unlockOpts)
-		if err != nil {
-			return BackupResult{}, out, err
-		}
+// parseStatusMessage returns the BackupStatus contained in the given json-log line or nil if it isn't a status message
+func parseStatusMessage(line []byte) *BackupStatus {
+	var message struct {
+		MessageType string `json:"message_type"` //nolint:tagliatelle // upstream type
+	}
+	if err := json.Unmarshal(line, &message); err != nil || message.MessageType != messageTypeStatus {
+		return nil
 	}
 
-	var args []string
-	args = cli.StructToCLI(globalOpts)
-	args = append(args, cli.StructToCLI(backupOpts)...)
+	var status BackupStatus
+	if err := json.Unmarshal(line, &status); err != nil {
+		return nil
+	}
+	return &status
+}
 
-	cmd := newCommand("backup", args...)
+// CreateBackup executes "restic backup" and returns the parent snapshot id (if available) and the snapshot id.
+// Status messages are passed to the given ProgressReporter while the backup is running.
+func CreateBackup(
+	ctx context.Context, globalOpts *GlobalOptions, backupOpts *BackupOptions, progress *ProgressReporter,
+) (BackupResult, []byte, error) {
+	return createBackup(ctx, globalOpts, backupOpts, progress, nil)
+}
+
+// CreateBackupFromStdin executes "restic backup --stdin" like CreateBackup, everything written by write
+// is saved as the file given by the stdin filename of backupOpts
+func CreateBackupFromStdin(
+	ctx context.Context, globalOpts *GlobalOptions, backupOpts *BackupOptions, progress *ProgressReporter,
+	write func(w io.Writer) error,
+) (BackupResult, []byte, error) {
+	flags := *backupOpts.Flags
+	flags.Stdin = true
+	stdinOpts := &BackupOptions{Flags: &flags}
+
+	return createBackup(ctx, globalOpts, stdinOpts, progress, write)
+}
 
-	out, err = cli.RunWithTimeout(ctx, cmd, cmdTimeout)
+func createBackup(
+	ctx context.Context, globalOpts *GlobalOptions, backupOpts *BackupOptions, progress *ProgressReporter,
+	write func(w io.Writer) error,
+) (BackupResult, []byte, error) {
+	out, err := runBackup(ctx, globalOpts, backupOpts, progress, write)
 	if err != nil {
 		return BackupResult{}, out, err
 	}
 
-	// transform output from restic into list of json elements
-	out = []byte(fmt.Sprint(
-		"[" +
-			strings.ReplaceAll(strings.TrimRight(string(out), "\n"), "\n", ",") +
-			"]",
-	))
-
 	var backupRes BackupResult
 	backupRes, err = parseSnapshotOut(out)
 	if err != nil {
@@ -123,6 +167,37 @@ func CreateBackup(ctx context.Context, globalOpts *GlobalOptions, backupOpts *Ba
 	return backupRes, nil, nil
 }
 
+// runBackup executes "restic backup" and returns its json-logs except for status messages.
+// If write is given, everything written by it is passed to the stdin of restic.
+func runBackup(
+	ctx context.Context, globalOpts *GlobalOptions, backupOpts *BackupOptions, progress *ProgressReporter,
+	write func(w io.Writer) error,
+) ([]byte, error) {
+	var args []string
+	args = cli.StructToCLI(globalOpts)
+	args = append(args, cli.StructToCLI(backupOpts)...)
+
+	cmd := newCommand("backup", args...)
+
+	// status messages are handled while restic is running, only the remaining messages are kept for parsing
+	var messages []string
+	handleLine := func(line []byte) {
+		if status := parseStatusMessage(line); status != nil {
+			progress.Report(status)
+			return
+		}
+		messages = append(messages, string(line))
+	}
+
+	out, err := cli.RunWithInputAndLineHandler(ctx, cmd, write, handleLine)
+	if err != nil {
+		return out, ClassifyError(err, out)
+	}
+
+	// transform output from restic into list of json elements
+	return []byte("[" + strings.Join(messages, ",") + "]"), nil
+}
+
 // newCommand initializes an instance of cli.CommandType with given parameters
 func newCommand(command string, args ...string) cli.CommandType {
 	// enable json-logging
@@ -141,7 +216,7 @@ func Ls(ctx context.Context, glob *GlobalOptions, opts *LsOptions) ([]LsResult,
 	args = append(args, cli.StructToCLI(opts)...)
 	cmd := newCommand("ls", args...)
 
-	out, err := cli.Run(ctx, cmd)
+	out, err := runCommand(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +301,7 @@ func GetSnapshotSize(ctx context.Context, snapshotIDs []string) (size uint64) {
 	}
 	cmd := newCommand("stats", cli.StructToCLI(&opts)...)
 
-	out, err := cli.Run(ctx, cmd)
+	out, err := runCommand(ctx, cmd)
 	if err != nil {
 		return
 	}
@@ -238,36 +313,56 @@ func GetSnapshotSize(ctx context.Context, snapshotIDs []string) (size uint64) {
 	return stats.TotalSize
 }
 
-// GetSnapshotSizeByPath returns the summed file size (filtered by its path)...
-// ...of the given snapshot id in bytes, based of the "restic ls -l" command.
-func GetSnapshotSizeByPath(ctx context.Context, snapshotID, path string) (size uint64) {
+// GetSnapshotSizeByPath returns the summed file size of the given snapshot in bytes, filtered by the given path
+// and everything below it, based of the "restic ls -l" command.
+func GetSnapshotSizeByPath(ctx context.Context, glob *GlobalOptions, snapshotID, path string) (size uint64, err error) {
 	opts := LsOptions{
 		Flags: &LsFlags{
 			Long: true,
 		},
 		SnapshotIDs: []string{snapshotID},
 	}
-	ls, err := Ls(ctx, &GlobalOptions{}, &opts)
-
+	ls, err := Ls(ctx, glob, &opts)
 	if err != nil {
-		return
+		return 0, err
 	}
 
+	dirPrefix := strings.TrimSuffix(path, "/") + "/"
 	for _, itm := range ls {
 		for _, f := range itm.Files {
-			if strings.HasPrefix(f.Path, path) {
+			if f.Path == path || strings.HasPrefix(f.Path, dirPrefix) {
 				size += f.Size
 			}
 		}
 	}
-	return
+	return size, nil
+}
+
+// GetStats executes "restic stats"
+func GetStats(ctx context.Context, glob *GlobalOptions, opts *StatsOptions) (*Stats, error) {
+	args := cli.StructToCLI(glob)
+	args = append(args, cli.StructToCLI(opts)...)
+	cmd := newCommand("stats", args...)
+
+	out, err := runCommand(ctx, cmd)
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%s - %s", err.Error(), out))
+	}
+
+	var stats Stats
+	if err = json.Unmarshal(out, &stats); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &stats, nil
 }
 
 // ListSnapshots executes "restic snapshots"
-func ListSnapshots(ctx context.Context, opts *SnapshotOptions) ([]Snapshot, error) {
-	cmd := newCommand("snapshots", cli.StructToCLI(&opts)...)
+func ListSnapshots(ctx context.Context, glob *GlobalOptions, opts *SnapshotOptions) ([]Snapshot, error) {
+	args := cli.StructToCLI(glob)
+	args = append(args, cli.StructToCLI(opts)...)
+	cmd := newCommand("snapshots", args...)
 
-	out, err := cli.Run(ctx, cmd)
+	out, err := runCommand(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +377,7 @@ func ListSnapshots(ctx context.Context, opts *SnapshotOptions) ([]Snapshot, erro
 // Find executes "restic find"
 func Find(ctx context.Context, opts *FindOptions) ([]FindResult, error) {
 	cmd := newCommand("find", cli.StructToCLI(&opts)...)
-	out, err := cli.Run(ctx, cmd)
+	out, err := runCommand(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -298,14 +393,36 @@ func Find(ctx context.Context, opts *FindOptions) ([]FindResult, error) {
 // Check executes "restic check"
 func Check(ctx context.Context, flags *CheckFlags) ([]byte, error) {
 	cmd := newCommand("check", cli.StructToCLI(flags)...)
-	return cli.Run(ctx, cmd)
+	return runCommand(ctx, cmd)
 }
 
-// Forget executes "restic forget"
+// Forget executes "restic forget" and returns the ids of the removed snapshots
 func Forget(
 	ctx context.Context, globalOpts *GlobalOptions, forgetOpts *ForgetOptions,
 ) (
 	[]string, []byte, error,
+) {
+	forgetTags, out, err := ForgetGroups(ctx, globalOpts, forgetOpts)
+	if err != nil {
+		return nil, out, err
+	}
+
+	var deletedSnapshots []string
+	for idx := range forgetTags {
+		for index := range forgetTags[idx].Remove {
+			if forgetTags[idx].Remove[index].ID != nil {
+				deletedSnapshots = append(deletedSnapshots, *forgetTags[idx].Remove[index].ID)
+			}
+		}
+	}
+	return deletedSnapshots, out, nil
+}
+
+// ForgetGroups executes "restic forget" and returns the kept and removed snapshots per group
+func ForgetGroups(
+	ctx context.Context, globalOpts *GlobalOptions, forgetOpts *ForgetOptions,
+) (
+	[]*ForgetTag, []byte, error,
 ) {
 	forgetOpts.Flags.Compact = true // make sure compact mode is enabled to parse result correctly
 
@@ -320,7 +437,7 @@ func Forget(
 		Args:    args,
 	}
 
-	out, err := cli.Run(ctx, cmd)
+	out, err := runCommand(ctx, cmd)
 	if err != nil {
 		return nil, out, err
 	}
@@ -328,28 +445,19 @@ func Forget(
 		return nil, out, errors.New("no restic forget output, check your flag config")
 	}
 
-	var deletedSnapshots []string
 	var forgetTags []*ForgetTag
 	err = json.Unmarshal(out, &forgetTags)
-	forgetResponse := ForgetResponse{Tags: forgetTags}
 	if err != nil {
 		return nil, out, err
 	}
-	for idx := range forgetResponse.Tags {
-		for index := range forgetResponse.Tags[idx].Remove {
-			if forgetResponse.Tags[idx].Remove[index].ID != nil {
-				deletedSnapshots = append(deletedSnapshots, *forgetResponse.Tags[idx].Remove[index].ID)
-			}
-		}
-	}
-	return deletedSnapshots, out, nil
+	return forgetTags, out, nil
 }
 
 // Prune executes "restic prune"
 func Prune(ctx context.Context, globalOpts *GlobalOptions) ([]byte, error) {
 	cmd := newCommand("prune", cli.StructToCLI(globalOpts)...)
 
-	return cli.Run(ctx, cmd)
+	return runCommand(ctx, cmd)
 }
 
 // RebuildIndex executes "restic rebuild-index"
@@ -363,29 +471,17 @@ func RebuildIndex(ctx context.Context) ([]byte, error) {
 		Nice:    &nice,
 		IONice:  &ionice,
 	}
-	return cli.Run(ctx, cmd)
+	return runCommand(ctx, cmd)
 }
 
 // RestoreBackup executes "restic restore"
-func RestoreBackup(ctx context.Context, glob *GlobalOptions, opts *RestoreOptions, unlock bool) ([]byte, error) {
+func RestoreBackup(ctx context.Context, glob *GlobalOptions, opts *RestoreOptions) ([]byte, error) {
 	args := cli.StructToCLI(glob)
 	args = append(args, cli.StructToCLI(opts)...)
 
-	if unlock {
-		unlockOpts := UnlockOptions{
-			Flags: &UnlockFlags{
-				RemoveAll: false,
-			},
-		}
-		_, err := Unlock(ctx, glob, &unlockOpts)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	cmd := newCommand("restore", args...)
 
-	return cli.Run(ctx, cmd)
+	return runCommand(ctx, cmd)
 }
 
 // Unlock executes "restic unlock"
@@ -395,12 +491,12 @@ func Unlock(ctx context.Context, globalOpts *GlobalOptions, unlockOpts *UnlockOp
 	args = append(args, cli.StructToCLI(unlockOpts)...)
 	cmd := newCommand("unlock", args...)
 
-	return cli.Run(ctx, cmd)
+	return runCommand(ctx, cmd)
 }
 
 // Tag executes "restic tag"
 func Tag(ctx context.Context, opts *TagOptions) ([]byte, error) {
 	cmd := newCommand("tag", cli.StructToCLI(opts)...)
 
-	return cli.Run(ctx, cmd)
+	return runCommand(ctx, cmd)
 }
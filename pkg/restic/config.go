@@ -1,6 +1,13 @@
 package restic
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
 	"github.com/mittwald/brudi/pkg/config"
 )
 
@@ -9,10 +16,12 @@ const (
 )
 
 type Config struct {
-	Global  *GlobalOptions
-	Backup  *BackupOptions
-	Forget  *ForgetOptions
-	Restore *RestoreOptions
+	Global   *GlobalOptions
+	Backup   *BackupOptions
+	Forget   *ForgetOptions
+	Restore  *RestoreOptions
+	Progress *ProgressOptions
+	Locks    *LockOptions
 }
 
 func (c *Config) InitFromViper() error {
@@ -21,5 +30,50 @@ func (c *Config) InitFromViper() error {
 		return err
 	}
 
+	// the workspace is removed on cleanup, thus it must not be the root directory
+	if c.Restore != nil && c.Restore.Workspace != "" && filepath.Clean(c.Restore.Workspace) == "/" {
+		return errors.WithStack(fmt.Errorf("restore workspace must not be the root directory"))
+	}
+
 	return config.Validate(c)
 }
+
+// InitForgetForKind replaces the global forget policy with the policy declared for the given kind, if any.
+// Kind specific policies live in the kind's own config, e.g. "mysqldump.restic.forget",
+// thus every config file can bring its own retention policy.
+func (c *Config) InitForgetForKind(kind string) error {
+	if kind == "" {
+		return nil
+	}
+
+	forgetKey := fmt.Sprintf("%s.%s.forget", kind, Kind)
+	if viper.Get(forgetKey+".flags") != nil {
+		c.Forget.Flags = &ForgetFlags{}
+	}
+
+	err := config.InitializeStructFromViper(forgetKey, c.Forget)
+	if err != nil {
+		return err
+	}
+
+	return config.Validate(c)
+}
+
+// ScopeForget restricts "restic forget" to the host, tags and paths used for "restic backup",
+// unless they are explicitly configured for forget
+func (c *Config) ScopeForget() {
+	forgetFlags := c.Forget.Flags
+
+	if forgetFlags.Host == "" {
+		forgetFlags.Host = c.Backup.Flags.Host
+	}
+
+	if len(forgetFlags.Paths) == 0 {
+		forgetFlags.Paths = append([]string{}, c.Backup.Paths...)
+	}
+
+	// restic combines comma separated tags with AND, so only snapshots carrying all backup tags are considered
+	if len(forgetFlags.Tags) == 0 && len(c.Backup.Flags.Tags) > 0 {
+		forgetFlags.Tags = []string{strings.Join(c.Backup.Flags.Tags, ",")}
+	}
+}
@@ -0,0 +1,202 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/cli"
+)
+
+const messageTypeExitError = "exit_error"
+
+// exit codes of restic, see https://restic.readthedocs.io/en/stable/075_scripting.html#exit-codes
+const (
+	exitCodeRepoNotExist  = 10
+	exitCodeRepoLocked    = 11
+	exitCodeWrongPassword = 12
+)
+
+// exitCodesMinMinor is the minor version of restic 0.x introducing the exit codes above and the json exit_error
+const exitCodesMinMinor = 17
+
+var versionPattern = regexp.MustCompile(`restic (\d+)\.(\d+)\.`)
+
+var (
+	ErrWrongPassword = fmt.Errorf("wrong password or no key found")
+	ErrRepoNotExist  = fmt.Errorf("repository does not exist")
+	ErrRepoLocked    = fmt.Errorf("repository is locked")
+	ErrBackend       = fmt.Errorf("backend error")
+	ErrNoSpace       = fmt.Errorf("no space left on device")
+)
+
+// errorPatterns maps parts of restic's output to error classes for versions without distinct exit codes.
+// Backend errors are checked before a missing repository, as restic suggests the latter for unreachable backends.
+var errorPatterns = []struct {
+	class    error
+	patterns []string
+}{
+	{
+		class: ErrWrongPassword,
+		patterns: []string{
+			"wrong password or no key found",
+		},
+	},
+	{
+		class: ErrRepoLocked,
+		patterns: []string{
+			"repository is already locked",
+			"unable to create lock",
+		},
+	},
+	{
+		class: ErrNoSpace,
+		patterns: []string{
+			"no space left on device",
+		},
+	},
+	{
+		class: ErrBackend,
+		patterns: []string{
+			"connection refused",
+			"connection reset",
+			"no such host",
+			"i/o timeout",
+			"tls handshake timeout",
+			"502 bad gateway",
+			"503 service unavailable",
+			"504 gateway timeout",
+			"internalerror",
+			"slowdown",
+		},
+	},
+	{
+		class: ErrRepoNotExist,
+		patterns: []string{
+			"repository does not exist",
+			"is there a repository at the following location",
+			"unable to open config file",
+		},
+	},
+}
+
+// Error is a failed restic command, classified by its exit code and output
+type Error struct {
+	// Class is one of the error classes like ErrRepoLocked, nil if the error couldn't be classified
+	Class    error
+	ExitCode int
+	Message  string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	class := "restic error"
+	if e.Class != nil {
+		class = e.Class.Error()
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("%s (exit code %d): %s", class, e.ExitCode, e.Err)
+	}
+	return fmt.Sprintf("%s (exit code %d): %s", class, e.ExitCode, e.Message)
+}
+
+// Unwrap makes the error class as well as the underlying error accessible by errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Class == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Class, e.Err}
+}
+
+// ClassifyError turns an error returned while running restic into an *Error, based on restic's exit code,
+// its json error messages and, for older versions, its plain text output
+func ClassifyError(err error, out []byte) error {
+	if err == nil {
+		return nil
+	}
+
+	resticErr := &Error{
+		ExitCode: -1,
+		Message:  exitErrorMessage(out),
+		Err:      err,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		resticErr.ExitCode = exitErr.ExitCode()
+	}
+
+	switch resticErr.ExitCode {
+	case exitCodeRepoNotExist:
+		resticErr.Class = ErrRepoNotExist
+	case exitCodeRepoLocked:
+		resticErr.Class = ErrRepoLocked
+	case exitCodeWrongPassword:
+		resticErr.Class = ErrWrongPassword
+	default:
+		resticErr.Class = classifyOutput(resticErr.Message + "\n" + string(out))
+	}
+
+	return resticErr
+}
+
+// IsTransient returns whether the given error is worth retrying
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrBackend)
+}
+
+// classifyOutput returns the error class matching restic's output, nil if there is none
+func classifyOutput(out string) error {
+	out = strings.ToLower(out)
+	for _, errorPattern := range errorPatterns {
+		for _, pattern := range errorPattern.patterns {
+			if strings.Contains(out, pattern) {
+				return errorPattern.class
+			}
+		}
+	}
+	return nil
+}
+
+// exitErrorMessage returns the message of the json error printed by restic before exiting, if there is one
+func exitErrorMessage(out []byte) string {
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		var message struct {
+			MessageType string `json:"message_type"` //nolint:tagliatelle // upstream type
+			Message     string `json:"message"`
+		}
+		if json.Unmarshal(line, &message) == nil && message.MessageType == messageTypeExitError {
+			return message.Message
+		}
+	}
+	return ""
+}
+
+// ParseVersion returns the major and minor version printed by "restic version"
+func ParseVersion(out []byte) (major, minor int, ok bool) {
+	match := versionPattern.FindSubmatch(out)
+	if match == nil {
+		return 0, 0, false
+	}
+	major, _ = strconv.Atoi(string(match[1]))
+	minor, _ = strconv.Atoi(string(match[2]))
+	return major, minor, true
+}
+
+// SupportsExitCodes returns whether the installed restic reports errors by distinct exit codes.
+// Older or unknown versions have to be classified by their output.
+func SupportsExitCodes(ctx context.Context) bool {
+	out, err := cli.Run(ctx, cli.CommandType{Binary: binary, Command: "version"})
+	if err != nil {
+		return false
+	}
+	major, minor, ok := ParseVersion(out)
+	return ok && (major > 0 || minor >= exitCodesMinMinor)
+}
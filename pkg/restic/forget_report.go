@@ -0,0 +1,177 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	forgetActionKeep   = "keep"
+	forgetActionRemove = "remove"
+	statsModeRawData   = "raw-data"
+)
+
+// ForgetReport lists the kept and removed snapshots of "restic forget" per group
+type ForgetReport struct {
+	DryRun bool                `json:"dryRun"`
+	Groups []ForgetReportGroup `json:"groups"`
+	// ReclaimableSize estimates the amount of data in bytes "restic prune" will reclaim
+	ReclaimableSize uint64 `json:"reclaimableSize"`
+}
+
+// ForgetReportGroup contains the decision of "restic forget" for one group of snapshots
+type ForgetReportGroup struct {
+	Host      string                 `json:"host"`
+	Paths     []string               `json:"paths"`
+	Tags      []string               `json:"tags"`
+	Snapshots []ForgetReportSnapshot `json:"snapshots"`
+}
+
+// ForgetReportSnapshot contains the decision of "restic forget" for one snapshot
+type ForgetReportSnapshot struct {
+	ID      string   `json:"id"`
+	Time    string   `json:"time"`
+	Action  string   `json:"action"`
+	Reasons []string `json:"reasons"`
+}
+
+// NewForgetReport creates a ForgetReport from the groups returned by "restic forget"
+func NewForgetReport(forgetTags []*ForgetTag, dryRun bool) *ForgetReport {
+	report := &ForgetReport{
+		DryRun: dryRun,
+		Groups: []ForgetReportGroup{},
+	}
+
+	for _, tag := range forgetTags {
+		reasons := make(map[string][]string)
+		for idx := range tag.Reasons {
+			reasons[snapshotIdentifier(&tag.Reasons[idx].Snapshot)] = tag.Reasons[idx].Matches
+		}
+
+		group := ForgetReportGroup{
+			Host:      tag.Host,
+			Paths:     tag.Paths,
+			Tags:      tag.Tags,
+			Snapshots: []ForgetReportSnapshot{},
+		}
+		for idx := range tag.Keep {
+			group.Snapshots = append(group.Snapshots, newForgetReportSnapshot(&tag.Keep[idx], forgetActionKeep, reasons))
+		}
+		for idx := range tag.Remove {
+			group.Snapshots = append(group.Snapshots, newForgetReportSnapshot(&tag.Remove[idx], forgetActionRemove, reasons))
+		}
+		report.Groups = append(report.Groups, group)
+	}
+
+	return report
+}
+
+func newForgetReportSnapshot(snapshot *Snapshot, action string, reasons map[string][]string) ForgetReportSnapshot {
+	return ForgetReportSnapshot{
+		ID:      snapshotIdentifier(snapshot),
+		Time:    snapshot.Time,
+		Action:  action,
+		Reasons: reasons[snapshotIdentifier(snapshot)],
+	}
+}
+
+// snapshotIdentifier returns the short id of a snapshot, falling back to its full id
+func snapshotIdentifier(snapshot *Snapshot) string {
+	if snapshot.ShortID != "" {
+		return snapshot.ShortID
+	}
+	if snapshot.ID != nil {
+		return *snapshot.ID
+	}
+	return ""
+}
+
+// Count returns the number of kept and removed snapshots
+func (r *ForgetReport) Count() (kept, removed int) {
+	for _, group := range r.Groups {
+		for _, snapshot := range group.Snapshots {
+			if snapshot.Action == forgetActionRemove {
+				removed++
+			} else {
+				kept++
+			}
+		}
+	}
+	return kept, removed
+}
+
+// WriteJSON writes the report as json to the given writer
+func (r *ForgetReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errors.WithStack(encoder.Encode(r))
+}
+
+// WriteTable writes the report as human readable table to the given writer
+func (r *ForgetReport) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, group := range r.Groups {
+		fmt.Fprintf(
+			table, "host: %s, paths: %s, tags: %s\n",
+			group.Host, strings.Join(group.Paths, ","), strings.Join(group.Tags, ","),
+		)
+		fmt.Fprintln(table, "ID\tTIME\tACTION\tREASONS")
+		for _, snapshot := range group.Snapshots {
+			fmt.Fprintf(
+				table, "%s\t%s\t%s\t%s\n",
+				snapshot.ID, snapshot.Time, snapshot.Action, strings.Join(snapshot.Reasons, ", "),
+			)
+		}
+		fmt.Fprintln(table)
+	}
+
+	kept, removed := r.Count()
+	verb := "removed"
+	if r.DryRun {
+		verb = "would be removed"
+	}
+	fmt.Fprintf(
+		table, "%d snapshots kept, %d snapshots %s, estimated reclaimable space: %s\n",
+		kept, removed, verb, formatBytes(r.ReclaimableSize),
+	)
+
+	return errors.WithStack(table.Flush())
+}
+
+// removedIDs returns the ids of all snapshots removed by "restic forget"
+func removedIDs(forgetTags []*ForgetTag) map[string]struct{} {
+	removed := make(map[string]struct{})
+	for _, tag := range forgetTags {
+		for idx := range tag.Remove {
+			if tag.Remove[idx].ID != nil {
+				removed[*tag.Remove[idx].ID] = struct{}{}
+			}
+		}
+	}
+	return removed
+}
+
+// rawDataSize returns the amount of raw data in bytes referenced by the given snapshots
+func rawDataSize(ctx context.Context, glob *GlobalOptions, snapshotIDs []string) (uint64, error) {
+	if len(snapshotIDs) == 0 {
+		return 0, nil
+	}
+
+	stats, err := GetStats(ctx, glob, &StatsOptions{
+		Flags: &StatsFlags{
+			Mode: statsModeRawData,
+		},
+		IDs: snapshotIDs,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return stats.TotalSize, nil
+}
@@ -0,0 +1,239 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+)
+
+const (
+	defaultLockMaxAge       = 30 * time.Minute
+	defaultLockWaitTimeout  = 30 * time.Minute
+	defaultLockPollInterval = 30 * time.Second
+
+	// resticStaleTimeout is the age after which "restic unlock" removes a lock, regardless of its process
+	resticStaleTimeout = 30 * time.Minute
+	localBackendPrefix = "local:"
+)
+
+// remoteBackendPrefixes are the prefixes of repositories which aren't stored on a local filesystem
+var remoteBackendPrefixes = []string{"rest:", "s3:", "sftp:", "b2:", "azure:", "gs:", "swift:", "rclone:"}
+
+// LockOptions configures how brudi deals with locks of the restic repository
+type LockOptions struct {
+	// MaxAge after which a lock is considered stale, restic refreshes locks in use every five minutes
+	MaxAge time.Duration
+	// WaitTimeout limits how long to wait for active locks to be released
+	WaitTimeout time.Duration
+	// PollInterval between two inspections of the repository's locks
+	PollInterval time.Duration
+}
+
+// Lock for "restic cat lock" json-logging
+type Lock struct {
+	ID        string    `json:"-"`
+	Time      time.Time `json:"time"`
+	Exclusive bool      `json:"exclusive"`
+	Hostname  string    `json:"hostname"`
+	Username  string    `json:"username"`
+	PID       int       `json:"pid"`
+}
+
+// IsStale returns whether the lock is older than maxAge or belongs to a dead process on the given host
+func (l *Lock) IsStale(maxAge time.Duration, hostname string, now time.Time) bool {
+	if now.Sub(l.Time) > maxAge {
+		return true
+	}
+	return l.Hostname == hostname && !processExists(l.PID)
+}
+
+// processExists returns whether a process with the given pid exists on this host
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return true
+	}
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// ListLocks executes "restic list locks" and "restic cat lock" for every lock found
+func ListLocks(ctx context.Context, globalOpts *GlobalOptions) ([]Lock, error) {
+	args := append(cli.StructToCLI(globalOpts), "--no-lock", "locks")
+	out, err := runCommand(ctx, newCommand("list", args...))
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%w - %s", err, out))
+	}
+
+	var locks []Lock
+	for _, id := range strings.Fields(string(out)) {
+		args = append(cli.StructToCLI(globalOpts), "--no-lock", "lock", id)
+		out, err = runCommand(ctx, newCommand("cat", args...))
+		if err != nil {
+			// the lock has been released in the meantime
+			continue
+		}
+
+		lock := Lock{ID: id}
+		if err = json.Unmarshal(out, &lock); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// waitForLocks removes stale locks and waits until the repository isn't locked by active locks anymore.
+// Operations using a shared lock, like backup and restore, are only blocked by exclusive locks.
+func (c *Client) waitForLocks(ctx context.Context, exclusive bool) error {
+	opts := c.Config.Locks
+	hostname, err := os.Hostname()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	deadline := time.Now().Add(opts.WaitTimeout)
+
+	for {
+		locks, listErr := ListLocks(ctx, c.Config.Global)
+		if listErr != nil {
+			return listErr
+		}
+
+		var stale, blocking []Lock
+		for idx := range locks {
+			switch {
+			case locks[idx].IsStale(opts.MaxAge, hostname, time.Now()):
+				stale = append(stale, locks[idx])
+			case exclusive || locks[idx].Exclusive:
+				blocking = append(blocking, locks[idx])
+			}
+		}
+
+		if len(stale) > 0 {
+			remaining, removeErr := c.removeStaleLocks(ctx, stale, hostname)
+			if removeErr != nil {
+				return removeErr
+			}
+			for idx := range remaining {
+				if exclusive || remaining[idx].Exclusive {
+					blocking = append(blocking, remaining[idx])
+				}
+			}
+		}
+
+		if len(blocking) == 0 {
+			return nil
+		}
+
+		lock := blocking[0]
+		if time.Now().After(deadline) {
+			return errors.WithStack(fmt.Errorf(
+				"%w: gave up after %s waiting for lock %s of %s@%s (pid %d)",
+				ErrRepoLocked, opts.WaitTimeout, lock.ID, lock.Username, lock.Hostname, lock.PID,
+			))
+		}
+
+		c.Logger.WithFields(
+			log.Fields{
+				"lock":      lock.ID,
+				"hostname":  lock.Hostname,
+				"pid":       lock.PID,
+				"exclusive": lock.Exclusive,
+				"since":     lock.Time,
+			},
+		).Info("restic repository is locked, waiting for the lock to be released")
+
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// removeStaleLocks removes the given stale locks and returns the ones which couldn't be removed.
+// Locks of local repositories are removed by their ID, thus locks created in the meantime are never touched.
+// restic can't remove single locks of other backends, "restic unlock" removes every lock which is stale by restic's
+// definition instead. It's only used if that can't include a lock which is still active by the configured max age.
+func (c *Client) removeStaleLocks(ctx context.Context, stale []Lock, hostname string) ([]Lock, error) {
+	for idx := range stale {
+		c.Logger.WithFields(
+			log.Fields{
+				"lock":     stale[idx].ID,
+				"hostname": stale[idx].Hostname,
+				"pid":      stale[idx].PID,
+				"since":    stale[idx].Time,
+			},
+		).Warn("removing stale restic lock")
+	}
+
+	if dir, ok := localRepository(c.Config.Global); ok {
+		for idx := range stale {
+			err := os.Remove(filepath.Join(dir, "locks", stale[idx].ID))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, errors.WithStack(fmt.Errorf("error while removing stale lock %s: %w", stale[idx].ID, err))
+			}
+		}
+		return nil, nil
+	}
+
+	if c.Config.Locks.MaxAge > resticStaleTimeout {
+		c.Logger.WithField("maxAge", c.Config.Locks.MaxAge).Warnf(
+			"'restic unlock' would remove locks younger than maxAge, lower it to %s for removing stale locks of this backend",
+			resticStaleTimeout,
+		)
+		return stale, nil
+	}
+
+	out, err := Unlock(ctx, c.Config.Global, &UnlockOptions{Flags: &UnlockFlags{}})
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("error while removing stale locks: %w - %s", err, out))
+	}
+
+	var remaining []Lock
+	for idx := range stale {
+		if !stale[idx].IsStale(resticStaleTimeout, hostname, time.Now()) {
+			remaining = append(remaining, stale[idx])
+		}
+	}
+	return remaining, nil
+}
+
+// localRepository returns the directory of the repository if it is stored on a local filesystem
+func localRepository(globalOpts *GlobalOptions) (string, bool) {
+	repo := globalOpts.Flags.Repo
+	if repo == "" {
+		repo = os.Getenv("RESTIC_REPOSITORY")
+	}
+	if repo == "" {
+		return "", false
+	}
+	for _, prefix := range remoteBackendPrefixes {
+		if strings.HasPrefix(repo, prefix) {
+			return "", false
+		}
+	}
+	return strings.TrimPrefix(repo, localBackendPrefix), true
+}
+
+// retryLocked runs fn once more after waiting for the repository's locks if it failed due to a locked repository
+func (c *Client) retryLocked(ctx context.Context, exclusive bool, fn func() error) error {
+	err := fn()
+	if !errors.Is(err, ErrRepoLocked) {
+		return err
+	}
+
+	if waitErr := c.waitForLocks(ctx, exclusive); waitErr != nil {
+		return waitErr
+	}
+	return fn()
+}
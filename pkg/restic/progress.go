@@ -0,0 +1,108 @@
+package restic
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultProgressInterval = time.Minute
+	messageTypeStatus       = "status"
+)
+
+// ProgressFunc receives the status of a running "restic backup"
+type ProgressFunc func(status *BackupStatus)
+
+// SummaryFunc receives the summary of a finished "restic backup"
+type SummaryFunc func(summary *BackupSummary)
+
+// ProgressReporter throttles the status messages of "restic backup" and passes them on to its handlers
+type ProgressReporter struct {
+	interval   time.Duration
+	handlers   []ProgressFunc
+	lastReport time.Time
+}
+
+// NewProgressReporter creates a ProgressReporter which calls the given handlers at most once per interval
+func NewProgressReporter(interval time.Duration, handlers ...ProgressFunc) *ProgressReporter {
+	return &ProgressReporter{
+		interval: interval,
+		handlers: handlers,
+	}
+}
+
+// Report passes the given status on to all handlers if the interval has passed since the last report.
+// It returns whether the status has been reported.
+func (p *ProgressReporter) Report(status *BackupStatus) bool {
+	if p == nil || status == nil {
+		return false
+	}
+
+	now := time.Now()
+	if !p.lastReport.IsZero() && now.Sub(p.lastReport) < p.interval {
+		return false
+	}
+	p.lastReport = now
+
+	for _, handler := range p.handlers {
+		handler(status)
+	}
+	return true
+}
+
+// LogProgress returns a ProgressFunc which logs the status of "restic backup" with the given logger
+func LogProgress(logger *log.Entry) ProgressFunc {
+	return func(status *BackupStatus) {
+		logger.WithFields(
+			log.Fields{
+				"percentDone": fmt.Sprintf("%.1f%%", status.PercentDone*100),
+				"filesDone":   status.FilesDone,
+				"totalFiles":  status.TotalFiles,
+				"bytesDone":   status.BytesDone,
+				"totalBytes":  status.TotalBytes,
+				"eta":         (time.Duration(status.SecondsRemaining) * time.Second).String(),
+				"errors":      status.ErrorCount,
+			},
+		).Infof(
+			"backup progress: %.1f%% (%s of %s)",
+			status.PercentDone*100, formatBytes(status.BytesDone), formatBytes(status.TotalBytes),
+		)
+	}
+}
+
+// LogSummary returns a SummaryFunc which logs the summary of "restic backup" with the given logger
+func LogSummary(logger *log.Entry) SummaryFunc {
+	return func(summary *BackupSummary) {
+		logger.WithFields(
+			log.Fields{
+				"snapshot":       summary.SnapshotID,
+				"filesNew":       summary.FilesNew,
+				"filesChanged":   summary.FilesChanged,
+				"filesUnchanged": summary.FilesUnmodified,
+				"dataAdded":      summary.DataAdded,
+				"totalFiles":     summary.TotalFilesProcessed,
+				"totalBytes":     summary.TotalBytesProcessed,
+				"duration":       (time.Duration(summary.TotalDuration * float64(time.Second))).Round(time.Second).String(),
+			},
+		).Infof(
+			"backup summary: added %s, processed %s in %d files",
+			formatBytes(summary.DataAdded), formatBytes(summary.TotalBytesProcessed), summary.TotalFilesProcessed,
+		)
+	}
+}
+
+// formatBytes returns a human readable representation of the given amount of bytes
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
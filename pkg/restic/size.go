@@ -0,0 +1,55 @@
+package restic
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// LatestSnapshot returns the latest snapshot containing the given path, which was created for the host and tags
+// of "restic backup", or nil if there is none
+func (c *Client) LatestSnapshot(ctx context.Context, path string) (*Snapshot, error) {
+	flags := &SnapshotFlags{
+		Host:  c.Config.Backup.Flags.Host,
+		Paths: []string{path},
+	}
+	// restic combines comma separated tags with AND, see ScopeForget
+	if len(c.Config.Backup.Flags.Tags) > 0 {
+		flags.Tags = []string{strings.Join(c.Config.Backup.Flags.Tags, ",")}
+	}
+
+	snapshots, err := ListSnapshots(ctx, c.Config.Global, &SnapshotOptions{Flags: flags})
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *Snapshot
+	var latestTime time.Time
+	for i := range snapshots {
+		snapshotTime, parseErr := time.Parse(time.RFC3339Nano, snapshots[i].Time)
+		if parseErr != nil {
+			c.Logger.WithError(parseErr).WithField("snapshot", snapshots[i].ShortID).Warn("failed to parse time of snapshot")
+			continue
+		}
+		if latest == nil || snapshotTime.After(latestTime) {
+			latest = &snapshots[i]
+			latestTime = snapshotTime
+		}
+	}
+	return latest, nil
+}
+
+// PreviousBackupSize returns the size of the given path in the latest snapshot, see LatestSnapshot.
+// The returned snapshot is nil if there is no previous snapshot.
+func (c *Client) PreviousBackupSize(ctx context.Context, path string) (uint64, *Snapshot, error) {
+	snapshot, err := c.LatestSnapshot(ctx, path)
+	if err != nil || snapshot == nil {
+		return 0, nil, err
+	}
+
+	size, err := GetSnapshotSizeByPath(ctx, c.Config.Global, snapshot.ShortID, path)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, snapshot, nil
+}
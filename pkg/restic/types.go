@@ -1,5 +1,7 @@
 package restic
 
+import "time"
+
 // Global options for restic
 type GlobalOptions struct {
 	Flags *GlobalFlags
@@ -25,6 +27,8 @@ type GlobalFlags struct {
 type BackupResult struct {
 	SnapshotID       string
 	ParentSnapshotID string
+	// Summary is the summary message restic printed at the end of the backup
+	Summary *BackupSummary
 }
 
 // BackupOptions for cmd: "restic backup"
@@ -47,7 +51,7 @@ type BackupFlags struct {
 	FilesFromFile     []string `flag:"--files-from file"`
 	FilesFromRaw      []string `flag:"--files-from-raw file"`
 	Tags              []string `flag:"--tag"`
-	ExcludeLargerThan int      `flag:"exclude-larger-than"`
+	ExcludeLargerThan int      `flag:"--exclude-larger-than"`
 	ExcludeCaches     bool     `flag:"--exclude-caches"`
 	Force             bool     `flag:"-f"`
 	IgnoreInode       bool     `flag:"--ignore-inode"`
@@ -56,6 +60,44 @@ type BackupFlags struct {
 	WithAtime         bool     `flag:"--with-atime"`
 }
 
+// BackupStatus for "restic backup" json-logging of status messages
+//
+//nolint:tagliatelle // upstream type
+type BackupStatus struct {
+	SecondsElapsed   uint64   `json:"seconds_elapsed"`
+	SecondsRemaining uint64   `json:"seconds_remaining"`
+	PercentDone      float64  `json:"percent_done"`
+	TotalFiles       uint64   `json:"total_files"`
+	FilesDone        uint64   `json:"files_done"`
+	TotalBytes       uint64   `json:"total_bytes"`
+	BytesDone        uint64   `json:"bytes_done"`
+	ErrorCount       uint64   `json:"error_count"`
+	CurrentFiles     []string `json:"current_files"`
+}
+
+// BackupSummary for "restic backup" json-logging of the summary message
+//
+//nolint:tagliatelle // upstream type
+type BackupSummary struct {
+	FilesNew            uint64  `json:"files_new"`
+	FilesChanged        uint64  `json:"files_changed"`
+	FilesUnmodified     uint64  `json:"files_unmodified"`
+	DirsNew             uint64  `json:"dirs_new"`
+	DirsChanged         uint64  `json:"dirs_changed"`
+	DirsUnmodified      uint64  `json:"dirs_unmodified"`
+	DataAdded           uint64  `json:"data_added"`
+	TotalFilesProcessed uint64  `json:"total_files_processed"`
+	TotalBytesProcessed uint64  `json:"total_bytes_processed"`
+	TotalDuration       float64 `json:"total_duration"`
+	SnapshotID          string  `json:"snapshot_id"`
+}
+
+// ProgressOptions for progress reporting of "restic backup"
+type ProgressOptions struct {
+	// Interval between two progress reports, reporting is disabled if zero
+	Interval time.Duration
+}
+
 // StatsOptions for cmd: "restic stats"
 type StatsOptions struct {
 	Flags *StatsFlags
@@ -96,8 +138,11 @@ type SnapshotFlags struct {
 }
 
 // Snapshot type for the (json-)result of "restic snapshots"
+//
+//nolint:tagliatelle // upstream type
 type Snapshot struct {
 	ID       *string  `json:"id"`
+	ShortID  string   `json:"short_id"`
 	Time     string   `json:"time"`
 	Tree     string   `json:"tree"`
 	Tags     []string `json:"tags"`
@@ -158,7 +203,7 @@ type ForgetTag struct {
 type ForgetReason struct {
 	Snapshot Snapshot `json:"snapshot"`
 	Matches  []string `json:"matches"`
-	Counters Counters `Json:"counters"`
+	Counters Counters `json:"counters"`
 }
 
 // Counters for "restic forget" json-logging
@@ -186,6 +231,8 @@ type ForgetSnapshot struct {
 type RestoreOptions struct {
 	Flags *RestoreFlags
 	ID    string
+	// Workspace is the directory backups are restored into instead of the target, restore backends read them from there
+	Workspace string `flag:"-"`
 }
 
 // RestoreFlags for cmd: "restic restore"
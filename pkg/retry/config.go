@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/config"
+)
+
+const (
+	Kind = "retry"
+)
+
+// stages of a run which can be retried
+const (
+	StageDump    = "dump"
+	StageInit    = "init"
+	StageBackup  = "backup"
+	StageForget  = "forget"
+	StagePrune   = "prune"
+	StageRestore = "restore"
+)
+
+// Policy for retrying a stage
+type Policy struct {
+	// MaxAttempts including the first one, the stage isn't retried if it is 1
+	MaxAttempts int `validate:"min=1"`
+	// InitialBackoff before the first retry, it is doubled for each further retry
+	InitialBackoff time.Duration
+	// MaxBackoff limits the backoff between two attempts, it isn't limited if it is 0
+	MaxBackoff time.Duration
+	// Jitter adds a random delay up to the given duration to each backoff
+	Jitter time.Duration
+}
+
+type Config struct {
+	Dump    *Policy
+	Init    *Policy
+	Backup  *Policy
+	Forget  *Policy
+	Prune   *Policy
+	Restore *Policy
+}
+
+func (c *Config) InitFromViper() error {
+	err := config.InitializeStructFromViper(Kind, c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return config.Validate(c)
+}
+
+// policy returns the Policy of the given stage
+func (c *Config) policy(stage string) *Policy {
+	switch stage {
+	case StageDump:
+		return c.Dump
+	case StageInit:
+		return c.Init
+	case StageBackup:
+		return c.Backup
+	case StageForget:
+		return c.Forget
+	case StagePrune:
+		return c.Prune
+	case StageRestore:
+		return c.Restore
+	default:
+		return &Policy{MaxAttempts: 1}
+	}
+}
@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInitialBackoff = 10 * time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	defaultJitter         = 5 * time.Second
+	// restic stages are only retried on backend errors, so they are retried by default
+	defaultResticAttempts = 3
+)
+
+// Retrier retries the stages of a run according to their Policy and counts the attempts per stage
+type Retrier struct {
+	cfg      *Config
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// NewRetrier creates a Retrier from the "retry" config
+func NewRetrier() (*Retrier, error) {
+	newPolicy := func(maxAttempts int) *Policy {
+		return &Policy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: defaultInitialBackoff,
+			MaxBackoff:     defaultMaxBackoff,
+			Jitter:         defaultJitter,
+		}
+	}
+
+	cfg := &Config{
+		Dump:    newPolicy(1),
+		Init:    newPolicy(defaultResticAttempts),
+		Backup:  newPolicy(defaultResticAttempts),
+		Forget:  newPolicy(defaultResticAttempts),
+		Prune:   newPolicy(defaultResticAttempts),
+		Restore: newPolicy(defaultResticAttempts),
+	}
+
+	err := cfg.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Retrier{
+		cfg:      cfg,
+		attempts: make(map[string]int),
+	}, nil
+}
+
+// Do runs fn until it succeeds, fails with an error which isn't transient or the max attempts of the stage's
+// Policy are reached. beforeRetry, if given, is called before each retry, e.g. to remove partial output.
+func (r *Retrier) Do(
+	ctx context.Context, logger *log.Entry, stage string, isTransient func(error) bool, fn func() error, beforeRetry func(),
+) error {
+	policy := r.cfg.policy(stage)
+	stageLogger := logger.WithField("stage", stage)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		r.count(stage)
+		stageLogger.WithFields(
+			log.Fields{
+				"attempt":     attempt,
+				"maxAttempts": policy.MaxAttempts,
+			},
+		).Info("starting attempt")
+
+		err = fn()
+		if err == nil || attempt >= policy.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		backoff := policy.backoff(attempt)
+		stageLogger.WithError(err).WithFields(
+			log.Fields{
+				"attempt": attempt,
+				"backoff": backoff.String(),
+			},
+		).Warn("attempt failed with transient error, retrying")
+
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		if beforeRetry != nil {
+			beforeRetry()
+		}
+	}
+}
+
+// Attempts returns the number of attempts per stage
+func (r *Retrier) Attempts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := make(map[string]int, len(r.attempts))
+	for stage, count := range r.attempts {
+		attempts[stage] = count
+	}
+	return attempts
+}
+
+func (r *Retrier) count(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts[stage]++
+}
+
+// backoff returns the exponential backoff after the given attempt, including a random jitter
+func (p *Policy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		backoff += time.Duration(rand.Int63n(int64(p.Jitter))) //nolint:gosec // no need for secure randomness
+	}
+	return backoff
+}
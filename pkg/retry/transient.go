@@ -0,0 +1,40 @@
+package retry
+
+import "strings"
+
+// transientPatterns are parts of error messages of dump and restore tools caused by temporary network or server issues
+var transientPatterns = []string{
+	"connection refused",
+	"connection reset",
+	"connection timed out",
+	"broken pipe",
+	"i/o timeout",
+	"no route to host",
+	"temporary failure in name resolution",
+	"lost connection",
+	"server has gone away",
+	"server closed the connection unexpectedly",
+	"can't connect to",
+	"the database system is starting up",
+	"the database system is shutting down",
+	"loading the dataset in memory",
+	"not master",
+	"no reachable servers",
+	"server selection error",
+}
+
+// IsTransientCommandError returns whether the error of a failed command, including its output, is caused by a
+// temporary issue and thus worth retrying
+func IsTransientCommandError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range transientPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package sizeguard
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/config"
+)
+
+const (
+	Kind = "sizeGuard"
+
+	// ActionWarn logs a warning if the size of a backup is anomalous
+	ActionWarn = "warn"
+	// ActionFail fails the run before the backup is saved with restic if the size of a backup is anomalous
+	ActionFail = "fail"
+)
+
+// Config of the checks of the size of backups
+type Config struct {
+	// MinSize in bytes every backup has to reach, 0 disables the check
+	MinSize int `validate:"min=0"`
+	// MaxDeviation in percent the size of a backup may deviate from the size of the previous snapshot, 0 disables the check
+	MaxDeviation int `validate:"min=0"`
+	// Action taken if a check fails, either "warn" or "fail"
+	Action string `validate:"oneof=warn fail"`
+}
+
+// NewConfig returns the "sizeGuard" config, all checks are disabled by default
+func NewConfig() (*Config, error) {
+	cfg := &Config{
+		Action: ActionWarn,
+	}
+
+	err := config.InitializeStructFromViper(Kind, cfg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return cfg, config.Validate(cfg)
+}
+
+// Enabled returns whether any check is enabled
+func (c *Config) Enabled() bool {
+	return c.MinSize > 0 || c.MaxDeviation > 0
+}
@@ -0,0 +1,69 @@
+package sizeguard
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAnomaly is wrapped by all errors about backups of anomalous size
+var ErrAnomaly = fmt.Errorf("anomalous backup size")
+
+// CheckMinSize checks the size of a backup against the configured minimum
+func (c *Config) CheckMinSize(size uint64) error {
+	if c.MinSize > 0 && size < uint64(c.MinSize) {
+		return errors.WithStack(fmt.Errorf("%w: backup has %d bytes, which is below the minimum of %d bytes", ErrAnomaly, size, c.MinSize))
+	}
+	return nil
+}
+
+// CheckDeviation checks the size of a backup against the size of the previous one
+func (c *Config) CheckDeviation(size, previousSize uint64) error {
+	// there is nothing to compare against, if the previous backup was empty
+	if c.MaxDeviation == 0 || previousSize == 0 {
+		return nil
+	}
+
+	deviation := Deviation(size, previousSize)
+	if deviation > float64(c.MaxDeviation) {
+		return errors.WithStack(fmt.Errorf(
+			"%w: backup has %d bytes, which deviates by %.1f%% from the %d bytes of the previous snapshot, the maximum is %d%%",
+			ErrAnomaly, size, deviation, previousSize, c.MaxDeviation,
+		))
+	}
+	return nil
+}
+
+// Deviation returns the deviation of size from previousSize in percent
+func Deviation(size, previousSize uint64) float64 {
+	if previousSize == 0 {
+		return 0
+	}
+	return math.Abs(float64(size)-float64(previousSize)) / float64(previousSize) * 100
+}
+
+// PathSize returns the size of the given file, or the summed size of all regular files below the given directory
+func PathSize(path string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		size += uint64(info.Size()) //nolint: gosec // file sizes aren't negative
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
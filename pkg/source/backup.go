@@ -3,8 +3,13 @@ package source
 import (
 	"context"
 	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/restic"
+	"github.com/mittwald/brudi/pkg/retry"
+	"github.com/mittwald/brudi/pkg/timeout"
 
 	"github.com/mittwald/brudi/pkg/source/pgdump"
 
@@ -12,10 +17,13 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mittwald/brudi/pkg/source/etcdsnapshot"
+	"github.com/mittwald/brudi/pkg/source/exec"
 	"github.com/mittwald/brudi/pkg/source/fsbackup"
 	"github.com/mittwald/brudi/pkg/source/mongodump"
 	"github.com/mittwald/brudi/pkg/source/mysqldump"
 	"github.com/mittwald/brudi/pkg/source/redisdump"
+	"github.com/mittwald/brudi/pkg/source/sqlitedump"
 )
 
 func getGenericBackendForKind(kind string) (Generic, error) {
@@ -32,12 +40,26 @@ func getGenericBackendForKind(kind string) (Generic, error) {
 		return tar.NewConfigBasedBackend()
 	case fsbackup.Kind:
 		return fsbackup.NewConfigBasedBackend()
+	case sqlitedump.Kind:
+		return sqlitedump.NewConfigBasedBackend()
+	case etcdsnapshot.Kind:
+		return etcdsnapshot.NewConfigBasedBackend()
+	case exec.Kind:
+		return exec.NewConfigBasedBackend()
 	default:
 		return nil, fmt.Errorf("unsupported kind '%s'", kind)
 	}
 }
 
+// DoBackupForKind creates a backup of the given kind and saves it with restic, if requested,
+// within the configured timeouts
 func DoBackupForKind(ctx context.Context, kind string, cleanup, useRestic, useResticForget, useResticPrune bool) error {
+	return timeout.RunWithConfig(ctx, func(ctx context.Context) error {
+		return doBackupForKind(ctx, kind, cleanup, useRestic, useResticForget, useResticPrune)
+	})
+}
+
+func doBackupForKind(ctx context.Context, kind string, cleanup, useRestic, useResticForget, useResticPrune bool) error {
 	logKind := log.WithFields(
 		log.Fields{
 			"kind": kind,
@@ -49,7 +71,47 @@ func DoBackupForKind(ctx context.Context, kind string, cleanup, useRestic, useRe
 		return err
 	}
 
-	err = backend.CreateBackup(ctx)
+	chainer, chained := backend.(Chainer)
+	chained = chained && chainer.Chained()
+	if chained && (cleanup || useRestic) {
+		return errors.WithStack(fmt.Errorf("chained backups of kind %s can't be used with --restic or --cleanup", kind))
+	}
+
+	retrier, err := retry.NewRetrier()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		logKind.WithField("attempts", retrier.Attempts()).Info("attempts per stage")
+	}()
+
+	if streamer, ok := backend.(Streamer); ok && useRestic && streamer.Streaming() {
+		return doStreamedBackup(ctx, logKind, kind, backend, streamer, retrier, useResticForget, useResticPrune)
+	}
+
+	err = retrier.Do(
+		ctx, logKind, retry.StageDump, retry.IsTransientCommandError,
+		func() error { return timeout.Run(ctx, retry.StageDump, backend.CreateBackup) },
+		func() {
+			// remove partial output of the failed attempt
+			if cleanupErr := backend.CleanUp(); cleanupErr != nil && !os.IsNotExist(errors.Cause(cleanupErr)) {
+				logKind.WithError(cleanupErr).Warn("failed to remove partial backup")
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if validator, ok := backend.(Validator); ok {
+		if err = validator.ValidateBackup(ctx); err != nil {
+			logKind.WithError(err).Error("backup failed validation, skipping restic")
+			return err
+		}
+		logKind.WithField("path", backend.GetBackupPath()).Info("backup passed validation")
+	}
+
+	manifestPath, err := writeManifest(ctx, logKind, kind, backend)
 	if err != nil {
 		return err
 	}
@@ -64,6 +126,8 @@ func DoBackupForKind(ctx context.Context, kind string, cleanup, useRestic, useRe
 			)
 			if err = backend.CleanUp(); err != nil {
 				cleanupLogger.WithError(err).Warn("failed to cleanup backup")
+			} else if err = removeManifest(backend.GetBackupPath()); err != nil {
+				cleanupLogger.WithError(err).Warn("failed to cleanup manifest")
 			} else {
 				cleanupLogger.Info("successfully cleaned up backup")
 			}
@@ -72,16 +136,53 @@ func DoBackupForKind(ctx context.Context, kind string, cleanup, useRestic, useRe
 
 	logKind.Info("finished backing up")
 
-	if !useRestic {
-		return nil
+	var resticClient *restic.Client
+	if useRestic {
+		resticClient, err = newResticClient(logKind, kind, backend.GetHostname(), backupPaths(backend)...)
+		if err != nil {
+			return err
+		}
+		resticClient.Retrier = retrier
+		if configurer, ok := backend.(ResticBackupConfigurer); ok {
+			configurer.ConfigureResticBackup(resticClient.Config.Backup.Flags)
+		}
 	}
 
-	var resticClient *restic.Client
-	resticClient, err = restic.NewResticClient(logKind, backend.GetHostname(), backend.GetBackupPath())
+	// the previous snapshot is looked up, thus the size is checked before the new one is created
+	err = guardSize(ctx, logKind, backend, resticClient)
 	if err != nil {
 		return err
 	}
 
+	if !useRestic {
+		if chained {
+			return chainer.FinishBackup()
+		}
+		return nil
+	}
+
+	// the manifest is part of the snapshot, but forget stays scoped to the backup itself
+	if manifestPath != "" {
+		resticClient.Config.Backup.Paths = append(resticClient.Config.Backup.Paths, manifestPath)
+	}
+
+	if doBackupErr := resticClient.DoResticBackup(ctx); doBackupErr != nil {
+		return doBackupErr
+	}
+
+	return forgetAndPrune(ctx, resticClient, useResticForget, useResticPrune)
+}
+
+// backupPaths returns all paths of the backup
+func backupPaths(backend Generic) []string {
+	if multiPather, ok := backend.(MultiPather); ok {
+		return multiPather.GetBackupPaths()
+	}
+	return []string{backend.GetBackupPath()}
+}
+
+// forgetAndPrune applies the forget policy and prunes the repository after a backup, if requested
+func forgetAndPrune(ctx context.Context, resticClient *restic.Client, useResticForget, useResticPrune bool) error {
 	// as of now (16.06.2023) there is no JSON-output for `restic forget --prune`
 	// if we use forget with the `prune`-flag we encounter a parse-error because of invalid json
 	// therefore we do not pass the `--prune`-flag to restic but execute `restic prune`
@@ -90,10 +191,6 @@ func DoBackupForKind(ctx context.Context, kind string, cleanup, useRestic, useRe
 		resticClient.Config.Forget.Flags.Prune = false
 	}
 
-	if doBackupErr := resticClient.DoResticBackup(ctx); doBackupErr != nil {
-		return doBackupErr
-	}
-
 	if useResticForget {
 		forgetErr := resticClient.DoResticForget(ctx)
 		if forgetErr != nil {
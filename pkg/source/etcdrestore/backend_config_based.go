@@ -0,0 +1,80 @@
+package etcdrestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		Options: &Options{
+			Flags:          &Flags{},
+			AdditionalArgs: []string{},
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+// RestoreBackup restores the snapshot into a new data directory, which a new single member or a member of a new
+// cluster is started from afterwards
+func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
+	dataDir := b.cfg.Options.Flags.DataDir
+	if _, err := os.Stat(dataDir); err == nil {
+		return errors.WithStack(fmt.Errorf("data directory %s already exists, etcd snapshots are restored into new data directories only", dataDir))
+	} else if !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	// etcdutl reads the snapshot from a file only
+	fileName, removePlain, err := dumpfile.Open(ctx, b.cfg.Options.SourceFile)
+	if err != nil {
+		return err
+	}
+	defer removePlain()
+
+	args := append([]string{"snapshot", "restore", fileName}, cli.StructToCLI(b.cfg.Options)...)
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   args,
+	}
+
+	out, err := cli.Run(ctx, cmd)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return nil
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.SourceFile
+}
+
+// SetBackupPath sets the file to restore
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	b.cfg.Options.SourceFile = path
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.HostName
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
@@ -0,0 +1,25 @@
+package etcdrestore
+
+const (
+	binary = "etcdutl"
+)
+
+type Options struct {
+	Flags          *Flags
+	AdditionalArgs []string
+	// SourceFile is the snapshot to restore, it may be compressed and/or encrypted
+	SourceFile string `flag:"-" validate:"min=1"`
+}
+
+type Flags struct {
+	// DataDir must not exist yet, etcdutl refuses to restore into an existing data directory
+	DataDir                  string `flag:"--data-dir="                    validate:"min=1"`
+	InitialAdvertisePeerURLs string `flag:"--initial-advertise-peer-urls="`
+	InitialCluster           string `flag:"--initial-cluster="`
+	InitialClusterToken      string `flag:"--initial-cluster-token="`
+	Name                     string `flag:"--name="`
+	WalDir                   string `flag:"--wal-dir="`
+	BumpRevision             int    `flag:"--bump-revision="               validate:"min=0"`
+	MarkCompacted            bool   `flag:"--mark-compacted"`
+	SkipHashCheck            bool   `flag:"--skip-hash-check"`
+}
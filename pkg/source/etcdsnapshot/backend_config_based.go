@@ -0,0 +1,117 @@
+package etcdsnapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/timeout"
+)
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		Options: &Options{
+			Flags:          &Flags{},
+			AdditionalArgs: []string{},
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+// CreateBackup saves a snapshot of the configured etcd member and verifies its status before it is compressed
+// and/or encrypted
+func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
+	backupFile := b.cfg.Options.File
+	// create temporary, plain snapshot first, thus trim the extensions of compression and encryption
+	plainFile := dumpfile.PlainName(backupFile)
+
+	args := append([]string{"snapshot", "save"}, cli.StructToCLI(b.cfg.Options)...)
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   append(args, plainFile),
+	}
+
+	out, err := cli.Run(ctx, cmd)
+	if err != nil {
+		_ = os.Remove(plainFile)
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	status, err := b.status(ctx, plainFile)
+	if err == nil {
+		err = status.Check(b.cfg.Options.MinKeys)
+	}
+	if err != nil {
+		_ = os.Remove(plainFile)
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"hash":      status.Hash,
+		"revision":  status.Revision,
+		"totalKey":  status.TotalKey,
+		"totalSize": status.TotalSize,
+	}).Info("verified etcd snapshot")
+
+	if dumpfile.IsPlain(backupFile) {
+		return nil
+	}
+
+	err = timeout.Run(ctx, timeout.StageCompress, func(ctx context.Context) error {
+		return dumpfile.WriteFile(ctx, plainFile, backupFile)
+	})
+	if err != nil {
+		_ = os.Remove(plainFile)
+		return err
+	}
+
+	return nil
+}
+
+// status returns the hash, revision and key count of the given plain snapshot
+func (b *ConfigBasedBackend) status(ctx context.Context, fileName string) (*Status, error) {
+	cmd := cli.CommandType{
+		Binary: statusBinary,
+		Args:   []string{"snapshot", "status", fileName, "--write-out=json"},
+	}
+
+	var stdout bytes.Buffer
+	out, err := cli.RunWithStdout(ctx, cmd, &stdout)
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return ParseStatus(stdout.Bytes())
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.File
+}
+
+func (b *ConfigBasedBackend) GetToolVersion(ctx context.Context) string {
+	return cli.ToolVersion(ctx, binary)
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.HostName
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
@@ -0,0 +1,34 @@
+package etcdsnapshot
+
+const (
+	binary = "etcdctl"
+	// statusBinary inspects snapshots offline, "etcdctl snapshot status" is deprecated since etcd 3.5
+	statusBinary = "etcdutl"
+)
+
+type Options struct {
+	Flags          *Flags
+	AdditionalArgs []string
+	// File is the snapshot to save, it is compressed and/or encrypted according to its suffixes
+	File string `flag:"-" validate:"min=1"`
+	// MinKeys is the number of keys the snapshot has to contain at least
+	MinKeys int `flag:"-" validate:"min=0"`
+}
+
+type Flags struct {
+	// Endpoint of the member to snapshot, etcdctl refuses to save snapshots from multiple endpoints
+	Endpoints             string `flag:"--endpoints="             validate:"min=1"`
+	CACert                string `flag:"--cacert="`
+	Cert                  string `flag:"--cert="`
+	CommandTimeout        string `flag:"--command-timeout="`
+	DialTimeout           string `flag:"--dial-timeout="`
+	DiscoverySRV          string `flag:"--discovery-srv="`
+	DiscoverySRVName      string `flag:"--discovery-srv-name="`
+	KeepaliveTime         string `flag:"--keepalive-time="`
+	KeepaliveTimeout      string `flag:"--keepalive-timeout="`
+	Key                   string `flag:"--key="`
+	Password              string `flag:"--password="`
+	User                  string `flag:"--user="`
+	InsecureDiscovery     bool   `flag:"--insecure-discovery"`
+	InsecureSkipTLSVerify bool   `flag:"--insecure-skip-tls-verify"`
+}
@@ -0,0 +1,41 @@
+package etcdsnapshot
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidSnapshot is returned if the status of a saved snapshot doesn't look like a usable snapshot
+var ErrInvalidSnapshot = errors.New("invalid etcd snapshot")
+
+// Status is the status of a snapshot as printed by "etcdutl snapshot status -w json"
+type Status struct {
+	Hash      uint32 `json:"hash"`
+	Revision  int64  `json:"revision"`
+	TotalKey  int    `json:"totalKey"`
+	TotalSize int64  `json:"totalSize"`
+	Version   string `json:"version"`
+}
+
+// ParseStatus parses the json output of "etcdutl snapshot status"
+func ParseStatus(data []byte) (*Status, error) {
+	status := &Status{}
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%w: unable to parse snapshot status: %v", ErrInvalidSnapshot, err))
+	}
+	return status, nil
+}
+
+// Check returns ErrInvalidSnapshot if the snapshot is empty or contains less than minKeys keys
+func (s *Status) Check(minKeys int) error {
+	if s.TotalSize <= 0 || s.Revision <= 0 {
+		return errors.WithStack(fmt.Errorf("%w: empty snapshot at revision %d", ErrInvalidSnapshot, s.Revision))
+	}
+	if s.TotalKey < minKeys {
+		return errors.WithStack(fmt.Errorf("%w: snapshot contains %d keys, expected at least %d",
+			ErrInvalidSnapshot, s.TotalKey, minKeys))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		Options: &Options{
+			Args: []string{},
+			Env:  []string{},
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+// CreateBackup runs the command and writes its stdout to the backup file, which is compressed and/or encrypted
+// while it is written. A non-zero exit code fails the backup and no partial file is kept.
+func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
+	return dumpfile.Write(b.cfg.Options.File, func(w io.Writer) error {
+		return b.StreamBackup(ctx, w)
+	})
+}
+
+// StreamBackup runs the command and writes its stdout into w
+func (b *ConfigBasedBackend) StreamBackup(ctx context.Context, w io.Writer) error {
+	cmd := cli.CommandType{
+		Binary: b.cfg.Options.Command,
+		Args:   b.cfg.Options.Args,
+		Env:    b.cfg.Options.Env,
+	}
+
+	out, err := cli.RunWithStdout(ctx, cmd, w)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return nil
+}
+
+func (b *ConfigBasedBackend) Streaming() bool {
+	return b.cfg.Options.Stream
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.File
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.HostName
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
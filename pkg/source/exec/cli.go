@@ -0,0 +1,13 @@
+package exec
+
+type Options struct {
+	// Command is the binary whose stdout becomes the backup, it is looked up in PATH
+	Command string `validate:"min=1"`
+	Args    []string
+	// Env holds additional environment variables of the command as KEY=value
+	Env []string
+	// File the output is written to, it is compressed and/or encrypted according to its suffixes
+	File string `validate:"min=1"`
+	// Stream streams the output to "restic backup --stdin" as File instead of writing it to disk, if restic is used
+	Stream bool
+}
@@ -0,0 +1,76 @@
+package execrestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		Options: &Options{
+			Args: []string{},
+			Env:  []string{},
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+// RestoreBackup feeds the plain backup into the stdin of the command, a non-zero exit code fails the restore
+func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
+	reader, err := dumpfile.NewReader(ctx, b.cfg.Options.SourceFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.WithError(closeErr).Errorf("failed to close source file %s", b.cfg.Options.SourceFile)
+		}
+	}()
+
+	cmd := cli.CommandType{
+		Binary: b.cfg.Options.Command,
+		Args:   b.cfg.Options.Args,
+		Env:    b.cfg.Options.Env,
+	}
+
+	out, err := cli.RunWithStdin(ctx, cmd, reader)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return nil
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.SourceFile
+}
+
+// SetBackupPath sets the file to restore
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	b.cfg.Options.SourceFile = path
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.HostName
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
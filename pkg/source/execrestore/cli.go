@@ -0,0 +1,11 @@
+package execrestore
+
+type Options struct {
+	// Command is the binary the backup is fed into, it is looked up in PATH
+	Command string `validate:"min=1"`
+	Args    []string
+	// Env holds additional environment variables of the command as KEY=value
+	Env []string
+	// SourceFile is passed to the stdin of the command, it is decrypted and uncompressed on the fly
+	SourceFile string `validate:"min=1"`
+}
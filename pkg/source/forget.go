@@ -0,0 +1,82 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/restic"
+	"github.com/mittwald/brudi/pkg/timeout"
+)
+
+const (
+	ReportFormatTable = "table"
+	ReportFormatJSON  = "json"
+)
+
+// DoForgetForKind applies the restic forget policy of the given kind and writes a report of the kept and removed
+// snapshots to out. Without a kind, the global forget policy is applied to the whole repository.
+func DoForgetForKind(ctx context.Context, kind string, dryRun, useResticPrune bool, format string, out io.Writer) error {
+	return timeout.RunWithConfig(ctx, func(ctx context.Context) error {
+		return doForgetForKind(ctx, kind, dryRun, useResticPrune, format, out)
+	})
+}
+
+func doForgetForKind(ctx context.Context, kind string, dryRun, useResticPrune bool, format string, out io.Writer) error {
+	if format != ReportFormatTable && format != ReportFormatJSON {
+		return fmt.Errorf("unsupported report format '%s'", format)
+	}
+
+	logKind := log.WithFields(
+		log.Fields{
+			"kind": kind,
+		},
+	)
+
+	var hostname string
+	var paths []string
+	if kind != "" {
+		backend, err := getGenericBackendForKind(kind)
+		if err != nil {
+			return err
+		}
+		hostname = backend.GetHostname()
+		paths = backupPaths(backend)
+	}
+
+	resticClient, err := restic.NewResticClient(logKind, kind, hostname, paths...)
+	if err != nil {
+		return err
+	}
+
+	// see DoBackupForKind, `restic forget --prune` has no JSON-output
+	if resticClient.Config.Forget.Flags.Prune {
+		useResticPrune = true
+		resticClient.Config.Forget.Flags.Prune = false
+	}
+	// a dry-run configured for forget can't be overridden by the command line
+	dryRun = resticClient.Config.Forget.Flags.DryRun || dryRun
+	resticClient.Config.Forget.Flags.DryRun = dryRun
+
+	report, err := resticClient.DoResticForgetReport(ctx)
+	if err != nil {
+		return err
+	}
+
+	if format == ReportFormatJSON {
+		err = report.WriteJSON(out)
+	} else {
+		err = report.WriteTable(out)
+	}
+	if err != nil {
+		return err
+	}
+
+	if useResticPrune && !dryRun {
+		return resticClient.DoResticPrune(ctx)
+	}
+
+	return nil
+}
@@ -6,11 +6,9 @@ import (
 	"os"
 
 	"github.com/pkg/errors"
-)
 
-type Options struct {
-	Path string `validate:"min=1"`
-}
+	"github.com/mittwald/brudi/pkg/restic"
+)
 
 type ConfigBasedBackend struct {
 	cfg *Config
@@ -18,7 +16,9 @@ type ConfigBasedBackend struct {
 
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	config := &Config{
-		Options: &Options{},
+		Options: &Options{
+			Paths: []string{},
+		},
 	}
 
 	if err := config.InitFromViper(); err != nil {
@@ -28,23 +28,41 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	return &ConfigBasedBackend{cfg: config}, nil
 }
 
+// CreateBackup checks that every configured path exists, the paths are backed up by restic directly
 func (b *ConfigBasedBackend) CreateBackup(_ context.Context) error {
-	path := b.cfg.Options.Path
-
-	info, err := os.Stat(path)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-
-	if !info.IsDir() {
-		return errors.WithStack(fmt.Errorf("configured path %s is not a directory", path))
+	for _, path := range b.cfg.Options.Paths {
+		if _, err := os.Lstat(path); err != nil {
+			return errors.WithStack(fmt.Errorf("configured path %s is not accessible: %w", path, err))
+		}
 	}
 
 	return nil
 }
 
+// ConfigureResticBackup maps the excludes and limits onto the flags of "restic backup"
+func (b *ConfigBasedBackend) ConfigureResticBackup(flags *restic.BackupFlags) {
+	opts := b.cfg.Options
+
+	flags.Exclude = append(flags.Exclude, opts.Exclude...)
+	// restic re-includes files matching a negated pattern, if it comes after the excluding patterns
+	for _, include := range opts.Include {
+		flags.Exclude = append(flags.Exclude, "!"+include)
+	}
+	flags.ExcludeCaches = flags.ExcludeCaches || opts.ExcludeCaches
+	flags.OneFileSystem = flags.OneFileSystem || opts.OneFileSystem
+	if opts.ExcludeLargerThan > 0 {
+		flags.ExcludeLargerThan = opts.ExcludeLargerThan
+	}
+}
+
+// GetBackupPath returns the first of the configured paths
 func (b *ConfigBasedBackend) GetBackupPath() string {
-	return b.cfg.Options.Path
+	return b.cfg.Options.Paths[0]
+}
+
+// GetBackupPaths returns all configured paths
+func (b *ConfigBasedBackend) GetBackupPaths() []string {
+	return b.cfg.Options.Paths
 }
 
 func (b *ConfigBasedBackend) GetHostname() string {
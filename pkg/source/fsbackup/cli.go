@@ -0,0 +1,19 @@
+package fsbackup
+
+type Options struct {
+	// Path is a single path to back up, it is backed up along with Paths
+	Path string
+	// Paths to back up, all of them end up in a single snapshot
+	Paths []string
+	// Exclude holds restic exclude patterns, e.g. "*.log" or "/srv/app/cache"
+	Exclude []string
+	// Include holds patterns of files which are backed up even though they match an exclude pattern.
+	// They are passed to restic as negated exclude patterns, which requires restic 0.16 or newer.
+	Include []string
+	// ExcludeCaches leaves out directories containing a CACHEDIR.TAG file, like "restic backup --exclude-caches"
+	ExcludeCaches bool
+	// OneFileSystem doesn't cross file system boundaries, like "restic backup --one-file-system"
+	OneFileSystem bool
+	// ExcludeLargerThan leaves out files larger than the given number of bytes, 0 disables the limit
+	ExcludeLargerThan int `validate:"min=0"`
+}
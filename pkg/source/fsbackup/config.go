@@ -2,7 +2,10 @@ package fsbackup
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/config"
@@ -31,5 +34,29 @@ func (c *Config) InitFromViper() error {
 		}
 	}
 
-	return config.Validate(c)
+	if c.Options.Path != "" {
+		c.Options.Paths = append([]string{c.Options.Path}, c.Options.Paths...)
+	}
+
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	if len(c.Options.Paths) == 0 {
+		sl.ReportError(c.Options.Paths, "paths", "Paths", "pathOrPathsRequired", "")
+	}
+	for _, path := range c.Options.Paths {
+		if path == "" {
+			sl.ReportError(c.Options.Paths, "paths", "Paths", "emptyPath", "")
+		}
+	}
+
+	// restic matches the patterns like filepath.Match, a leading "!" is handled by restic itself
+	for _, pattern := range append(append([]string{}, c.Options.Exclude...), c.Options.Include...) {
+		if _, err := filepath.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+			sl.ReportError(pattern, "exclude", "Exclude", "invalidPattern", pattern)
+		}
+	}
 }
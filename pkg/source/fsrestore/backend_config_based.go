@@ -1,18 +1,45 @@
 package fsrestore
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
 
 type Options struct {
+	// Path of the directory in the snapshot
 	Path string `validate:"min=1"`
+	// Target directory the content of Path is restored into. If empty, restic restores Path below its configured target.
+	Target string
+	// Mirror removes the files of Target which don't exist in the snapshot, otherwise they are kept
+	Mirror bool
+	// Ownership restores the owners of the snapshot when running as root, otherwise the files are owned by the user
+	// running brudi
+	Ownership bool
+	// Verify compares the restored files against the listing of the snapshot
+	Verify bool
 }
 
 type ConfigBasedBackend struct {
 	cfg *Config
+	// staging directory restic restored the snapshot into
+	staging string
+	// listing of the restored snapshot
+	listing *restic.LsResult
 }
 
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	config := &Config{
-		Options: &Options{},
+		Options: &Options{
+			Ownership: true,
+			Verify:    true,
+		},
 	}
 
 	if err := config.InitFromViper(); err != nil {
@@ -22,10 +49,72 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	return &ConfigBasedBackend{cfg: config}, nil
 }
 
-func (b *ConfigBasedBackend) RestoreBackup(context.Context) error {
+// RestoresFromRestic returns whether the snapshot is restored into a staging directory next to the target
+func (b *ConfigBasedBackend) RestoresFromRestic() bool {
+	return b.cfg.Options.Target != ""
+}
+
+// RestoreFromRestic restores Path of the snapshot into a staging directory next to the target,
+// thus its files can be moved into the target instead of being copied
+func (b *ConfigBasedBackend) RestoreFromRestic(ctx context.Context, client *restic.Client) error {
+	listing, err := client.DoResticLs(ctx)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Clean(b.cfg.Options.Target)
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(target), "."+filepath.Base(target)+".brudi-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = client.DoResticRestoreInto(ctx, listing.SnapshotID, staging, b.cfg.Options.Path); err != nil {
+		if removeErr := os.RemoveAll(staging); removeErr != nil {
+			log.WithError(removeErr).Warnf("failed to remove staging directory %s", staging)
+		}
+		return err
+	}
+
+	b.staging = staging
+	b.listing = listing
 	return nil
 }
 
+// RestoreBackup moves the files restored by restic into the target directory and verifies them afterwards.
+// Without a target directory, restic already restored the files and there is nothing left to do.
+func (b *ConfigBasedBackend) RestoreBackup(context.Context) error {
+	if b.cfg.Options.Target == "" {
+		return nil
+	}
+	if b.staging == "" {
+		return errors.WithStack(fmt.Errorf("restoring into target %s requires restic", b.cfg.Options.Target))
+	}
+	defer func() {
+		if err := os.RemoveAll(b.staging); err != nil {
+			log.WithError(err).Warnf("failed to remove staging directory %s", b.staging)
+		}
+	}()
+
+	opts := b.cfg.Options
+	restored, err := move(filepath.Join(b.staging, opts.Path), opts.Target, opts.Mirror, opts.Ownership)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"snapshot": b.listing.SnapshotID,
+		"target":   opts.Target,
+		"files":    len(restored),
+	}).Info("restored files into target")
+
+	if !opts.Verify {
+		return nil
+	}
+	return verify(b.listing, opts.Path, opts.Target, opts.Mirror, opts.Ownership)
+}
+
 func (b *ConfigBasedBackend) GetBackupPath() string {
 	return b.cfg.Options.Path
 }
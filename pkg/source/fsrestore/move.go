@@ -0,0 +1,146 @@
+package fsrestore
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// modeMask holds the bits of a mode which are restored
+const modeMask = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// move moves the content of src into target, existing files are replaced and files which don't exist in src are
+// removed in mirror mode. The attributes of the directories are taken from src, since existing directories are kept.
+// It returns the names of the restored files relative to target.
+func move(src, target string, mirror, ownership bool) (map[string]struct{}, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !info.IsDir() {
+		return nil, errors.WithStack(fmt.Errorf("restored path %s is not a directory", src))
+	}
+	if err = os.MkdirAll(target, 0o700); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	restored := map[string]struct{}{}
+	var dirs []string
+	err = filepath.WalkDir(src, func(srcPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		name, err := filepath.Rel(src, srcPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		restored[name] = struct{}{}
+		if d.IsDir() {
+			dirs = append(dirs, name)
+			return makeDir(filepath.Join(target, name))
+		}
+		return moveFile(srcPath, filepath.Join(target, name), ownership)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if mirror {
+		if err = removeOthers(target, restored); err != nil {
+			return nil, err
+		}
+	}
+
+	// the innermost directories come last, their times would be changed by setting those of their parents first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = copyAttributes(filepath.Join(src, dirs[i]), filepath.Join(target, dirs[i]), ownership); err != nil {
+			return nil, err
+		}
+	}
+	return restored, nil
+}
+
+// makeDir creates the directory, an existing directory is kept while any other file is replaced
+func makeDir(dst string) error {
+	existing, err := os.Lstat(dst)
+	switch {
+	case err == nil && existing.IsDir():
+		return nil
+	case err == nil:
+		if err = os.Remove(dst); err != nil {
+			return errors.WithStack(err)
+		}
+	case !os.IsNotExist(err):
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Mkdir(dst, 0o700))
+}
+
+// moveFile replaces dst by src, which keeps the attributes restic restored
+func moveFile(src, dst string, ownership bool) error {
+	existing, err := os.Lstat(dst)
+	if err == nil && existing.IsDir() {
+		if err = os.RemoveAll(dst); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err = os.Rename(src, dst); err != nil {
+		return errors.WithStack(err)
+	}
+	if !ownership && os.Geteuid() == 0 {
+		return errors.WithStack(os.Lchown(dst, os.Geteuid(), os.Getegid()))
+	}
+	return nil
+}
+
+// removeOthers removes the files below target which weren't restored
+func removeOthers(target string, restored map[string]struct{}) error {
+	return filepath.WalkDir(target, func(fsPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		name, err := filepath.Rel(target, fsPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if _, ok := restored[name]; ok {
+			return nil
+		}
+
+		log.WithField("path", fsPath).Debug("removing file which doesn't exist in the snapshot")
+		if err = os.RemoveAll(fsPath); err != nil {
+			return errors.WithStack(err)
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// copyAttributes sets the mode, owner and modification time of dst to those of src
+func copyAttributes(src, dst string, ownership bool) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if os.Geteuid() == 0 {
+		uid, gid := os.Geteuid(), os.Getegid()
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && ownership {
+			uid, gid = int(stat.Uid), int(stat.Gid)
+		}
+		if err = os.Lchown(dst, uid, gid); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err = os.Chmod(dst, info.Mode()&modeMask); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Chtimes(dst, info.ModTime(), info.ModTime()))
+}
@@ -0,0 +1,106 @@
+package fsrestore
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+// ErrVerificationFailed is returned if the restored files don't match the listing of the snapshot
+var ErrVerificationFailed = fmt.Errorf("restored files don't match the snapshot")
+
+// verify compares the files below target with the files below path in the listing of the snapshot. Their size and
+// mode have to match, as well as their owner if ownership is restored. In mirror mode, other files must not exist.
+func verify(listing *restic.LsResult, path, target string, mirror, ownership bool) error {
+	path = filepath.Clean(path)
+	checkOwner := ownership && os.Geteuid() == 0
+
+	expected := map[string]struct{}{}
+	var mismatches []string
+	for _, file := range listing.Files {
+		name, ok := relative(path, file.Path)
+		if !ok {
+			continue
+		}
+		expected[name] = struct{}{}
+
+		if mismatch := compare(&file, filepath.Join(target, name), checkOwner); mismatch != "" {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s", name, mismatch))
+		}
+	}
+
+	if mirror {
+		err := filepath.WalkDir(target, func(fsPath string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return errors.WithStack(err)
+			}
+			name, err := filepath.Rel(target, fsPath)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if _, ok := expected[name]; !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s: doesn't exist in the snapshot", name))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(mismatches) > 0 {
+		for _, mismatch := range mismatches {
+			log.WithField("target", target).Warn(mismatch)
+		}
+		return errors.WithStack(fmt.Errorf("%w: %d mismatches, e.g. %s", ErrVerificationFailed, len(mismatches), mismatches[0]))
+	}
+
+	log.WithFields(log.Fields{
+		"snapshot": listing.SnapshotID,
+		"target":   target,
+		"files":    len(expected),
+	}).Info("restored files match the snapshot")
+	return nil
+}
+
+// relative returns the name of the listed file relative to path, if it is below path
+func relative(path, filePath string) (string, bool) {
+	if path == "/" {
+		return strings.TrimPrefix(filePath, "/"), filePath != "/"
+	}
+	if !strings.HasPrefix(filePath, path+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(filePath, path+"/"), true
+}
+
+// compare returns the difference between the listed file and the restored one, if any
+func compare(file *restic.LsFile, fsPath string, checkOwner bool) string {
+	info, err := os.Lstat(fsPath)
+	switch {
+	case os.IsNotExist(err):
+		return "missing"
+	case err != nil:
+		return err.Error()
+	case !info.Mode().IsRegular():
+		return fmt.Sprintf("expected a regular file, got %s", info.Mode().Type())
+	case uint64(info.Size()) != file.Size:
+		return fmt.Sprintf("expected size %d, got %d", file.Size, info.Size())
+	case os.FileMode(file.Permissions)&modeMask != info.Mode()&modeMask:
+		return fmt.Sprintf("expected mode %s, got %s", os.FileMode(file.Permissions)&modeMask, info.Mode()&modeMask)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && checkOwner &&
+		(int(stat.Uid) != file.User || int(stat.Gid) != file.Group) {
+		return fmt.Sprintf("expected owner %d:%d, got %d:%d", file.User, file.Group, stat.Uid, stat.Gid)
+	}
+	return ""
+}
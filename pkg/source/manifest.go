@@ -0,0 +1,79 @@
+package source
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/manifest"
+)
+
+// writeManifest writes the manifest of the backup and returns its path. Backups which aren't a single file,
+// like directories or several paths, don't get a manifest, thus an empty path is returned.
+func writeManifest(ctx context.Context, logKind *log.Entry, kind string, backend Generic) (string, error) {
+	backupPath := backend.GetBackupPath()
+	if _, ok := backend.(MultiPather); ok || !manifest.IsRegularFile(backupPath) {
+		logKind.WithField("path", backupPath).Debug("skipping manifest, backup isn't a regular file")
+		return "", nil
+	}
+
+	var toolVersion string
+	if versioner, ok := backend.(ToolVersioner); ok {
+		toolVersion = versioner.GetToolVersion(ctx)
+	}
+
+	m, err := manifest.Write(backupPath, kind, toolVersion, backend.GetHostname())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	logKind.WithFields(
+		log.Fields{
+			"path":   manifest.FileName(backupPath),
+			"sha256": m.SHA256,
+			"size":   m.Size,
+		},
+	).Info("wrote manifest")
+	return manifest.FileName(backupPath), nil
+}
+
+// verifyManifest checks the backup against its manifest before restoring it. Mismatching backups are refused
+// unless force is set, backups without a manifest, e.g. created by older versions, are restored with a warning.
+func verifyManifest(logKind *log.Entry, backend GenericRestore, force bool) error {
+	backupPath := backend.GetBackupPath()
+	if !manifest.IsRegularFile(backupPath) {
+		return nil
+	}
+
+	m, err := manifest.Verify(backupPath)
+	switch {
+	case err == nil:
+		logKind.WithFields(
+			log.Fields{
+				"path":      backupPath,
+				"sha256":    m.SHA256,
+				"createdAt": m.CreatedAt,
+			},
+		).Info("backup matches its manifest")
+		return nil
+	case errors.Is(err, manifest.ErrNoManifest):
+		logKind.WithField("path", backupPath).Warn("backup has no manifest, unable to verify its integrity")
+		return nil
+	case force:
+		logKind.WithError(err).Warn("backup failed verification, restoring anyway as forced")
+		return nil
+	default:
+		return err
+	}
+}
+
+// removeManifest removes the manifest of the backup, if there is one
+func removeManifest(backupPath string) error {
+	err := os.Remove(manifest.FileName(backupPath))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -3,12 +3,16 @@ package mongodump
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/internal"
 	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/validate"
 )
 
 type ConfigBasedBackend struct {
@@ -45,6 +49,23 @@ func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
 	return nil
 }
 
+// ValidateBackup checks the header of archives, or that all bson files of the output directory consist of complete documents
+func (b *ConfigBasedBackend) ValidateBackup(ctx context.Context) error {
+	if b.cfg.Options.Flags.Archive != "" {
+		return validate.File(ctx, b.cfg.Options.Flags.Archive, validate.MongoArchive)
+	}
+
+	return filepath.WalkDir(b.cfg.Options.Flags.Out, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if entry.IsDir() || !(strings.HasSuffix(path, ".bson") || strings.HasSuffix(path, ".bson.gz")) {
+			return nil
+		}
+		return validate.File(ctx, path, validate.BSON)
+	})
+}
+
 func (b *ConfigBasedBackend) GetBackupPath() string {
 	if b.cfg.Options.Flags.Archive != "" {
 		return b.cfg.Options.Flags.Archive
@@ -53,6 +74,10 @@ func (b *ConfigBasedBackend) GetBackupPath() string {
 	return b.cfg.Options.Flags.Out
 }
 
+func (b *ConfigBasedBackend) GetToolVersion(ctx context.Context) string {
+	return cli.ToolVersion(ctx, binary)
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
@@ -52,6 +52,15 @@ func (b *ConfigBasedBackend) GetBackupPath() string {
 	return b.cfg.Options.Flags.Dir
 }
 
+// SetBackupPath sets the archive or the directory to restore, whichever is configured
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	if b.cfg.Options.Flags.Archive != "" {
+		b.cfg.Options.Flags.Archive = path
+		return
+	}
+	b.cfg.Options.Flags.Dir = path
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
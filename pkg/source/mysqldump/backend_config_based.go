@@ -3,15 +3,20 @@ package mysqldump
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"strings"
 
 	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/validate"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type ConfigBasedBackend struct {
 	cfg *Config
+	// dumps written in per-database mode
+	dumps []string
 }
 
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
@@ -19,6 +24,9 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 		&Options{
 			Flags:          &Flags{},
 			AdditionalArgs: []string{},
+			PerDatabase: &PerDatabase{
+				Concurrency: 1,
+			},
 		},
 	}
 
@@ -31,11 +39,11 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 }
 
 func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
-	gzip := false
-	// create temporary, unzipped backup first, thus trim '.gz' extension
-	if strings.HasSuffix(b.cfg.Options.Flags.ResultFile, cli.GzipSuffix) {
-		b.cfg.Options.Flags.ResultFile = strings.TrimSuffix(b.cfg.Options.Flags.ResultFile, cli.GzipSuffix)
-		gzip = true
+	if b.cfg.Options.PerDatabase.Enabled {
+		return b.createPerDatabaseBackup(ctx)
+	}
+	if !dumpfile.IsPlain(b.cfg.Options.Flags.ResultFile) {
+		return b.createTransformedBackup(ctx)
 	}
 
 	cmd := cli.CommandType{
@@ -47,25 +55,83 @@ func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
 		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
 	}
 
-	// zip backup, update flag with the name returned by GzipFile for correct handover to restic
-	if gzip {
-		b.cfg.Options.Flags.ResultFile, err = cli.GzipFile(b.cfg.Options.Flags.ResultFile)
-		if err != nil {
-			return err
-		}
+	return nil
+}
+
+// createTransformedBackup compresses and encrypts the dump while it is written to stdout,
+// thus no plain copy is stored on disk
+func (b *ConfigBasedBackend) createTransformedBackup(ctx context.Context) error {
+	flags := *b.cfg.Options.Flags
+	flags.ResultFile = ""
+	options := *b.cfg.Options
+	options.Flags = &flags
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   cli.StructToCLI(&options),
+	}
+
+	var out []byte
+	err := dumpfile.Write(b.cfg.Options.Flags.ResultFile, func(w io.Writer) (runErr error) {
+		out, runErr = cli.RunWithStdout(ctx, cmd, w)
+		return runErr
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
 	}
 
 	return nil
 }
 
+// ValidateBackup checks the trailer written at the end of complete dumps
+func (b *ConfigBasedBackend) ValidateBackup(ctx context.Context) error {
+	if !b.hasTrailer() {
+		log.WithField("path", b.GetBackupPath()).Debug("skipping validation, dump has no trailer due to its flags")
+		return nil
+	}
+	if b.cfg.Options.PerDatabase.Enabled {
+		for _, dump := range b.dumps {
+			if err := validate.File(ctx, dump, validate.MySQLDump); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return validate.File(ctx, b.GetBackupPath(), validate.MySQLDump)
+}
+
+// hasTrailer returns whether the dump ends with a trailer, which is a comment written to the result file
+func (b *ConfigBasedBackend) hasTrailer() bool {
+	flags := b.cfg.Options.Flags
+	if flags.Tab != "" || flags.Compact || flags.SkipComments {
+		return false
+	}
+	for _, arg := range b.cfg.Options.AdditionalArgs {
+		if arg == "--compact" || arg == "--skip-comments" || arg == "--comments=0" {
+			return false
+		}
+	}
+	return true
+}
+
+// GetBackupPath returns the result file, or the directory of the dumps in per-database mode
 func (b *ConfigBasedBackend) GetBackupPath() string {
+	if b.cfg.Options.PerDatabase.Enabled {
+		return b.cfg.Options.PerDatabase.Directory
+	}
 	return b.cfg.Options.Flags.ResultFile
 }
 
+func (b *ConfigBasedBackend) GetToolVersion(ctx context.Context) string {
+	return cli.ToolVersion(ctx, binary)
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
 
 func (b *ConfigBasedBackend) CleanUp() error {
+	if b.cfg.Options.PerDatabase.Enabled {
+		return b.cleanUpPerDatabase()
+	}
 	return os.Remove(b.GetBackupPath())
 }
@@ -8,6 +8,22 @@ const (
 type Options struct {
 	Flags          *Flags
 	AdditionalArgs []string
+	PerDatabase    *PerDatabase `flag:"-"`
+}
+
+// PerDatabase dumps every database of the server into its own file within Directory
+type PerDatabase struct {
+	Enabled bool
+	// Directory the dumps are written to, each named after its database, e.g. "<Directory>/shop.sql.gz"
+	Directory string
+	// Suffix is appended to ".sql" and selects the compression and encryption of the dumps, e.g. ".gz"
+	Suffix string
+	// Include restricts the dumped databases to the ones matching one of the patterns
+	Include []string
+	// Exclude skips the databases matching one of the patterns
+	Exclude []string
+	// Concurrency is the number of databases which are dumped at the same time
+	Concurrency int `validate:"min=1"`
 }
 
 type Flags struct {
@@ -36,7 +52,7 @@ type Flags struct {
 	Password                   string   `flag:"--password="`
 	PluginDir                  string   `flag:"--plugin-dir="`
 	Protocol                   string   `flag:"--protocol="`
-	ResultFile                 string   `flag:"--result-file="                       validate:"min=1"`
+	ResultFile                 string   `flag:"--result-file="`
 	ServerPublicKeyPath        string   `flag:"--server-public-key-path="`
 	SharedMemoryBaseName       string   `flag:"--shared-memory-base-name="`
 	ShowCreateSkipSecondary    string   `flag:"--show-create-skip-secondary-engine="`
@@ -1,9 +1,11 @@
 package mysqldump
 
 import (
+	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/config"
+	"github.com/mittwald/brudi/pkg/databases"
 )
 
 const (
@@ -20,5 +22,24 @@ func (c *Config) InitFromViper() error {
 		return errors.WithStack(err)
 	}
 
-	return config.Validate(c)
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	perDatabase := c.Options.PerDatabase
+	if !perDatabase.Enabled {
+		if c.Options.Flags.ResultFile == "" {
+			sl.ReportError(c.Options.Flags.ResultFile, "resultFile", "ResultFile", "resultFileRequired", "")
+		}
+		return
+	}
+
+	if perDatabase.Directory == "" {
+		sl.ReportError(perDatabase.Directory, "directory", "Directory", "directoryRequired", "")
+	}
+	if err := databases.ValidatePatterns(append(append([]string{}, perDatabase.Include...), perDatabase.Exclude...)); err != nil {
+		sl.ReportError(perDatabase.Include, "include", "Include", "invalidPattern", err.Error())
+	}
 }
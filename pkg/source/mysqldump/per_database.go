@@ -0,0 +1,189 @@
+package mysqldump
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+const (
+	clientBinary = "mysql"
+	dumpSuffix   = ".sql"
+)
+
+// systemDatabases are never dumped in per-database mode, they belong to the server instead of the application
+var systemDatabases = []string{"information_schema", "performance_schema", "mysql", "sys"}
+
+// clientFlags are the flags of the mysql client used to discover the databases, the options reading defaults
+// files have to be passed first
+type clientFlags struct {
+	NoDefaults        bool   `flag:"--no-defaults"`
+	DefaultsFile      string `flag:"--defaults-file="`
+	DefaultsExtraFile string `flag:"--defaults-extra-file="`
+	LoginPath         string `flag:"--login-path="`
+	Host              string `flag:"--host="`
+	Port              int    `flag:"--port="`
+	User              string `flag:"--user="`
+	Password          string `flag:"--password="`
+	Socket            string `flag:"--socket="`
+	Protocol          string `flag:"--protocol="`
+	SkipSsl           bool   `flag:"--skip-ssl"`
+	SslCa             string `flag:"--ssl-ca="`
+	SslCaPath         string `flag:"--ssl-capath="`
+	SslCert           string `flag:"--ssl-cert="`
+	SslCipher         string `flag:"--ssl-cipher="`
+	SslKey            string `flag:"--ssl-key="`
+	Batch             bool   `flag:"--batch"`
+	SkipColumnNames   bool   `flag:"--skip-column-names"`
+	Execute           string `flag:"--execute="`
+}
+
+// discoverDatabases returns the databases of the server which are selected by the include and exclude patterns
+func (b *ConfigBasedBackend) discoverDatabases(ctx context.Context) ([]string, error) {
+	flags := b.cfg.Options.Flags
+	cmd := cli.CommandType{
+		Binary: clientBinary,
+		Args: cli.StructToCLI(&clientFlags{
+			NoDefaults:        flags.NoDefaults,
+			DefaultsFile:      flags.DefaultsFile,
+			DefaultsExtraFile: flags.DefaultsExtraFile,
+			LoginPath:         flags.LoginPath,
+			Host:              flags.Host,
+			Port:              flags.Port,
+			User:              flags.User,
+			Password:          flags.Password,
+			Socket:            flags.Socket,
+			Protocol:          flags.Protocol,
+			SkipSsl:           flags.SkipSsl,
+			SslCa:             flags.SslCa,
+			SslCaPath:         flags.SslCaPath,
+			SslCert:           flags.SslCert,
+			SslCipher:         flags.SslCipher,
+			SslKey:            flags.SslKey,
+			Batch:             true,
+			SkipColumnNames:   true,
+			Execute:           "SHOW DATABASES",
+		}),
+	}
+
+	var stdout bytes.Buffer
+	out, err := cli.RunWithStdout(ctx, cmd, &stdout)
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("failed to list databases: %+v - %s", err, out))
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if name := scanner.Text(); name != "" {
+			names = append(names, name)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	perDatabase := b.cfg.Options.PerDatabase
+	exclude := append(append([]string{}, systemDatabases...), perDatabase.Exclude...)
+	return databases.Select(names, perDatabase.Include, exclude), nil
+}
+
+// createPerDatabaseBackup dumps every selected database into its own file within a staging directory, which replaces
+// the directory of the previous run once all dumps succeeded
+func (b *ConfigBasedBackend) createPerDatabaseBackup(ctx context.Context) error {
+	perDatabase := b.cfg.Options.PerDatabase
+	names, err := b.discoverDatabases(ctx)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.WithStack(fmt.Errorf("no databases to dump on %s", b.cfg.Options.Flags.Host))
+	}
+
+	log.WithFields(log.Fields{
+		"directory":   perDatabase.Directory,
+		"databases":   names,
+		"concurrency": perDatabase.Concurrency,
+	}).Info("dumping databases into separate files")
+
+	err = databases.ReplaceDirectory(perDatabase.Directory, func(staging string) error {
+		return databases.Run(ctx, perDatabase.Concurrency, names, func(ctx context.Context, database string) error {
+			return b.dumpDatabase(ctx, staging, database)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	dumps := make([]string, len(names))
+	for i, name := range names {
+		dumps[i] = b.databaseFile(perDatabase.Directory, name)
+	}
+	b.dumps = dumps
+	return nil
+}
+
+// databaseFile returns the dump of the given database within dir
+func (b *ConfigBasedBackend) databaseFile(dir, database string) string {
+	return databases.FileName(dir, database, dumpSuffix+b.cfg.Options.PerDatabase.Suffix)
+}
+
+// dumpDatabase dumps a single database to stdout and writes it to its file within dir
+func (b *ConfigBasedBackend) dumpDatabase(ctx context.Context, dir, database string) error {
+	flags := *b.cfg.Options.Flags
+	flags.ResultFile = ""
+	flags.AllDatabases = false
+	flags.Tables = nil
+	flags.Databases = []string{database}
+	options := *b.cfg.Options
+	options.Flags = &flags
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   cli.StructToCLI(&options),
+	}
+
+	fileName := b.databaseFile(dir, database)
+	var out []byte
+	err := dumpfile.Write(fileName, func(w io.Writer) (runErr error) {
+		out, runErr = cli.RunWithStdout(ctx, cmd, w)
+		return runErr
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	log.WithFields(log.Fields{
+		"database": database,
+		"path":     fileName,
+	}).Debug("dumped database")
+	return nil
+}
+
+// cleanUpPerDatabase removes the dumps and the directory, unless it contains other files
+func (b *ConfigBasedBackend) cleanUpPerDatabase() error {
+	for _, dump := range b.dumps {
+		if err := os.Remove(dump); err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+	}
+
+	entries, err := os.ReadDir(b.cfg.Options.PerDatabase.Directory)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(entries) > 0 {
+		log.WithField("directory", b.cfg.Options.PerDatabase.Directory).Debug("keeping directory, it contains other files")
+		return nil
+	}
+	return errors.WithStack(os.Remove(b.cfg.Options.PerDatabase.Directory))
+}
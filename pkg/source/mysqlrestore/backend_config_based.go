@@ -20,6 +20,7 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 			Flags:          &Flags{},
 			AdditionalArgs: []string{},
 			SourceFile:     "",
+			PerDatabase:    &PerDatabase{},
 		},
 	}
 	err := config.InitFromViper()
@@ -31,35 +32,48 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 }
 
 func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
-	fileName, err := cli.CheckAndGunzipFile(b.cfg.Options.SourceFile)
-	if err != nil {
-		return err
-	}
-	if b.cfg.Options.Flags.Execute == "" {
-		b.cfg.Options.Flags.Execute = fmt.Sprintf("source %s", fileName)
+	if b.cfg.Options.PerDatabase.Enabled {
+		return b.restorePerDatabase(ctx)
 	}
 	args := append(cli.StructToCLI(b.cfg.Options.Flags), b.cfg.Options.AdditionalArgs...)
 	cmd := cli.CommandType{
 		Binary: binary,
 		Args:   args,
 	}
-	var out []byte
-	out, err = cli.Run(ctx, cmd)
-	if err != nil {
-		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	if b.cfg.Options.Flags.Execute != "" {
+		out, err := cli.Run(ctx, cmd)
+		if err != nil {
+			return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+		}
+		return nil
 	}
-
-	return nil
+	return b.restoreDatabase(ctx, b.cfg.Options.SourceFile)
 }
 
+// GetBackupPath returns the file to restore, or the directory of the dumps in per-database mode
 func (b *ConfigBasedBackend) GetBackupPath() string {
+	if b.cfg.Options.PerDatabase.Enabled {
+		return b.cfg.Options.PerDatabase.Directory
+	}
 	return b.cfg.Options.SourceFile
 }
 
+// SetBackupPath sets the file to restore, or the directory of the dumps in per-database mode
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	if b.cfg.Options.PerDatabase.Enabled {
+		b.cfg.Options.PerDatabase.Directory = path
+		return
+	}
+	b.cfg.Options.SourceFile = path
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
 
 func (b *ConfigBasedBackend) CleanUp() error {
+	if b.cfg.Options.PerDatabase.Enabled {
+		return b.cleanUpPerDatabase()
+	}
 	return os.Remove(b.GetBackupPath())
 }
@@ -8,6 +8,16 @@ type Options struct {
 	Flags          *Flags
 	AdditionalArgs []string
 	SourceFile     string
+	PerDatabase    *PerDatabase `flag:"-"`
+}
+
+// PerDatabase restores the dumps written by the per-database mode of mysqldump from Directory
+type PerDatabase struct {
+	Enabled bool
+	// Directory containing the dumps, each named after its database
+	Directory string
+	// Databases to restore, all dumps of Directory are restored if empty
+	Databases []string
 }
 
 type Flags struct {
@@ -1,6 +1,7 @@
 package mysqlrestore
 
 import (
+	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/config"
@@ -20,5 +21,20 @@ func (c *Config) InitFromViper() error {
 		return errors.WithStack(err)
 	}
 
-	return config.Validate(c)
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	if !c.Options.PerDatabase.Enabled {
+		return
+	}
+	if c.Options.PerDatabase.Directory == "" {
+		sl.ReportError(c.Options.PerDatabase.Directory, "directory", "Directory", "directoryRequired", "")
+	}
+	// the dumps are passed to stdin, which mysql ignores when executing a statement
+	if c.Options.Flags.Execute != "" {
+		sl.ReportError(c.Options.Flags.Execute, "execute", "Execute", "executeNotSupported", "")
+	}
 }
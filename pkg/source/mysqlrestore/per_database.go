@@ -0,0 +1,106 @@
+package mysqlrestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+const dumpSuffix = ".sql"
+
+// selectDumps returns the dumps of the requested databases, or all dumps of the directory
+func (b *ConfigBasedBackend) selectDumps() (map[string]string, error) {
+	perDatabase := b.cfg.Options.PerDatabase
+	dumps, err := databases.Dumps(perDatabase.Directory, dumpSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(perDatabase.Databases) == 0 {
+		if len(dumps) == 0 {
+			return nil, errors.WithStack(fmt.Errorf("no dumps found in %s", perDatabase.Directory))
+		}
+		return dumps, nil
+	}
+
+	selected := map[string]string{}
+	for _, database := range perDatabase.Databases {
+		dump, ok := dumps[database]
+		if !ok {
+			return nil, errors.WithStack(fmt.Errorf("no dump of database %s found in %s", database, perDatabase.Directory))
+		}
+		selected[database] = dump
+	}
+	return selected, nil
+}
+
+// restorePerDatabase restores the selected dumps one after another
+func (b *ConfigBasedBackend) restorePerDatabase(ctx context.Context) error {
+	dumps, err := b.selectDumps()
+	if err != nil {
+		return err
+	}
+
+	for _, database := range databases.Names(dumps) {
+		if err = b.restoreDatabase(ctx, dumps[database]); err != nil {
+			return errors.WithStack(fmt.Errorf("database %s: %w", database, err))
+		}
+		log.WithFields(log.Fields{
+			"database": database,
+			"path":     dumps[database],
+		}).Info("restored database")
+	}
+	return nil
+}
+
+// restoreDatabase streams the plain content of a single dump into mysql, a dump of a single database selects it itself
+func (b *ConfigBasedBackend) restoreDatabase(ctx context.Context, fileName string) error {
+	reader, err := dumpfile.NewReader(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.WithError(closeErr).Warnf("failed to close %s", fileName)
+		}
+	}()
+
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   append(cli.StructToCLI(b.cfg.Options.Flags), b.cfg.Options.AdditionalArgs...),
+	}
+	out, err := cli.RunWithStdin(ctx, cmd, reader)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+	return nil
+}
+
+// cleanUpPerDatabase removes the dumps and the directory, unless it contains other files
+func (b *ConfigBasedBackend) cleanUpPerDatabase() error {
+	dumps, err := databases.Dumps(b.cfg.Options.PerDatabase.Directory, dumpSuffix)
+	if err != nil {
+		return err
+	}
+	for _, dump := range dumps {
+		if err = os.Remove(dump); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	entries, err := os.ReadDir(b.cfg.Options.PerDatabase.Directory)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(entries) > 0 {
+		log.WithField("directory", b.cfg.Options.PerDatabase.Directory).Debug("keeping directory, it contains other files")
+		return nil
+	}
+	return errors.WithStack(os.Remove(b.cfg.Options.PerDatabase.Directory))
+}
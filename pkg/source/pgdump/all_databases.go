@@ -0,0 +1,187 @@
+package pgdump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/validate"
+)
+
+// connection returns the flags of the client tools to connect to the server
+func (b *ConfigBasedBackend) connection() *databases.PostgresConnection {
+	flags := b.cfg.Options.Flags
+	return &databases.PostgresConnection{
+		Host:       flags.Host,
+		Port:       flags.Port,
+		Username:   flags.Username,
+		NoPassword: flags.NoPassword,
+	}
+}
+
+// maintenanceDatabase returns the database connected to for listing the databases and dumping the globals
+func (b *ConfigBasedBackend) maintenanceDatabase() string {
+	if b.cfg.Options.Flags.DBName != "" {
+		return b.cfg.Options.Flags.DBName
+	}
+	return databases.PostgresMaintenanceDatabase
+}
+
+// globalsFile returns the dump of the globals within dir
+func (b *ConfigBasedBackend) globalsFile(dir string) string {
+	return filepath.Join(dir, databases.PostgresGlobalsFile+b.cfg.Options.AllDatabases.Suffix)
+}
+
+// databaseFile returns the dump of the given database within dir, its name is escaped since PostgreSQL allows any
+// character in database names, see databases.FileName. The unescaped name is logged and restored by pgrestore.
+func (b *ConfigBasedBackend) databaseFile(dir, database string) string {
+	allDatabases := b.cfg.Options.AllDatabases
+	return databases.FileName(
+		filepath.Join(dir, databases.PostgresDatabasesDirectory),
+		database,
+		databases.PostgresDumpSuffix(b.cfg.Options.Flags.Format)+allDatabases.Suffix,
+	)
+}
+
+// createAllDatabasesBackup dumps the globals and every selected database into its own file within a staging directory,
+// which replaces the directory of the previous run once all dumps succeeded
+func (b *ConfigBasedBackend) createAllDatabasesBackup(ctx context.Context) error {
+	allDatabases := b.cfg.Options.AllDatabases
+	names, err := databases.PostgresDatabases(ctx, b.connection(), b.maintenanceDatabase())
+	if err != nil {
+		return err
+	}
+	names = databases.Select(names, allDatabases.Include, allDatabases.Exclude)
+	if len(names) == 0 {
+		return errors.WithStack(fmt.Errorf("no databases to dump on %s", b.cfg.Options.Flags.Host))
+	}
+
+	log.WithFields(log.Fields{
+		"directory":   allDatabases.Directory,
+		"databases":   names,
+		"concurrency": allDatabases.Concurrency,
+	}).Info("dumping databases into separate files")
+
+	err = databases.ReplaceDirectory(allDatabases.Directory, func(staging string) error {
+		if mkdirErr := os.Mkdir(filepath.Join(staging, databases.PostgresDatabasesDirectory), 0o755); mkdirErr != nil {
+			return errors.WithStack(mkdirErr)
+		}
+		if dumpErr := b.dumpGlobals(ctx, staging); dumpErr != nil {
+			return dumpErr
+		}
+		return databases.Run(ctx, allDatabases.Concurrency, names, func(ctx context.Context, database string) error {
+			return b.dumpDatabase(ctx, staging, database)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	dumps := make([]string, len(names))
+	for i, name := range names {
+		dumps[i] = b.databaseFile(allDatabases.Directory, name)
+	}
+	b.dumps = dumps
+	return nil
+}
+
+// dumpGlobals dumps the roles and tablespaces, which aren't part of the dumps of the databases, into dir
+func (b *ConfigBasedBackend) dumpGlobals(ctx context.Context, dir string) error {
+	args := append(cli.StructToCLI(b.connection()), "--database="+b.maintenanceDatabase(), "--globals-only")
+	if b.cfg.Options.Flags.Role != "" {
+		args = append(args, "--role="+b.cfg.Options.Flags.Role)
+	}
+	cmd := cli.CommandType{
+		Binary: globalsBinary,
+		Args:   args,
+	}
+
+	var out []byte
+	err := dumpfile.Write(b.globalsFile(dir), func(w io.Writer) (runErr error) {
+		out, runErr = cli.RunWithStdout(ctx, cmd, w)
+		return runErr
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("failed to dump globals: %+v - %s", err, out))
+	}
+	return nil
+}
+
+// dumpDatabase dumps a single database to stdout and writes it to its file within dir
+func (b *ConfigBasedBackend) dumpDatabase(ctx context.Context, dir, database string) error {
+	flags := *b.cfg.Options.Flags
+	flags.File = ""
+	flags.DBName = database
+	options := *b.cfg.Options
+	options.Flags = &flags
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   cli.StructToCLI(&options),
+	}
+
+	fileName := b.databaseFile(dir, database)
+	var out []byte
+	err := dumpfile.Write(fileName, func(w io.Writer) (runErr error) {
+		out, runErr = cli.RunWithStdout(ctx, cmd, w)
+		return runErr
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	log.WithFields(log.Fields{
+		"database": database,
+		"path":     fileName,
+	}).Debug("dumped database")
+	return nil
+}
+
+// validateAllDatabases checks the dump of the globals and of every database
+func (b *ConfigBasedBackend) validateAllDatabases(ctx context.Context) error {
+	if err := validate.File(ctx, b.globalsFile(b.cfg.Options.AllDatabases.Directory), validate.PostgresClusterDump); err != nil {
+		return err
+	}
+	for _, dump := range b.dumps {
+		if err := b.validateDump(ctx, dump); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanUpAllDatabases removes the dumps and their directories, unless they contain other files. Nothing is removed
+// if the backup failed, the directory still holds the dumps of the previous run then.
+func (b *ConfigBasedBackend) cleanUpAllDatabases() error {
+	if b.dumps == nil {
+		return nil
+	}
+	for _, dump := range append([]string{b.globalsFile(b.cfg.Options.AllDatabases.Directory)}, b.dumps...) {
+		if err := os.Remove(dump); err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+	}
+
+	directory := b.cfg.Options.AllDatabases.Directory
+	for _, dir := range []string{filepath.Join(directory, databases.PostgresDatabasesDirectory), directory} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(entries) > 0 {
+			log.WithField("directory", dir).Debug("keeping directory, it contains other files")
+			return nil
+		}
+		if err = os.Remove(dir); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -3,16 +3,20 @@ package pgdump
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/validate"
 )
 
 type ConfigBasedBackend struct {
 	cfg *Config
+	// dumps of the databases written in all-databases mode
+	dumps []string
 }
 
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
@@ -20,6 +24,9 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 		&Options{
 			Flags:          &Flags{},
 			AdditionalArgs: []string{},
+			AllDatabases: &AllDatabases{
+				Concurrency: 1,
+			},
 		},
 	}
 
@@ -32,43 +39,119 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 }
 
 func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
-	gzip := false
-	// create temporary, unzipped backup first, thus trim '.gz' extension
-	if strings.HasSuffix(b.cfg.Options.Flags.File, cli.GzipSuffix) {
-		b.cfg.Options.Flags.File = strings.TrimSuffix(b.cfg.Options.Flags.File, cli.GzipSuffix)
-		gzip = true
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.createAllDatabasesBackup(ctx)
 	}
+	if isDirectoryFormat(b.cfg.Options.Flags.Format) {
+		return b.createDirectoryBackup(ctx)
+	}
+	if !dumpfile.IsPlain(b.cfg.Options.Flags.File) {
+		return b.createTransformedBackup(ctx)
+	}
+
 	cmd := cli.CommandType{
 		Binary: binary,
 		Args:   cli.StructToCLI(b.cfg.Options),
 	}
-
 	out, err := cli.Run(ctx, cmd)
 	if err != nil {
 		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
 	}
 
-	// zip backup, update flag with the name returned by GzipFile for correct handover to restic
-	if gzip {
-		b.cfg.Options.Flags.File, err = cli.GzipFile(b.cfg.Options.Flags.File)
-		if err != nil {
-			return err
-		}
+	return nil
+}
+
+// createTransformedBackup compresses and encrypts the dump while it is written to stdout,
+// thus no plain copy is stored on disk
+func (b *ConfigBasedBackend) createTransformedBackup(ctx context.Context) error {
+	flags := *b.cfg.Options.Flags
+	flags.File = ""
+	options := *b.cfg.Options
+	options.Flags = &flags
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   cli.StructToCLI(&options),
+	}
+
+	var out []byte
+	err := dumpfile.Write(b.cfg.Options.Flags.File, func(w io.Writer) (runErr error) {
+		out, runErr = cli.RunWithStdout(ctx, cmd, w)
+		return runErr
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return nil
+}
+
+// ValidateBackup checks the trailer of plain-text dumps and lists the contents of the other formats with pg_restore
+func (b *ConfigBasedBackend) ValidateBackup(ctx context.Context) error {
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.validateAllDatabases(ctx)
+	}
+	return b.validateDump(ctx, b.GetBackupPath())
+}
+
+// validateDump checks a single dump written in the configured format
+func (b *ConfigBasedBackend) validateDump(ctx context.Context, fileName string) error {
+	switch b.cfg.Options.Flags.Format {
+	case "", "p", "plain":
+		return validate.File(ctx, fileName, validate.PostgresPlainDump)
+	case "d", "directory":
+		return b.listArchive(ctx, fileName, nil)
+	}
+
+	if dumpfile.IsPlain(fileName) {
+		return b.listArchive(ctx, fileName, nil)
+	}
+	reader, err := dumpfile.NewReader(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return b.listArchive(ctx, fileName, reader)
+}
+
+// listArchive runs "pg_restore --list" on the given dump, which is read from stdin if given
+func (b *ConfigBasedBackend) listArchive(ctx context.Context, fileName string, stdin io.Reader) error {
+	args := []string{"--list"}
+	if stdin == nil {
+		args = append(args, fileName)
+	}
+	cmd := cli.CommandType{
+		Binary: restoreBinary,
+		Args:   args,
 	}
 
+	out, err := cli.RunWithStdin(ctx, cmd, stdin)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%w: pg_restore is unable to read %s: %+v - %s", validate.ErrInvalidDump, fileName, err, out))
+	}
 	return nil
 }
 
+// GetBackupPath returns the dump, or the directory of the dumps in all-databases mode
 func (b *ConfigBasedBackend) GetBackupPath() string {
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.cfg.Options.AllDatabases.Directory
+	}
 	return b.cfg.Options.Flags.File
 }
 
+func (b *ConfigBasedBackend) GetToolVersion(ctx context.Context) string {
+	return cli.ToolVersion(ctx, binary)
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
 
 func (b *ConfigBasedBackend) CleanUp() error {
-	if b.cfg.Options.Flags.Format == "d" {
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.cleanUpAllDatabases()
+	}
+	if isDirectoryFormat(b.cfg.Options.Flags.Format) {
 		return os.RemoveAll(b.GetBackupPath())
 	}
 	return os.Remove(b.GetBackupPath())
@@ -2,11 +2,32 @@ package pgdump
 
 const (
 	binary = "pg_dump"
+	// globalsBinary dumps the roles and tablespaces in all-databases mode
+	globalsBinary = "pg_dumpall"
+	// restoreBinary is used to validate dumps in the archive formats
+	restoreBinary = "pg_restore"
 )
 
 type Options struct {
 	Flags          *Flags
 	AdditionalArgs []string
+	AllDatabases   *AllDatabases `flag:"-"`
+}
+
+// AllDatabases dumps every database of the server into its own file and the globals, i.e. roles and tablespaces,
+// with "pg_dumpall --globals-only" into Directory
+type AllDatabases struct {
+	Enabled bool
+	// Directory the dumps are written to, "<Directory>/globals.sql" and "<Directory>/databases/<name>.<format>"
+	Directory string
+	// Suffix is appended to the dumps and selects their compression and encryption, e.g. ".gz"
+	Suffix string
+	// Include restricts the dumped databases to the ones matching one of the patterns
+	Include []string
+	// Exclude skips the databases matching one of the patterns
+	Exclude []string
+	// Concurrency is the number of databases which are dumped at the same time
+	Concurrency int `validate:"min=1"`
 }
 
 type Flags struct {
@@ -29,7 +50,7 @@ type Flags struct {
 	// therefore we have to workaround by setting the corresponding password env-var
 	Password                   string `env:"PGPASSWORD"                       flag:"-"`
 	Role                       string `flag:"--role="`
-	Jobs                       int    `flag:"--jobs="`
+	Jobs                       int    `flag:"--jobs="                         validate:"min=0"`
 	Compress                   int    `flag:"--compress="`
 	ExtraFloatDigits           int    `flag:"--extra-float-digits="`
 	RowsPerInsert              int    `flag:"--rows-per-insert="`
@@ -1,9 +1,12 @@
 package pgdump
 
 import (
+	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/config"
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/dumpfile"
 )
 
 const (
@@ -25,5 +28,32 @@ func (c *Config) InitFromViper() error {
 		return errors.WithStack(err)
 	}
 
-	return config.Validate(c)
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	flags := c.Options.Flags
+	// pg_dump runs jobs in parallel only when writing the directory format, which it can't write to stdout
+	if flags.Jobs > 1 && !isDirectoryFormat(flags.Format) {
+		sl.ReportError(flags.Jobs, "jobs", "Jobs", "jobsRequireDirectoryFormat", flags.Format)
+	}
+	if isDirectoryFormat(flags.Format) && !dumpfile.IsPlain(flags.File) {
+		sl.ReportError(flags.File, "file", "File", "directoryFormatNotTransformable", flags.File)
+	}
+
+	allDatabases := c.Options.AllDatabases
+	if !allDatabases.Enabled {
+		return
+	}
+	if allDatabases.Directory == "" {
+		sl.ReportError(allDatabases.Directory, "directory", "Directory", "directoryRequired", "")
+	}
+	if databases.PostgresDumpSuffix(c.Options.Flags.Format) == "" {
+		sl.ReportError(c.Options.Flags.Format, "format", "Format", "singleFileFormat", c.Options.Flags.Format)
+	}
+	if err := databases.ValidatePatterns(append(append([]string{}, allDatabases.Include...), allDatabases.Exclude...)); err != nil {
+		sl.ReportError(allDatabases.Include, "include", "Include", "invalidPattern", err.Error())
+	}
 }
@@ -0,0 +1,69 @@
+package pgdump
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/databases"
+)
+
+// isDirectoryFormat returns whether pg_dump writes a directory with one file per table, which is the only format
+// supporting parallel jobs
+func isDirectoryFormat(format string) bool {
+	return format == "d" || format == "directory"
+}
+
+// createDirectoryBackup dumps into a staging directory next to the configured one, which replaces the dump of the
+// previous run only after pg_dump succeeded
+func (b *ConfigBasedBackend) createDirectoryBackup(ctx context.Context) error {
+	flags := *b.cfg.Options.Flags
+	if err := databases.CheckPostgresJobs(ctx, b.connection(), flags.DBName, flags.Jobs); err != nil {
+		return err
+	}
+
+	target := filepath.Clean(flags.File)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	// pg_dump refuses to write into directories which aren't empty
+	staging, err := os.MkdirTemp(filepath.Dir(target), "."+filepath.Base(target)+".brudi-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(staging); removeErr != nil {
+			log.WithError(removeErr).Warnf("failed to remove staging directory %s", staging)
+		}
+	}()
+
+	flags.File = staging
+	options := *b.cfg.Options
+	options.Flags = &flags
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   cli.StructToCLI(&options),
+	}
+	out, err := cli.Run(ctx, cmd)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	if err = os.RemoveAll(target); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = os.Rename(staging, target); err != nil {
+		return errors.WithStack(err)
+	}
+
+	log.WithFields(log.Fields{
+		"path": target,
+		"jobs": flags.Jobs,
+	}).Info("dumped database in directory format")
+	return nil
+}
@@ -0,0 +1,223 @@
+package pgrestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+// connection returns the flags of the client tools to connect to the server
+func (b *ConfigBasedBackend) connection() *databases.PostgresConnection {
+	flags := b.cfg.Options.Flags
+	return &databases.PostgresConnection{
+		Host:       flags.Host,
+		Port:       flags.Port,
+		Username:   flags.Username,
+		NoPassword: flags.NoPassword,
+	}
+}
+
+// maintenanceDatabase returns the database connected to for restoring the globals and creating the databases
+func (b *ConfigBasedBackend) maintenanceDatabase() string {
+	if b.cfg.Options.Flags.DBName != "" {
+		return b.cfg.Options.Flags.DBName
+	}
+	return databases.PostgresMaintenanceDatabase
+}
+
+// globalsDump returns the dump of the globals within the directory
+func (b *ConfigBasedBackend) globalsDump() (string, error) {
+	directory := b.cfg.Options.AllDatabases.Directory
+	dumps, err := databases.Dumps(directory, ".sql")
+	if err != nil {
+		return "", err
+	}
+	dump, ok := dumps[strings.TrimSuffix(databases.PostgresGlobalsFile, ".sql")]
+	if !ok {
+		return "", errors.WithStack(fmt.Errorf("no dump of the globals found in %s", directory))
+	}
+	return dump, nil
+}
+
+// selectDumps returns the dumps of the requested databases, or all dumps of the directory
+func (b *ConfigBasedBackend) selectDumps() (map[string]string, error) {
+	allDatabases := b.cfg.Options.AllDatabases
+	dumps, err := databases.PostgresDumps(allDatabases.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if len(allDatabases.Databases) == 0 {
+		if len(dumps) == 0 {
+			return nil, errors.WithStack(fmt.Errorf("no dumps found in %s", allDatabases.Directory))
+		}
+		return dumps, nil
+	}
+
+	selected := map[string]string{}
+	for _, database := range allDatabases.Databases {
+		dump, ok := dumps[database]
+		if !ok {
+			return nil, errors.WithStack(fmt.Errorf("no dump of database %s found in %s", database, allDatabases.Directory))
+		}
+		selected[database] = dump
+	}
+	return selected, nil
+}
+
+// restoreAllDatabases restores the globals first, then the selected databases one after another,
+// creating the ones which don't exist yet
+func (b *ConfigBasedBackend) restoreAllDatabases(ctx context.Context) error {
+	dumps, err := b.selectDumps()
+	if err != nil {
+		return err
+	}
+
+	if b.cfg.Options.AllDatabases.Globals {
+		var globals string
+		if globals, err = b.globalsDump(); err != nil {
+			return err
+		}
+		if err = b.restoreGlobals(ctx, globals); err != nil {
+			return errors.WithStack(fmt.Errorf("failed to restore globals: %w", err))
+		}
+		log.WithField("path", globals).Info("restored globals")
+	}
+
+	existing, err := databases.PostgresDatabases(ctx, b.connection(), b.maintenanceDatabase())
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, database := range existing {
+		exists[database] = true
+	}
+
+	for _, database := range databases.Names(dumps) {
+		if !exists[database] {
+			if err = databases.CreatePostgresDatabase(ctx, b.connection(), b.maintenanceDatabase(), database); err != nil {
+				return err
+			}
+			log.WithField("database", database).Info("created database")
+		}
+		if err = b.restoreDatabase(ctx, database, dumps[database]); err != nil {
+			return errors.WithStack(fmt.Errorf("database %s: %w", database, err))
+		}
+		log.WithFields(log.Fields{
+			"database": database,
+			"path":     dumps[database],
+		}).Info("restored database")
+	}
+	return nil
+}
+
+// restoreDatabase restores a dump with psql or pg_restore, depending on its format
+func (b *ConfigBasedBackend) restoreDatabase(ctx context.Context, database, fileName string) error {
+	if strings.HasSuffix(dumpfile.PlainName(fileName), ".sql") {
+		return b.restorePlain(ctx, database, fileName)
+	}
+
+	flags := *b.cfg.Options.Flags
+	flags.DBName = database
+	return b.restoreArchive(ctx, &flags, fileName)
+}
+
+// restorePlain streams a plain-text dump into psql connected to the given database. psql stops at the first error
+// and rolls back the whole dump, thus a failed restore doesn't leave a partially restored database behind.
+func (b *ConfigBasedBackend) restorePlain(ctx context.Context, database, fileName string) error {
+	cmd := cli.CommandType{
+		Binary: plainBinary,
+		Args:   append(cli.StructToCLI(b.connection()), "--dbname="+database, "--set=ON_ERROR_STOP=1", "--single-transaction"),
+	}
+	_, err := b.runWithDump(ctx, cmd, fileName)
+	return err
+}
+
+// restoreGlobals streams the dump of the globals into psql connected to the maintenance database. Roles and
+// tablespaces which exist already, at least the bootstrap superuser, can't be created again, thus psql continues
+// on errors and any other error fails the restore afterwards.
+func (b *ConfigBasedBackend) restoreGlobals(ctx context.Context, fileName string) error {
+	cmd := cli.CommandType{
+		Binary: plainBinary,
+		Args:   append(cli.StructToCLI(b.connection()), "--dbname="+b.maintenanceDatabase()),
+	}
+	out, err := b.runWithDump(ctx, cmd, fileName)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.Contains(line, "ERROR:") && !strings.Contains(line, "already exists") {
+			failed = append(failed, strings.TrimSpace(line))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.WithStack(fmt.Errorf("psql reported errors: %s", strings.Join(failed, "; ")))
+	}
+	return nil
+}
+
+// runWithDump runs the command with the plain content of the dump as stdin and returns its output
+func (b *ConfigBasedBackend) runWithDump(ctx context.Context, cmd cli.CommandType, fileName string) ([]byte, error) {
+	reader, err := dumpfile.NewReader(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.WithError(closeErr).Warnf("failed to close %s", fileName)
+		}
+	}()
+
+	var out []byte
+	out, err = cli.RunWithStdin(ctx, cmd, reader)
+	if err != nil {
+		return out, errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+	return out, nil
+}
+
+// cleanUpAllDatabases removes the dumps and their directories, unless they contain other files
+func (b *ConfigBasedBackend) cleanUpAllDatabases() error {
+	directory := b.cfg.Options.AllDatabases.Directory
+	dumps, err := databases.PostgresDumps(directory)
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(dumps)+1)
+	for _, dump := range dumps {
+		files = append(files, dump)
+	}
+	if globals, globalsErr := b.globalsDump(); globalsErr == nil {
+		files = append(files, globals)
+	}
+	for _, dump := range files {
+		if err = os.Remove(dump); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, dir := range []string{filepath.Join(directory, databases.PostgresDatabasesDirectory), directory} {
+		entries, readErr := os.ReadDir(dir)
+		if readErr != nil {
+			return errors.WithStack(readErr)
+		}
+		if len(entries) > 0 {
+			log.WithField("directory", dir).Debug("keeping directory, it contains other files")
+			return nil
+		}
+		if err = os.Remove(dir); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
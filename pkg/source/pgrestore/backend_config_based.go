@@ -8,10 +8,15 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
 )
 
 type ConfigBasedBackend struct {
 	cfg *Config
+	// staging directory restic restored the dump directory into
+	staging string
+	// path of the dump within the staging directory
+	restored string
 }
 
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
@@ -21,6 +26,9 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 			AdditionalArgs: []string{},
 			SourceFile:     "",
 			PGRestore:      false,
+			AllDatabases: &AllDatabases{
+				Globals: true,
+			},
 		},
 	}
 
@@ -33,33 +41,60 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 }
 
 func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
-	// Check if SourceFile is a directory (i.e., a pg_dump directory format)
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.restoreAllDatabases(ctx)
+	}
+
 	src := b.cfg.Options.SourceFile
+	if b.restored != "" {
+		src = b.restored
+	}
+	if isDirectoryFormat(b.cfg.Options.Flags.Format) {
+		return b.restoreDirectory(ctx, src)
+	}
+
 	info, statErr := os.Stat(src)
 	if statErr != nil {
 		return statErr
 	}
+	if info.IsDir() {
+		return b.restoreDirectory(ctx, src)
+	}
 
-	fileName := src
+	return b.restoreArchive(ctx, b.cfg.Options.Flags, src)
+}
 
-	if !info.IsDir() {
-		unzippedFileName, err := cli.CheckAndGunzipFile(src)
+// restoreArchive runs pg_restore with the given flags on a single archive. Archives which are neither compressed nor
+// encrypted are passed by name, thus pg_restore is able to seek within them. Several jobs require that, thus other
+// archives are restored into a temporary file first, which is removed right afterwards. Otherwise, the plain content
+// is streamed into pg_restore and never written to disk.
+func (b *ConfigBasedBackend) restoreArchive(ctx context.Context, flags *Flags, fileName string) error {
+	if dumpfile.IsPlain(fileName) || flags.Jobs > 1 {
+		plainName, removePlain, err := dumpfile.Open(ctx, fileName)
 		if err != nil {
 			return err
 		}
+		defer removePlain()
+		return b.runRestore(ctx, flags, plainName)
+	}
 
-		fileName = unzippedFileName
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   append(cli.StructToCLI(flags), b.cfg.Options.AdditionalArgs...),
 	}
+	_, err := b.runWithDump(ctx, cmd, fileName)
+	return err
+}
 
-	args := append(cli.StructToCLI(b.cfg.Options.Flags), b.cfg.Options.AdditionalArgs...)
+// runRestore runs pg_restore with the given flags on the given dump
+func (b *ConfigBasedBackend) runRestore(ctx context.Context, flags *Flags, fileName string) error {
+	args := append(cli.StructToCLI(flags), b.cfg.Options.AdditionalArgs...)
 	args = append(args, fileName)
 	cmd := cli.CommandType{
 		Binary: binary,
 		Args:   args,
 	}
-	var out []byte
-	var err error
-	out, err = cli.Run(ctx, cmd)
+	out, err := cli.Run(ctx, cmd)
 	if err != nil {
 		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
 	}
@@ -67,14 +102,37 @@ func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
 	return nil
 }
 
+// GetBackupPath returns the file to restore, or the directory of the dumps in all-databases mode
 func (b *ConfigBasedBackend) GetBackupPath() string {
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.cfg.Options.AllDatabases.Directory
+	}
 	return b.cfg.Options.SourceFile
 }
 
+// SetBackupPath sets the file to restore, or the directory of the dumps in all-databases mode
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	if b.cfg.Options.AllDatabases.Enabled {
+		b.cfg.Options.AllDatabases.Directory = path
+		return
+	}
+	b.cfg.Options.SourceFile = path
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
 
 func (b *ConfigBasedBackend) CleanUp() error {
+	if b.cfg.Options.AllDatabases.Enabled {
+		return b.cleanUpAllDatabases()
+	}
+	// the staging directory is removed right after restoring, the dump at the configured path wasn't touched
+	if b.staging != "" {
+		return nil
+	}
+	if info, err := os.Stat(b.GetBackupPath()); err == nil && info.IsDir() {
+		return os.RemoveAll(b.GetBackupPath())
+	}
 	return os.Remove(b.GetBackupPath())
 }
@@ -2,6 +2,8 @@ package pgrestore
 
 const (
 	binary = "pg_restore"
+	// plainBinary restores the globals and the databases dumped in plain format in all-databases mode
+	plainBinary = "psql"
 )
 
 type Options struct {
@@ -10,6 +12,18 @@ type Options struct {
 	SourceFile     string
 	PGRestore      bool
 	AdditionalArgs []string
+	AllDatabases   *AllDatabases `flag:"-"`
+}
+
+// AllDatabases restores the dumps written by the all-databases mode of pgdump from Directory
+type AllDatabases struct {
+	Enabled bool
+	// Directory containing the dump of the globals and the dumps of the databases
+	Directory string
+	// Databases to restore, all dumps of Directory are restored if empty
+	Databases []string
+	// Globals restores the roles and tablespaces before the databases
+	Globals bool
 }
 
 type Flags struct {
@@ -32,7 +46,7 @@ type Flags struct {
 	Trigger                    string `flag:"--trigger="`
 	Username                   string `flag:"--username="`
 	Compress                   int    `flag:"--compress="`
-	Jobs                       int    `flag:"--jobs="`
+	Jobs                       int    `flag:"--jobs="                         validate:"min=0"`
 	Port                       int    `flag:"--port="`
 	BinaryUpgrade              bool   `flag:"--binary-upgrade"`
 	Blobs                      bool   `flag:"--blobs"`
@@ -1,6 +1,7 @@
 package pgrestore
 
 import (
+	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/config"
@@ -25,5 +26,25 @@ func (c *Config) InitFromViper() error {
 		return errors.WithStack(err)
 	}
 
-	return config.Validate(c)
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	// pg_restore runs jobs in parallel only for the custom and the directory format
+	if c.Options.Flags.Jobs > 1 && (c.Options.Flags.Format == "t" || c.Options.Flags.Format == "tar") {
+		sl.ReportError(c.Options.Flags.Jobs, "jobs", "Jobs", "jobsNotSupportedByFormat", c.Options.Flags.Format)
+	}
+
+	if !c.Options.AllDatabases.Enabled {
+		return
+	}
+	if c.Options.AllDatabases.Directory == "" {
+		sl.ReportError(c.Options.AllDatabases.Directory, "directory", "Directory", "directoryRequired", "")
+	}
+	// missing databases are created before restoring them, pg_restore must not create them again
+	if c.Options.Flags.Create {
+		sl.ReportError(c.Options.Flags.Create, "create", "Create", "createNotSupported", "")
+	}
 }
@@ -0,0 +1,86 @@
+package pgrestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+// tocFile is written by pg_dump into every dump in directory format
+const tocFile = "toc.dat"
+
+// isDirectoryFormat returns whether the dump is a directory written by pg_dump, which is restored with parallel jobs
+func isDirectoryFormat(format string) bool {
+	return format == "d" || format == "directory"
+}
+
+// RestoresFromRestic returns whether the dump is restored from restic into a staging directory,
+// which is the case for the directory format
+func (b *ConfigBasedBackend) RestoresFromRestic() bool {
+	return isDirectoryFormat(b.cfg.Options.Flags.Format)
+}
+
+// RestoreFromRestic restores the dump directory from restic into a staging directory, thus it doesn't mix with
+// the files of an older dump at the same path. The staging directory is created within the restore workspace
+// if one is configured, otherwise next to the dump.
+func (b *ConfigBasedBackend) RestoreFromRestic(ctx context.Context, client *restic.Client) error {
+	// restic matches the include against the absolute paths of the snapshot
+	source, err := filepath.Abs(b.cfg.Options.SourceFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	parent := client.Config.Restore.Workspace
+	if parent == "" {
+		parent = filepath.Dir(source)
+	}
+	if err = os.MkdirAll(parent, 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(source)+".brudi-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = client.DoResticRestoreInto(ctx, client.Config.Restore.ID, staging, source); err != nil {
+		if removeErr := os.RemoveAll(staging); removeErr != nil {
+			log.WithError(removeErr).Warnf("failed to remove staging directory %s", staging)
+		}
+		return err
+	}
+
+	b.staging = staging
+	b.restored = filepath.Join(staging, source)
+	log.WithFields(log.Fields{
+		"staging": staging,
+		"path":    b.restored,
+	}).Info("restored dump directory from restic")
+	return nil
+}
+
+// restoreDirectory runs pg_restore with the configured jobs on the dump directory
+func (b *ConfigBasedBackend) restoreDirectory(ctx context.Context, dir string) error {
+	if b.staging != "" {
+		defer func() {
+			if err := os.RemoveAll(b.staging); err != nil {
+				log.WithError(err).Warnf("failed to remove staging directory %s", b.staging)
+			}
+		}()
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, tocFile)); err != nil {
+		return errors.WithStack(fmt.Errorf("%s is no dump in directory format: %w", dir, err))
+	}
+
+	flags := b.cfg.Options.Flags
+	if err := databases.CheckPostgresJobs(ctx, b.connection(), flags.DBName, flags.Jobs); err != nil {
+		return err
+	}
+	return b.runRestore(ctx, flags, dir)
+}
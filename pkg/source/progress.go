@@ -0,0 +1,53 @@
+package source
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+var (
+	handlersMutex    sync.Mutex
+	progressHandlers []restic.ProgressFunc
+	summaryHandlers  []restic.SummaryFunc
+)
+
+// RegisterProgressHandler adds a handler which receives the status of every "restic backup" run by brudi,
+// e.g. for exporting metrics or sending notifications. The status is logged regardless of the handlers.
+func RegisterProgressHandler(handler restic.ProgressFunc) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	progressHandlers = append(progressHandlers, handler)
+}
+
+// RegisterSummaryHandler adds a handler which receives the summary of every successful "restic backup" run by brudi
+func RegisterSummaryHandler(handler restic.SummaryFunc) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	summaryHandlers = append(summaryHandlers, handler)
+}
+
+// ResetHandlers removes all registered progress and summary handlers
+func ResetHandlers() {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	progressHandlers = nil
+	summaryHandlers = nil
+}
+
+// newResticClient creates the restic client of a backup, which passes its progress and summary on to the
+// registered handlers
+func newResticClient(logKind *log.Entry, kind, hostname string, backupPaths ...string) (*restic.Client, error) {
+	client, err := restic.NewResticClient(logKind, kind, hostname, backupPaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	client.ProgressHandlers = append(client.ProgressHandlers, progressHandlers...)
+	client.SummaryHandlers = append(client.SummaryHandlers, summaryHandlers...)
+	return client, nil
+}
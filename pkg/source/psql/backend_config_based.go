@@ -6,8 +6,10 @@ import (
 	"os"
 
 	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type ConfigBasedBackend struct {
@@ -31,21 +33,32 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	return &ConfigBasedBackend{cfg: config}, nil
 }
 
+// RestoreBackup streams the plain content of the dump into psql, unless a command is configured to run instead
 func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
-	fileName, err := cli.CheckAndGunzipFile(b.cfg.Options.SourceFile)
-	if err != nil {
-		return err
-	}
-	if b.cfg.Options.Flags.Command == "" {
-		b.cfg.Options.Flags.Command = fmt.Sprintf("\\i %s", fileName)
-	}
 	args := append(cli.StructToCLI(b.cfg.Options.Flags), b.cfg.Options.AdditionalArgs...)
 	cmd := cli.CommandType{
 		Binary: binary,
 		Args:   args,
 	}
-	var out []byte
-	out, err = cli.Run(ctx, cmd)
+	if b.cfg.Options.Flags.Command != "" {
+		out, err := cli.Run(ctx, cmd)
+		if err != nil {
+			return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+		}
+		return nil
+	}
+
+	reader, err := dumpfile.NewReader(ctx, b.cfg.Options.SourceFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.WithError(closeErr).Warnf("failed to close %s", b.cfg.Options.SourceFile)
+		}
+	}()
+
+	out, err := cli.RunWithStdin(ctx, cmd, reader)
 	if err != nil {
 		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
 	}
@@ -56,6 +69,11 @@ func (b *ConfigBasedBackend) GetBackupPath() string {
 	return b.cfg.Options.SourceFile
 }
 
+// SetBackupPath sets the file to restore
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	b.cfg.Options.SourceFile = path
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/timeout"
+	"github.com/mittwald/brudi/pkg/validate"
 )
 
 type ConfigBasedBackend struct {
@@ -34,37 +36,52 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 
 // Do a bgsave of the given redis instance
 func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
-	gzip := false
-	// create temporary, unzipped backup first, thus trim '.gz' extension
-	if strings.HasSuffix(b.cfg.Options.Flags.Rdb, cli.GzipSuffix) {
-		b.cfg.Options.Flags.Rdb = strings.TrimSuffix(b.cfg.Options.Flags.Rdb, cli.GzipSuffix)
-		gzip = true
-	}
+	backupFile := b.cfg.Options.Flags.Rdb
+	// create temporary, plain backup first, thus trim the extensions of compression and encryption
+	flags := *b.cfg.Options.Flags
+	flags.Rdb = dumpfile.PlainName(backupFile)
+	options := *b.cfg.Options
+	options.Flags = &flags
+
 	cmd := cli.CommandType{
 		Binary: binary,
-		Args:   cli.StructToCLI(b.cfg.Options),
+		Args:   cli.StructToCLI(&options),
 	}
 
 	out, err := cli.Run(ctx, cmd)
 	if err != nil {
+		_ = os.Remove(flags.Rdb)
 		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
 	}
 
-	// zip backup, update flag with the name returned by GzipFile for correct handover to restic
-	if gzip {
-		b.cfg.Options.Flags.Rdb, err = cli.GzipFile(b.cfg.Options.Flags.Rdb)
-		if err != nil {
-			return err
-		}
+	if dumpfile.IsPlain(backupFile) {
+		return nil
+	}
+
+	err = timeout.Run(ctx, timeout.StageCompress, func(ctx context.Context) error {
+		return dumpfile.WriteFile(ctx, flags.Rdb, backupFile)
+	})
+	if err != nil {
+		_ = os.Remove(flags.Rdb)
+		return err
 	}
 
 	return nil
 }
 
+// ValidateBackup checks the magic, the end marker and the checksum of the rdb file
+func (b *ConfigBasedBackend) ValidateBackup(ctx context.Context) error {
+	return validate.File(ctx, b.GetBackupPath(), validate.RedisRDB)
+}
+
 func (b *ConfigBasedBackend) GetBackupPath() string {
 	return b.cfg.Options.Flags.Rdb
 }
 
+func (b *ConfigBasedBackend) GetToolVersion(ctx context.Context) string {
+	return cli.ToolVersion(ctx, binary)
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.Options.Flags.Host
 }
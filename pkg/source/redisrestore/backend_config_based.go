@@ -0,0 +1,223 @@
+package redisrestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/compress"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/rdb"
+	"github.com/mittwald/brudi/pkg/validate"
+)
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		&Options{
+			Flags:          &Flags{},
+			AdditionalArgs: []string{},
+			Mode:           ModeReplay,
+			DBFilename:     "dump.rdb",
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
+	// corrupted dumps neither end up in the data directory nor partially in the server
+	err := validate.File(ctx, b.cfg.Options.SourceFile, validate.RedisRDB)
+	if err != nil {
+		return err
+	}
+
+	if b.cfg.Options.Mode == ModeFile {
+		return b.restoreFile(ctx)
+	}
+	return b.replay(ctx)
+}
+
+// restoreFile places the plain rdb file into the data directory, where it is loaded by the server on its next start
+func (b *ConfigBasedBackend) restoreFile(ctx context.Context) error {
+	target := filepath.Join(b.cfg.Options.DataDir, b.cfg.Options.DBFilename)
+
+	reader, err := dumpfile.NewReader(ctx, b.cfg.Options.SourceFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	err = compress.WriteFileAtomic(target, func(w io.Writer) error {
+		_, copyErr := io.Copy(w, reader)
+		return errors.WithStack(copyErr)
+	})
+	if err != nil {
+		return err
+	}
+
+	if b.cfg.Options.Owner != "" {
+		uid, gid, ownerErr := lookupOwner(b.cfg.Options.Owner)
+		if ownerErr != nil {
+			return ownerErr
+		}
+		if ownerErr = os.Chown(target, uid, gid); ownerErr != nil {
+			return errors.WithStack(ownerErr)
+		}
+	}
+
+	log.WithFields(
+		log.Fields{
+			"path":  target,
+			"owner": b.cfg.Options.Owner,
+		},
+	).Info("placed rdb file into data directory, it is loaded on the next start of redis")
+	return nil
+}
+
+// parse reads all keys of the rdb file without replaying them. The validation only checks the checksum of the file,
+// thus content which can't be replayed would otherwise be noticed after the server has been flushed.
+func (b *ConfigBasedBackend) parse(ctx context.Context) error {
+	reader, err := dumpfile.NewReader(ctx, b.cfg.Options.SourceFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	rdbReader, err := rdb.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	_, err = rdb.WriteRestoreCommands(io.Discard, rdbReader, rdb.RestoreOptions{Now: time.Now()})
+	return err
+}
+
+// replay loads the keys of the rdb file into the running server with "redis-cli --pipe", once the whole file has been
+// parsed successfully
+func (b *ConfigBasedBackend) replay(ctx context.Context) error {
+	if err := b.parse(ctx); err != nil {
+		return err
+	}
+
+	reader, err := dumpfile.NewReader(ctx, b.cfg.Options.SourceFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	rdbReader, err := rdb.NewReader(reader)
+	if err != nil {
+		return err
+	}
+
+	commands, commandWriter := io.Pipe()
+	var stats *rdb.RestoreStats
+	var writeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stats, writeErr = rdb.WriteRestoreCommands(commandWriter, rdbReader, rdb.RestoreOptions{
+			Flush:        b.cfg.Options.Flush,
+			SkipExisting: b.cfg.Options.SkipExisting,
+			Now:          time.Now(),
+		})
+		commandWriter.CloseWithError(writeErr)
+	}()
+
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   append(cli.StructToCLI(b.cfg.Options), "--pipe"),
+	}
+	out, err := cli.RunWithStdin(ctx, cmd, commands)
+	// unblock the writer if redis-cli exited early
+	_ = commands.Close()
+	<-done
+
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	log.WithFields(
+		log.Fields{
+			"keys":      stats.Keys,
+			"expired":   stats.Expired,
+			"functions": rdbReader.Functions,
+		},
+	).Info("replayed rdb file")
+	if rdbReader.Functions > 0 {
+		log.Warn("function libraries aren't replayed, load them with 'FUNCTION RESTORE' if needed")
+	}
+	return nil
+}
+
+// lookupOwner returns the ids of the given "user:group", the group defaults to the primary group of a user given by name
+func lookupOwner(owner string) (uid, gid int, err error) {
+	userName, groupName, _ := strings.Cut(owner, ":")
+	gid = -1
+
+	if uid, err = strconv.Atoi(userName); err != nil {
+		u, lookupErr := user.Lookup(userName)
+		if lookupErr != nil {
+			return 0, 0, errors.WithStack(lookupErr)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, errors.WithStack(err)
+		}
+		if groupName == "" {
+			gid, err = strconv.Atoi(u.Gid)
+			return uid, gid, errors.WithStack(err)
+		}
+	}
+
+	if groupName == "" {
+		return uid, gid, nil
+	}
+	if gid, err = strconv.Atoi(groupName); err != nil {
+		g, lookupErr := user.LookupGroup(groupName)
+		if lookupErr != nil {
+			return 0, 0, errors.WithStack(lookupErr)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return 0, 0, errors.WithStack(err)
+		}
+	}
+	return uid, gid, nil
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.SourceFile
+}
+
+// SetBackupPath sets the file to restore
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	b.cfg.Options.SourceFile = path
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.Options.Flags.Host
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
@@ -0,0 +1,46 @@
+package redisrestore
+
+const (
+	binary = "redis-cli"
+)
+
+const (
+	// ModeReplay loads the rdb file into a running server by replaying its keys with RESTORE
+	ModeReplay = "replay"
+	// ModeFile places the rdb file into the data directory of a stopped server
+	ModeFile = "file"
+)
+
+type Options struct {
+	Flags          *Flags
+	AdditionalArgs []string
+	// SourceFile is the rdb file to restore, which may be compressed and/or encrypted
+	SourceFile string `flag:"-" validate:"min=1"`
+	// Mode is either "replay" or "file"
+	Mode string `flag:"-" validate:"oneof=replay file"`
+	// Flush removes the keys of all databases before replaying
+	Flush bool `flag:"-"`
+	// SkipExisting keeps existing keys instead of replacing them while replaying
+	SkipExisting bool `flag:"-"`
+	// DataDir of the stopped server the rdb file is placed into
+	DataDir string `flag:"-"`
+	// DBFilename is the name of the rdb file in the data directory, as configured by "dbfilename" of the server
+	DBFilename string `flag:"-"`
+	// Owner of the rdb file as "user:group", either by name or by id
+	Owner string `flag:"-"`
+}
+
+type Flags struct {
+	Host          string `flag:"-h"`
+	Password      string `flag:"-a"`
+	Socket        string `flag:"-s"`
+	URI           string `flag:"-u"`
+	User          string `flag:"--user"`
+	Cacert        string `flag:"--cacert"`
+	Cert          string `flag:"--cert"`
+	Key           string `flag:"--key"`
+	Port          int    `flag:"-p"`
+	PipeTimeout   int    `flag:"--pipe-timeout"`
+	TLS           bool   `flag:"--tls"`
+	NoAuthWarning bool   `flag:"--no-auth-warning"`
+}
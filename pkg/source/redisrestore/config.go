@@ -0,0 +1,41 @@
+package redisrestore
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/config"
+)
+
+const (
+	Kind = "redisrestore"
+)
+
+type Config struct {
+	Options *Options
+}
+
+func (c *Config) InitFromViper() error {
+	err := config.InitializeStructFromViper(Kind, c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	switch c.Options.Mode {
+	case ModeReplay:
+		flags := c.Options.Flags
+		if flags.Host == "" && flags.Socket == "" && flags.URI == "" {
+			sl.ReportError(flags.Host, "host", "Host", "hostSocketOrURIRequiredForReplay", "")
+		}
+	case ModeFile:
+		if c.Options.DataDir == "" {
+			sl.ReportError(c.Options.DataDir, "dataDir", "DataDir", "dataDirRequiredForFile", "")
+		}
+	}
+}
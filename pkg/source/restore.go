@@ -3,16 +3,24 @@ package source
 import (
 	"context"
 	"fmt"
+	"os"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/mittwald/brudi/pkg/restic"
+	"github.com/mittwald/brudi/pkg/retry"
+	"github.com/mittwald/brudi/pkg/source/etcdrestore"
+	"github.com/mittwald/brudi/pkg/source/execrestore"
 	"github.com/mittwald/brudi/pkg/source/fsrestore"
 	"github.com/mittwald/brudi/pkg/source/mongorestore"
 	"github.com/mittwald/brudi/pkg/source/mysqlrestore"
 	"github.com/mittwald/brudi/pkg/source/pgrestore"
 	"github.com/mittwald/brudi/pkg/source/psql"
+	"github.com/mittwald/brudi/pkg/source/redisrestore"
+	"github.com/mittwald/brudi/pkg/source/sqliterestore"
 	"github.com/mittwald/brudi/pkg/source/tarrestore"
+	"github.com/mittwald/brudi/pkg/timeout"
 )
 
 func getGenericRestoreBackendForKind(kind string) (GenericRestore, error) {
@@ -29,12 +37,28 @@ func getGenericRestoreBackendForKind(kind string) (GenericRestore, error) {
 		return psql.NewConfigBasedBackend()
 	case fsrestore.Kind:
 		return fsrestore.NewConfigBasedBackend()
+	case redisrestore.Kind:
+		return redisrestore.NewConfigBasedBackend()
+	case sqliterestore.Kind:
+		return sqliterestore.NewConfigBasedBackend()
+	case etcdrestore.Kind:
+		return etcdrestore.NewConfigBasedBackend()
+	case execrestore.Kind:
+		return execrestore.NewConfigBasedBackend()
 	default:
 		return nil, fmt.Errorf("unsupported kind '%s'", kind)
 	}
 }
 
-func DoRestoreForKind(ctx context.Context, kind string, cleanup, useRestic bool) error {
+// DoRestoreForKind restores a backup of the given kind, restoring it from restic first if requested,
+// within the configured timeouts
+func DoRestoreForKind(ctx context.Context, kind string, cleanup, useRestic, force bool) error {
+	return timeout.RunWithConfig(ctx, func(ctx context.Context) error {
+		return doRestoreForKind(ctx, kind, cleanup, useRestic, force)
+	})
+}
+
+func doRestoreForKind(ctx context.Context, kind string, cleanup, useRestic, force bool) error {
 	logKind := log.WithFields(
 		log.Fields{
 			"kind": kind,
@@ -46,20 +70,36 @@ func DoRestoreForKind(ctx context.Context, kind string, cleanup, useRestic bool)
 		return err
 	}
 
+	var workspace string
 	if useRestic { // nolint: nestif
 		var resticClient *restic.Client
-		resticClient, err = restic.NewResticClient(logKind, backend.GetHostname(), backend.GetBackupPath())
+		resticClient, err = restic.NewResticClient(logKind, kind, backend.GetHostname(), backend.GetBackupPath())
 		if err != nil {
 			return err
 		}
 
-		err = resticClient.DoResticRestore(ctx, backend.GetBackupPath())
+		// backends restoring from restic themselves may use the workspace as well, thus it is removed in either case
+		workspace = resticClient.Config.Restore.Workspace
+		restorer, ok := backend.(ResticRestorer)
+		switch {
+		case ok && restorer.RestoresFromRestic():
+			err = restorer.RestoreFromRestic(ctx, resticClient)
+		case workspace != "":
+			err = restoreIntoWorkspace(ctx, logKind, kind, backend, resticClient, workspace)
+		default:
+			err = resticClient.DoResticRestore(ctx, backend.GetBackupPath())
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	err = backend.RestoreBackup(ctx)
+	err = verifyManifest(logKind, backend, force)
+	if err != nil {
+		return err
+	}
+
+	err = timeout.Run(ctx, retry.StageRestore, backend.RestoreBackup)
 	if err != nil {
 		return err
 	}
@@ -74,9 +114,20 @@ func DoRestoreForKind(ctx context.Context, kind string, cleanup, useRestic bool)
 			)
 			if cleanupErr := backend.CleanUp(); cleanupErr != nil {
 				cleanupLogger.WithError(cleanupErr).Warn("failed to cleanup backup")
+			} else if cleanupErr = removeManifest(backend.GetBackupPath()); cleanupErr != nil {
+				cleanupLogger.WithError(cleanupErr).Warn("failed to cleanup manifest")
 			} else {
 				cleanupLogger.Info("successfully cleaned up backup")
 			}
+
+			if workspace == "" {
+				return
+			}
+			if cleanupErr := os.RemoveAll(workspace); cleanupErr != nil {
+				cleanupLogger.WithError(cleanupErr).Warnf("failed to remove restore workspace %s", workspace)
+			} else {
+				cleanupLogger.WithField("workspace", workspace).Info("successfully removed restore workspace")
+			}
 		}()
 	}
 
@@ -84,3 +135,36 @@ func DoRestoreForKind(ctx context.Context, kind string, cleanup, useRestic bool)
 
 	return nil
 }
+
+// restoreIntoWorkspace restores the backup from restic into the workspace instead of its original path,
+// the backend reads it from there afterwards
+func restoreIntoWorkspace(
+	ctx context.Context, logKind *log.Entry, kind string, backend GenericRestore, resticClient *restic.Client, workspace string,
+) error {
+	setter, ok := backend.(BackupPathSetter)
+	if !ok {
+		return fmt.Errorf("kind '%s' doesn't support restoring into a workspace", kind)
+	}
+
+	restoredPath, err := restic.RestoredPath(workspace, backend.GetBackupPath())
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(workspace, 0o700); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the manifest is restored along with the backup, it is part of the same snapshot
+	if err = resticClient.DoResticRestoreInto(ctx, resticClient.Config.Restore.ID, workspace); err != nil {
+		return err
+	}
+
+	logKind.WithFields(
+		log.Fields{
+			"workspace": workspace,
+			"path":      restoredPath,
+		},
+	).Info("restored backup into workspace")
+	setter.SetBackupPath(restoredPath)
+	return nil
+}
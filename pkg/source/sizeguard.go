@@ -0,0 +1,94 @@
+package source
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/restic"
+	"github.com/mittwald/brudi/pkg/sizeguard"
+)
+
+// guardSize checks the size of the backup against the configured minimum and, if restic is used,
+// against the size of the previous snapshot. Depending on the configured action anomalies are logged or returned.
+func guardSize(ctx context.Context, logKind *log.Entry, backend Generic, resticClient *restic.Client) error {
+	cfg, err := sizeguard.NewConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	paths := backupPaths(backend)
+	var size uint64
+	for _, path := range paths {
+		pathSize, pathErr := sizeguard.PathSize(path)
+		if pathErr != nil {
+			return pathErr
+		}
+		size += pathSize
+	}
+	logGuard := logKind.WithFields(
+		log.Fields{
+			"path": backend.GetBackupPath(),
+			"size": size,
+		},
+	)
+
+	anomaly := cfg.CheckMinSize(size)
+	if anomaly == nil && cfg.MaxDeviation > 0 && resticClient != nil {
+		anomaly, err = checkDeviation(ctx, logGuard, cfg, resticClient, paths, size)
+		if err != nil {
+			return err
+		}
+	}
+
+	if anomaly == nil {
+		logGuard.Debug("backup size is within the configured limits")
+		return nil
+	}
+	if cfg.Action == sizeguard.ActionFail {
+		logGuard.WithError(anomaly).Error("backup size is anomalous, skipping restic")
+		return anomaly
+	}
+	logGuard.WithError(anomaly).Warn("backup size is anomalous")
+	return nil
+}
+
+// checkDeviation compares the size of the backup to the size of the same paths in the previous snapshot
+func checkDeviation(
+	ctx context.Context, logGuard *log.Entry, cfg *sizeguard.Config, resticClient *restic.Client, backupPaths []string, size uint64,
+) (anomaly, err error) {
+	var previousSize uint64
+	var snapshot *restic.Snapshot
+	for _, backupPath := range backupPaths {
+		// restic stores absolute paths
+		absPath, absErr := filepath.Abs(backupPath)
+		if absErr != nil {
+			return nil, errors.WithStack(absErr)
+		}
+
+		var pathSize uint64
+		pathSize, snapshot, err = resticClient.PreviousBackupSize(ctx, absPath)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot == nil {
+			logGuard.WithField("path", backupPath).Info("no previous snapshot to compare the backup size to")
+			return nil, nil
+		}
+		previousSize += pathSize
+	}
+
+	logGuard.WithFields(
+		log.Fields{
+			"snapshot":     snapshot.ShortID,
+			"previousSize": previousSize,
+			"deviation":    sizeguard.Deviation(size, previousSize),
+		},
+	).Debug("compared backup size to previous snapshot")
+	return cfg.CheckDeviation(size, previousSize), nil
+}
@@ -0,0 +1,175 @@
+package sqlitedump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/timeout"
+	"github.com/mittwald/brudi/pkg/validate"
+)
+
+// copySuffix is appended to the name of the backup file to get the name of the temporary copy
+const copySuffix = ".tmp"
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		Options: &Options{
+			AdditionalArgs: []string{},
+			Mode:           ModeBackup,
+			BusyTimeout:    5000,
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
+	if b.cfg.Options.Mode == ModeDump {
+		return b.createDump(ctx)
+	}
+
+	return b.createCopy(ctx)
+}
+
+// createDump streams the SQL export of ".dump" into the backup file
+func (b *ConfigBasedBackend) createDump(ctx context.Context) error {
+	cmd := b.command(true, ".dump")
+
+	var out []byte
+	err := dumpfile.Write(b.cfg.Options.File, func(w io.Writer) (runErr error) {
+		out, runErr = cli.RunWithStdout(ctx, cmd, w)
+		return runErr
+	})
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return nil
+}
+
+// createCopy creates a consistent copy of the live database, which is checked and compressed afterwards
+func (b *ConfigBasedBackend) createCopy(ctx context.Context) error {
+	backupFile := b.cfg.Options.File
+	// create temporary, plain copy first, which is moved or compressed into the backup file once it is complete
+	plainFile := backupFile + copySuffix
+	// "VACUUM INTO" refuses to overwrite existing files
+	if err := os.Remove(plainFile); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	var cmd cli.CommandType
+	if b.cfg.Options.Mode == ModeVacuum {
+		cmd = b.command(true, fmt.Sprintf("VACUUM INTO %s;", quoteLiteral(plainFile)))
+	} else {
+		cmd = b.command(false, ".backup "+quoteArg(plainFile))
+	}
+	out, err := cli.Run(ctx, cmd)
+	if err != nil {
+		_ = os.Remove(plainFile)
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	if err = checkIntegrity(ctx, plainFile); err != nil {
+		_ = os.Remove(plainFile)
+		return err
+	}
+
+	if dumpfile.IsPlain(backupFile) {
+		if err = os.Rename(plainFile, backupFile); err != nil {
+			_ = os.Remove(plainFile)
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+
+	err = timeout.Run(ctx, timeout.StageCompress, func(ctx context.Context) error {
+		return dumpfile.WriteFile(ctx, plainFile, backupFile)
+	})
+	if err != nil {
+		_ = os.Remove(plainFile)
+		return err
+	}
+
+	return nil
+}
+
+// command returns the sqlite3 command running the given dot-command or SQL statement on the database
+func (b *ConfigBasedBackend) command(readOnly bool, statement string) cli.CommandType {
+	args := []string{"-bail", "-cmd", fmt.Sprintf(".timeout %d", b.cfg.Options.BusyTimeout)}
+	if readOnly {
+		args = append(args, "-readonly")
+	}
+	args = append(args, b.cfg.Options.AdditionalArgs...)
+	args = append(args, b.cfg.Options.Database, statement)
+
+	return cli.CommandType{
+		Binary: binary,
+		Args:   args,
+	}
+}
+
+// checkIntegrity runs "PRAGMA quick_check" on the given database
+func checkIntegrity(ctx context.Context, database string) error {
+	cmd := cli.CommandType{
+		Binary: binary,
+		Args:   []string{"-bail", "-readonly", database, "PRAGMA quick_check;"},
+	}
+	out, err := cli.Run(ctx, cmd)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+	if result := strings.TrimSpace(string(out)); result != "ok" {
+		return errors.WithStack(fmt.Errorf("%w: integrity check of %s failed: %s", validate.ErrInvalidDump, database, result))
+	}
+	return nil
+}
+
+// ValidateBackup checks the trailer of SQL exports, copies are checked for integrity before they are compressed
+func (b *ConfigBasedBackend) ValidateBackup(ctx context.Context) error {
+	if b.cfg.Options.Mode != ModeDump {
+		return nil
+	}
+	return validate.File(ctx, b.GetBackupPath(), validate.SQLiteDump)
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.File
+}
+
+func (b *ConfigBasedBackend) GetToolVersion(ctx context.Context) string {
+	return cli.ToolVersion(ctx, binary)
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.HostName
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
+
+// quoteArg quotes an argument of a dot-command, the shell of sqlite3 resolves backslash escapes in double quotes
+func quoteArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// quoteLiteral quotes a SQL string literal
+func quoteLiteral(literal string) string {
+	return "'" + strings.ReplaceAll(literal, "'", "''") + "'"
+}
@@ -0,0 +1,26 @@
+package sqlitedump
+
+const (
+	binary = "sqlite3"
+)
+
+const (
+	// ModeBackup copies the database with the online backup API of SQLite
+	ModeBackup = "backup"
+	// ModeVacuum copies the database with "VACUUM INTO", which also defragments the copy
+	ModeVacuum = "vacuum"
+	// ModeDump exports the database as SQL text with ".dump"
+	ModeDump = "dump"
+)
+
+type Options struct {
+	AdditionalArgs []string
+	// Database is the path of the SQLite database to back up
+	Database string `validate:"min=1"`
+	// File the backup is written to, it is compressed and/or encrypted according to its suffixes, e.g. ".db.gz"
+	File string `validate:"min=1"`
+	// Mode is one of "backup", "vacuum" or "dump"
+	Mode string `validate:"oneof=backup vacuum dump"`
+	// BusyTimeout in milliseconds to wait for locks held by other connections
+	BusyTimeout int `validate:"min=0"`
+}
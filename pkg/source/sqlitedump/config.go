@@ -0,0 +1,44 @@
+package sqlitedump
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/config"
+)
+
+const (
+	Kind = "sqlitedump"
+)
+
+type Config struct {
+	Options  *Options
+	HostName string `validate:"min=1"`
+}
+
+func (c *Config) InitFromViper() error {
+	err := config.InitializeStructFromViper(Kind, c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if c.HostName == "" {
+		c.HostName, err = os.Hostname()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return config.Validate(c, configStructLevelValidation)
+}
+
+func configStructLevelValidation(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	if filepath.Clean(c.Options.Database) == filepath.Clean(c.Options.File) {
+		sl.ReportError(c.Options.File, "file", "File", "fileMustDifferFromDatabase", "")
+	}
+}
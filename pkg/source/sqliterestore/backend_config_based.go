@@ -0,0 +1,145 @@
+package sqliterestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+// databaseHeader starts every SQLite database file
+const databaseHeader = "SQLite format 3\x00"
+
+// importSuffix is appended to the name of the database to get the name of the temporary database SQL exports are imported into
+const importSuffix = ".import.tmp"
+
+type ConfigBasedBackend struct {
+	cfg *Config
+}
+
+func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
+	config := &Config{
+		Options: &Options{
+			AdditionalArgs: []string{},
+			BusyTimeout:    5000,
+		},
+	}
+
+	err := config.InitFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBasedBackend{cfg: config}, nil
+}
+
+// RestoreBackup replaces the database with the backup using the online backup API, thus other connections see either
+// the old or the restored database. SQL exports are imported into a temporary database first.
+func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
+	// sqlite3 restores and imports files only
+	fileName, removePlain, err := dumpfile.Open(ctx, b.cfg.Options.File)
+	if err != nil {
+		return err
+	}
+	defer removePlain()
+
+	isDatabase, err := isDatabaseFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	source := fileName
+	if !isDatabase {
+		source = b.cfg.Options.Database + importSuffix
+		defer func() {
+			if removeErr := os.Remove(source); removeErr != nil && !os.IsNotExist(removeErr) {
+				log.WithError(removeErr).Errorf("failed to remove temporary database %s", source)
+			}
+		}()
+
+		if err = b.importDump(ctx, fileName, source); err != nil {
+			return err
+		}
+	}
+
+	out, err := cli.Run(ctx, b.command(b.cfg.Options.Database, ".restore "+quoteArg(source)))
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+
+	return nil
+}
+
+// importDump imports the given SQL export into a new database
+func (b *ConfigBasedBackend) importDump(ctx context.Context, dumpFile, database string) error {
+	if err := os.Remove(database); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	out, err := cli.Run(ctx, b.command(database, ".read "+quoteArg(dumpFile)))
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+	}
+	return nil
+}
+
+// command returns the sqlite3 command running the given dot-command on the given database
+func (b *ConfigBasedBackend) command(database, statement string) cli.CommandType {
+	args := []string{"-bail", "-cmd", fmt.Sprintf(".timeout %d", b.cfg.Options.BusyTimeout)}
+	args = append(args, b.cfg.Options.AdditionalArgs...)
+	args = append(args, database, statement)
+
+	return cli.CommandType{
+		Binary: binary,
+		Args:   args,
+	}
+}
+
+// isDatabaseFile returns whether the given file is a SQLite database, otherwise it is treated as SQL export
+func isDatabaseFile(fileName string) (bool, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(databaseHeader))
+	_, err = io.ReadFull(file, header)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return bytes.Equal(header, []byte(databaseHeader)), nil
+}
+
+func (b *ConfigBasedBackend) GetBackupPath() string {
+	return b.cfg.Options.File
+}
+
+// SetBackupPath sets the file to restore
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	b.cfg.Options.File = path
+}
+
+func (b *ConfigBasedBackend) GetHostname() string {
+	return b.cfg.HostName
+}
+
+func (b *ConfigBasedBackend) CleanUp() error {
+	return os.Remove(b.GetBackupPath())
+}
+
+// quoteArg quotes an argument of a dot-command, the shell of sqlite3 resolves backslash escapes in double quotes
+func quoteArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
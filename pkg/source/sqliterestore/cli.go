@@ -0,0 +1,15 @@
+package sqliterestore
+
+const (
+	binary = "sqlite3"
+)
+
+type Options struct {
+	AdditionalArgs []string
+	// File is the backup to restore, either a copy of a database or a SQL export, which may be compressed and/or encrypted
+	File string `validate:"min=1"`
+	// Database is the path of the SQLite database to restore into, it is replaced as a whole
+	Database string `validate:"min=1"`
+	// BusyTimeout in milliseconds to wait for locks held by other connections
+	BusyTimeout int `validate:"min=0"`
+}
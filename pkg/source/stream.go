@@ -0,0 +1,39 @@
+package source
+
+import (
+	"context"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/retry"
+)
+
+// doStreamedBackup streams the backup to "restic backup --stdin", compressed and encrypted according to the suffixes
+// of the backup path, without writing it to disk. Thus there is nothing to validate, guard, or clean up locally.
+func doStreamedBackup(
+	ctx context.Context, logKind *log.Entry, kind string, backend Generic, streamer Streamer, retrier *retry.Retrier,
+	useResticForget, useResticPrune bool,
+) error {
+	backupPath := backend.GetBackupPath()
+
+	resticClient, err := newResticClient(logKind, kind, backend.GetHostname(), backupPath)
+	if err != nil {
+		return err
+	}
+	resticClient.Retrier = retrier
+
+	err = resticClient.DoResticBackupFromStdin(ctx, backupPath, func(w io.Writer) error {
+		return dumpfile.Stream(w, backupPath, func(w io.Writer) error {
+			return streamer.StreamBackup(ctx, w)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	logKind.WithField("path", backupPath).Info("finished streaming backup")
+
+	return forgetAndPrune(ctx, resticClient, useResticForget, useResticPrune)
+}
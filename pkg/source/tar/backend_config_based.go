@@ -2,24 +2,29 @@ package tar
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"os"
+	"time"
 
-	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
-	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/archive"
+	"github.com/mittwald/brudi/pkg/compress"
+	"github.com/mittwald/brudi/pkg/dumpfile"
 )
 
 type ConfigBasedBackend struct {
 	cfg *Config
+	// snapshot of the incremental chain the archive is added to
+	snapshot *archive.Snapshot
 }
 
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	config := &Config{
 		Options: &Options{
-			Flags:          &Flags{},
-			Paths:          []string{},
-			AdditionalArgs: []string{},
+			Flags:       &Flags{},
+			Paths:       []string{},
+			Incremental: &Incremental{},
 		},
 	}
 
@@ -28,23 +33,118 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 		return nil, err
 	}
 
-	return &ConfigBasedBackend{cfg: config}, nil
+	backend := &ConfigBasedBackend{cfg: config}
+	if config.Options.Incremental.Enabled {
+		if backend.snapshot, err = nextSnapshot(config.Options, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return backend, nil
 }
 
-func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
-	cmd := cli.CommandType{
-		Binary: binary,
-		Args:   cli.StructToCLI(b.cfg.Options),
+// nextSnapshot returns the snapshot of the chain the next archive is added to,
+// a new chain is started once the current one reached its limits
+func nextSnapshot(opts *Options, now time.Time) (*archive.Snapshot, error) {
+	incremental := opts.Incremental
+	if incremental.SnapshotFile == "" {
+		incremental.SnapshotFile = opts.Flags.File + snapshotSuffix
 	}
-	out, err := cli.Run(ctx, cmd)
+
+	snapshot, err := archive.ReadSnapshot(incremental.SnapshotFile)
 	if err != nil {
-		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+		return nil, err
+	}
+
+	previous := snapshot
+	switch {
+	case snapshot == nil:
+		log.WithField("snapshotFile", incremental.SnapshotFile).Info("no snapshot found, starting a new chain")
+	case incremental.MaxIncrementals > 0 && snapshot.Level >= incremental.MaxIncrementals:
+		log.WithField("chain", snapshot.Chain).Info("chain reached the maximum number of incrementals, starting a new chain")
+		snapshot = nil
+	case incremental.MaxDays > 0 && now.Sub(snapshot.Started) >= time.Duration(incremental.MaxDays)*24*time.Hour:
+		log.WithField("chain", snapshot.Chain).Info("chain reached the maximum age, starting a new chain")
+		snapshot = nil
 	}
+	if snapshot == nil {
+		// chains are named after the second they were started, the new one must not replace the previous one
+		if previous != nil && !now.Truncate(time.Second).After(previous.Started) {
+			now = previous.Started.Add(time.Second)
+		}
+		snapshot = archive.NewChain(now)
+	}
+
+	snapshot.Level++
+	return snapshot, nil
+}
 
+// CreateBackup archives the configured paths, the archive is compressed and/or encrypted while it is written
+func (b *ConfigBasedBackend) CreateBackup(ctx context.Context) error {
+	flags := b.cfg.Options.Flags
+	opts := &archive.CreateOptions{
+		Dir:     flags.Target,
+		Exclude: flags.Exclude,
+	}
+	if b.snapshot != nil {
+		if b.snapshot.Level > 0 {
+			opts.Previous = b.snapshot.Files
+		}
+		opts.State = map[string]archive.FileState{}
+	}
+
+	err := b.createArchive(ctx, opts)
+	if err != nil || b.snapshot == nil {
+		return err
+	}
+
+	// the snapshot file is written by FinishBackup, thus a run failing later on doesn't advance the chain
+	b.snapshot.Files = opts.State
+	log.WithFields(log.Fields{
+		"chain": b.snapshot.Chain,
+		"level": b.snapshot.Level,
+		"file":  b.GetBackupPath(),
+	}).Info("created incremental archive")
 	return nil
 }
 
+// Chained returns whether incremental archives are created
+func (b *ConfigBasedBackend) Chained() bool {
+	return b.snapshot != nil
+}
+
+// FinishBackup writes the snapshot of the created archive, the next archive of the chain builds on it
+func (b *ConfigBasedBackend) FinishBackup() error {
+	return b.snapshot.Write(b.cfg.Options.Incremental.SnapshotFile)
+}
+
+func (b *ConfigBasedBackend) createArchive(ctx context.Context, opts *archive.CreateOptions) error {
+	flags := b.cfg.Options.Flags
+	return dumpfile.Write(b.GetBackupPath(), func(w io.Writer) error {
+		if !flags.Gzip || dumpfile.Compression(flags.File) != compress.None {
+			return archive.Create(ctx, w, b.cfg.Options.Paths, opts)
+		}
+
+		compressConfig, err := compress.NewConfig(compress.Gzip)
+		if err != nil {
+			return err
+		}
+		gzipWriter, err := compress.NewGzipWriter(w, "", compressConfig)
+		if err != nil {
+			return err
+		}
+		if err = archive.Create(ctx, gzipWriter, b.cfg.Options.Paths, opts); err != nil {
+			_ = gzipWriter.Close()
+			return err
+		}
+		return gzipWriter.Close()
+	})
+}
+
+// GetBackupPath returns the archive file, incremental archives have their chain and level in their name
 func (b *ConfigBasedBackend) GetBackupPath() string {
+	if b.snapshot != nil {
+		return archive.IncrementalName(b.cfg.Options.Flags.File, b.snapshot.Chain, b.snapshot.Level)
+	}
 	return b.cfg.Options.Flags.File
 }
 
@@ -1,24 +1,30 @@
 package tar
 
-const (
-	binary = "tar"
-)
-
 type Options struct {
-	Flags          *Flags
-	AdditionalArgs []string
-	Paths          []string
+	Flags *Flags
+	// Paths to archive, relative paths are resolved against Target
+	Paths       []string `validate:"min=1"`
+	Incremental *Incremental
 }
 
 type Flags struct {
-	Target          string   `flag:"-C"`
-	File            string   `flag:"-f"                  validate:"min=1"`
-	Warning         []string `flag:"--warning"`
-	Exclude         []string `flag:"--exclude"`
-	StripComponents int      `flag:"--strip-components="`
-	Create          bool     `flag:"-c"`
-	Gzip            bool     `flag:"-z"`
-	Extract         bool     `flag:"-x"`
-	Overwrite       bool     `flag:"--overwrite"`
-	NoOverwriteDir  bool     `flag:"--no-overwrite-dir"`
+	// Target is the directory relative paths are resolved against, like "tar -C"
+	Target string
+	// File the archive is written to, it is compressed and/or encrypted according to its suffixes
+	File    string `validate:"min=1"`
+	Exclude []string
+	// Gzip compresses archives whose name has no compression suffix with gzip, like "tar -z"
+	Gzip bool
+}
+
+// Incremental archives are written in chains of a full archive followed by archives of the changes since the
+// previous one, like "tar --listed-incremental"
+type Incremental struct {
+	Enabled bool
+	// SnapshotFile holds the state of the chain after its latest archive, File with ".snar" appended if empty
+	SnapshotFile string
+	// MaxIncrementals starts a new chain after the given number of incremental archives, unlimited if 0
+	MaxIncrementals int `validate:"min=0"`
+	// MaxDays starts a new chain once the current one is older than the given number of days, unlimited if 0
+	MaxDays int `validate:"min=0"`
 }
@@ -1,15 +1,19 @@
 package tar
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/mittwald/brudi/pkg/config"
 )
 
 const (
 	Kind = "tar"
+	// snapshotSuffix is appended to the archive file to name the snapshot file of incremental chains
+	snapshotSuffix = ".snar"
 )
 
 type Config struct {
@@ -22,6 +26,9 @@ func (c *Config) InitFromViper() error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if err = checkRemovedOptions(); err != nil {
+		return err
+	}
 
 	if c.HostName == "" {
 		c.HostName, err = os.Hostname()
@@ -32,3 +39,48 @@ func (c *Config) InitFromViper() error {
 
 	return config.Validate(c)
 }
+
+// removedOptions were passed to the tar binary, which isn't used anymore since archives are written natively
+type removedOptions struct {
+	AdditionalArgs []string
+	Flags          struct {
+		Create          bool
+		Extract         bool
+		Overwrite       bool
+		NoOverwriteDir  bool
+		Warning         []string
+		StripComponents int
+	}
+}
+
+// checkRemovedOptions fails for options of the tar binary which can't be mapped onto the native archiver,
+// thus configs written for it aren't silently misinterpreted
+func checkRemovedOptions() error {
+	var removed removedOptions
+	if err := config.InitializeStructFromViper(Kind+".options", &removed); err != nil {
+		return errors.WithStack(err)
+	}
+
+	flags := removed.Flags
+	if flags.Create {
+		log.WithField("key", Kind+".options.flags.create").Warn("ignoring removed option, archives are always created")
+	}
+	for _, option := range []struct {
+		key string
+		set bool
+	}{
+		{key: "additionalArgs", set: len(removed.AdditionalArgs) > 0},
+		{key: "flags.extract", set: flags.Extract},
+		{key: "flags.overwrite", set: flags.Overwrite},
+		{key: "flags.noOverwriteDir", set: flags.NoOverwriteDir},
+		{key: "flags.warning", set: len(flags.Warning) > 0},
+		{key: "flags.stripComponents", set: flags.StripComponents != 0},
+	} {
+		if option.set {
+			return errors.WithStack(fmt.Errorf(
+				"%s.options.%s has been removed, archives are written natively without the tar binary", Kind, option.key,
+			))
+		}
+	}
+	return nil
+}
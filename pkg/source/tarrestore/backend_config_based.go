@@ -7,7 +7,11 @@ import (
 
 	"github.com/pkg/errors"
 
-	"github.com/mittwald/brudi/pkg/cli"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/archive"
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/restic"
 )
 
 type ConfigBasedBackend struct {
@@ -17,9 +21,11 @@ type ConfigBasedBackend struct {
 func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	config := &Config{
 		Options: &Options{
-			Flags:          &Flags{},
-			Paths:          []string{},
-			AdditionalArgs: []string{},
+			Flags: &Flags{},
+			Paths: []string{},
+			Incremental: &Incremental{
+				Level: -1,
+			},
 		},
 	}
 
@@ -31,27 +37,114 @@ func NewConfigBasedBackend() (*ConfigBasedBackend, error) {
 	return &ConfigBasedBackend{cfg: config}, nil
 }
 
+// RestoreBackup extracts the archive into the target directory, it is decrypted and uncompressed on the fly.
+// Incremental chains are extracted in order, members deleted between their archives are removed again.
 func (b *ConfigBasedBackend) RestoreBackup(ctx context.Context) error {
-	cmd := cli.CommandType{
-		Binary: binary,
-		Args:   cli.StructToCLI(b.cfg.Options),
+	if !b.cfg.Options.Incremental.Enabled {
+		return b.extract(ctx, b.cfg.Options.Flags.File)
 	}
-	out, err := cli.Run(ctx, cmd)
+
+	archives, err := b.chain()
 	if err != nil {
-		return errors.WithStack(fmt.Errorf("%+v - %s", err, out))
+		return err
+	}
+	for _, incremental := range archives {
+		log.WithFields(log.Fields{
+			"chain": incremental.Chain,
+			"level": incremental.Level,
+			"file":  incremental.Path,
+		}).Info("extracting incremental archive")
+		if err = b.extract(ctx, incremental.Path); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
+// chain returns the archives of the configured chain up to the configured level
+func (b *ConfigBasedBackend) chain() ([]archive.IncrementalArchive, error) {
+	file := b.cfg.Options.Flags.File
+	incremental := b.cfg.Options.Incremental
+
+	archives, err := archive.IncrementalArchives(file)
+	if err != nil {
+		return nil, err
+	}
+	chain := incremental.Chain
+	if chain == "" && len(archives) > 0 {
+		chain = archives[len(archives)-1].Chain
+	}
+
+	var selected []archive.IncrementalArchive
+	for _, candidate := range archives {
+		if candidate.Chain != chain || (incremental.Level >= 0 && candidate.Level > incremental.Level) {
+			continue
+		}
+		// every archive only holds the changes since the previous one, thus none must be missing
+		if candidate.Level != len(selected) {
+			return nil, errors.WithStack(fmt.Errorf("archive of level %d of chain %s of %s is missing", len(selected), chain, file))
+		}
+		selected = append(selected, candidate)
+	}
+
+	switch {
+	case len(selected) == 0:
+		return nil, errors.WithStack(fmt.Errorf("no incremental archives of chain %q of %s found", chain, file))
+	case incremental.Level >= 0 && selected[len(selected)-1].Level != incremental.Level:
+		return nil, errors.WithStack(fmt.Errorf("archive of level %d of chain %s of %s is missing", incremental.Level, chain, file))
+	}
+	return selected, nil
+}
+
+// extract extracts a single archive into the target directory
+func (b *ConfigBasedBackend) extract(ctx context.Context, file string) error {
+	reader, err := dumpfile.NewReader(ctx, file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.WithError(closeErr).Errorf("failed to close archive %s", file)
+		}
+	}()
+
+	flags := b.cfg.Options.Flags
+	return archive.Extract(ctx, reader, &archive.ExtractOptions{
+		Target:          flags.Target,
+		StripComponents: flags.StripComponents,
+		Exclude:         flags.Exclude,
+		Members:         b.cfg.Options.Paths,
+		KeepOldFiles:    flags.KeepOldFiles,
+	})
+}
+
 func (b *ConfigBasedBackend) GetBackupPath() string {
 	return b.cfg.Options.Flags.File
 }
 
+// SetBackupPath sets the archive to extract, or the file of the incremental chain
+func (b *ConfigBasedBackend) SetBackupPath(path string) {
+	b.cfg.Options.Flags.File = path
+}
+
 func (b *ConfigBasedBackend) GetHostname() string {
 	return b.cfg.HostName
 }
 
+// RestoresFromRestic returns whether an incremental chain is restored, which is never stored in restic
+func (b *ConfigBasedBackend) RestoresFromRestic() bool {
+	return b.cfg.Options.Incremental.Enabled
+}
+
+// RestoreFromRestic fails, since incremental chains are kept on disk instead of being backed up with restic
+func (b *ConfigBasedBackend) RestoreFromRestic(_ context.Context, _ *restic.Client) error {
+	return errors.WithStack(fmt.Errorf("incremental chains of %s aren't stored in restic, restore them without --restic", b.GetBackupPath()))
+}
+
+// CleanUp removes the restored archive, incremental chains are kept since they aren't restored from restic
 func (b *ConfigBasedBackend) CleanUp() error {
+	if b.cfg.Options.Incremental.Enabled {
+		return nil
+	}
 	return os.Remove(b.GetBackupPath())
 }
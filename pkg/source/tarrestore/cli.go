@@ -1,24 +1,28 @@
 package tarrestore
 
-const (
-	binary = "tar"
-)
-
 type Options struct {
-	Flags          *Flags
-	AdditionalArgs []string
-	Paths          []string
+	Flags *Flags
+	// Paths are the members to extract including everything below them, all if empty
+	Paths       []string
+	Incremental *Incremental
 }
 
 type Flags struct {
-	Target          string   `flag:"-C"`
-	File            string   `flag:"-f"                  validate:"min=1"`
-	Warning         []string `flag:"--warning"`
-	Exclude         []string `flag:"--exclude"`
-	StripComponents int      `flag:"--strip-components="`
-	Create          bool     `flag:"-c"`
-	Gzip            bool     `flag:"-z"`
-	Extract         bool     `flag:"-x"`
-	Overwrite       bool     `flag:"--overwrite"`
-	NoOverwriteDir  bool     `flag:"--no-overwrite-dir"`
+	// Target is the directory the archive is extracted into, like "tar -C"
+	Target string
+	// File is the archive to extract, compression and encryption are detected by its content
+	File            string `validate:"min=1"`
+	Exclude         []string
+	StripComponents int `validate:"min=0"`
+	// KeepOldFiles skips members which already exist instead of replacing them
+	KeepOldFiles bool
+}
+
+// Incremental restores a chain of incremental archives written by the tar kind
+type Incremental struct {
+	Enabled bool
+	// Chain to restore, which is the time it was started like "20261019T080000Z", the latest one if empty
+	Chain string
+	// Level of the last archive of the chain to restore, all archives if -1
+	Level int `validate:"min=-1"`
 }
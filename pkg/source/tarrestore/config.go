@@ -1,9 +1,11 @@
 package tarrestore
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/mittwald/brudi/pkg/config"
 )
@@ -22,6 +24,9 @@ func (c *Config) InitFromViper() error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if err = c.mapRemovedOptions(); err != nil {
+		return err
+	}
 
 	if c.HostName == "" {
 		c.HostName, err = os.Hostname()
@@ -32,3 +37,56 @@ func (c *Config) InitFromViper() error {
 
 	return config.Validate(c)
 }
+
+// removedOptions were passed to the tar binary, which isn't used anymore since archives are extracted natively
+type removedOptions struct {
+	AdditionalArgs []string
+	Flags          struct {
+		Create         bool
+		Extract        bool
+		Gzip           bool
+		Overwrite      bool
+		NoOverwriteDir bool
+		Warning        []string
+	}
+}
+
+// mapRemovedOptions maps options of the tar binary onto the native extraction and fails for the ones which can't
+// be mapped, thus configs written for it aren't silently misinterpreted
+func (c *Config) mapRemovedOptions() error {
+	var removed removedOptions
+	if err := config.InitializeStructFromViper(Kind+".options", &removed); err != nil {
+		return errors.WithStack(err)
+	}
+
+	flags := removed.Flags
+	if flags.Extract {
+		log.WithField("key", Kind+".options.flags.extract").Warn("ignoring removed option, archives are always extracted")
+	}
+	if flags.Gzip {
+		log.WithField("key", Kind+".options.flags.gzip").Warn("ignoring removed option, compression is detected by the content")
+	}
+	// the native extraction replaces existing files unless keepOldFiles is set, like "tar --overwrite"
+	if flags.Overwrite && c.Options.Flags.KeepOldFiles {
+		return errors.WithStack(fmt.Errorf(
+			"%[1]s.options.flags.overwrite has been removed and contradicts %[1]s.options.flags.keepOldFiles", Kind,
+		))
+	}
+
+	for _, option := range []struct {
+		key string
+		set bool
+	}{
+		{key: "additionalArgs", set: len(removed.AdditionalArgs) > 0},
+		{key: "flags.create", set: flags.Create},
+		{key: "flags.noOverwriteDir", set: flags.NoOverwriteDir},
+		{key: "flags.warning", set: len(flags.Warning) > 0},
+	} {
+		if option.set {
+			return errors.WithStack(fmt.Errorf(
+				"%s.options.%s has been removed, archives are extracted natively without the tar binary", Kind, option.key,
+			))
+		}
+	}
+	return nil
+}
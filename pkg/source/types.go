@@ -2,6 +2,9 @@ package source
 
 import (
 	"context"
+	"io"
+
+	"github.com/mittwald/brudi/pkg/restic"
 )
 
 type Generic interface {
@@ -11,6 +14,60 @@ type Generic interface {
 	CleanUp() error
 }
 
+// ToolVersioner is implemented by backends which can report the version of the tool creating the backup
+type ToolVersioner interface {
+	GetToolVersion(ctx context.Context) string
+}
+
+// Validator is implemented by backends which can check a created backup for completeness
+type Validator interface {
+	ValidateBackup(ctx context.Context) error
+}
+
+// Streamer is implemented by backends which can stream the backup to restic instead of writing it to disk
+type Streamer interface {
+	// Streaming returns whether the backup is streamed to restic, if restic is used
+	Streaming() bool
+	// StreamBackup writes the plain backup into w
+	StreamBackup(ctx context.Context, w io.Writer) error
+}
+
+// Chainer is implemented by backends whose backups build on the previous one, like incremental archives.
+// The state of the chain is only advanced once the backup has been handed off, and chained backups can't be shipped
+// with restic or removed by cleanup, since either would leave a gap in the chain.
+type Chainer interface {
+	// Chained returns whether the backup builds on the previous one
+	Chained() bool
+	// FinishBackup advances the state of the chain to the created backup
+	FinishBackup() error
+}
+
+// MultiPather is implemented by backends whose backup consists of several paths,
+// GetBackupPath returns the first of them
+type MultiPather interface {
+	GetBackupPaths() []string
+}
+
+// ResticBackupConfigurer is implemented by backends which pass their own options to "restic backup"
+type ResticBackupConfigurer interface {
+	ConfigureResticBackup(flags *restic.BackupFlags)
+}
+
+// ResticRestorer is implemented by backends which restore their backup from restic themselves,
+// e.g. into a staging directory instead of the configured restic target
+type ResticRestorer interface {
+	// RestoresFromRestic returns whether the backend restores from restic itself
+	RestoresFromRestic() bool
+	// RestoreFromRestic restores the backup from restic before RestoreBackup is called
+	RestoreFromRestic(ctx context.Context, client *restic.Client) error
+}
+
+// BackupPathSetter is implemented by restore backends which can read their backup from another path,
+// e.g. from the restore workspace restic restored it into
+type BackupPathSetter interface {
+	SetBackupPath(path string)
+}
+
 type GenericRestore interface {
 	RestoreBackup(ctx context.Context) error
 	GetBackupPath() string
@@ -0,0 +1,81 @@
+package timeout
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/config"
+	"github.com/mittwald/brudi/pkg/retry"
+)
+
+const (
+	Kind = "timeout"
+
+	// StageRun is the whole run of a command, all other stages have to finish within its deadline
+	StageRun = "run"
+	// StageCompress is the compression of a dump written to a file first
+	StageCompress = "compress"
+
+	defaultStageTimeout = 6 * time.Hour
+)
+
+// Config contains the timeout of each stage, a timeout of 0 disables it
+type Config struct {
+	Run      time.Duration `validate:"min=0"`
+	Dump     time.Duration `validate:"min=0"`
+	Compress time.Duration `validate:"min=0"`
+	Init     time.Duration `validate:"min=0"`
+	Backup   time.Duration `validate:"min=0"`
+	Forget   time.Duration `validate:"min=0"`
+	Prune    time.Duration `validate:"min=0"`
+	Restore  time.Duration `validate:"min=0"`
+}
+
+// NewConfig returns the "timeout" config, every stage but the run itself defaults to six hours
+func NewConfig() (*Config, error) {
+	cfg := defaultConfig()
+
+	err := config.InitializeStructFromViper(Kind, cfg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return cfg, config.Validate(cfg)
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Dump:     defaultStageTimeout,
+		Compress: defaultStageTimeout,
+		Init:     defaultStageTimeout,
+		Backup:   defaultStageTimeout,
+		Forget:   defaultStageTimeout,
+		Prune:    defaultStageTimeout,
+		Restore:  defaultStageTimeout,
+	}
+}
+
+// For returns the timeout of the given stage
+func (c *Config) For(stage string) time.Duration {
+	switch stage {
+	case StageRun:
+		return c.Run
+	case StageCompress:
+		return c.Compress
+	case retry.StageDump:
+		return c.Dump
+	case retry.StageInit:
+		return c.Init
+	case retry.StageBackup:
+		return c.Backup
+	case retry.StageForget:
+		return c.Forget
+	case retry.StagePrune:
+		return c.Prune
+	case retry.StageRestore:
+		return c.Restore
+	default:
+		return 0
+	}
+}
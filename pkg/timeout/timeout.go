@@ -0,0 +1,81 @@
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrTimeout = fmt.Errorf("timeout exceeded")
+
+type configKey struct{}
+
+// Error is returned if a stage exceeded its timeout
+type Error struct {
+	Stage   string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("stage '%s' exceeded its timeout of %s: %s", e.Stage, e.Timeout, e.Err)
+}
+
+// Unwrap makes ErrTimeout as well as the error returned by the stage accessible by errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	return []error{ErrTimeout, e.Err}
+}
+
+// NewContext returns a context carrying the given config, which is used by Run for all stages
+func NewContext(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// FromContext returns the config of the given context, or the default config if there is none
+func FromContext(ctx context.Context) *Config {
+	if cfg, ok := ctx.Value(configKey{}).(*Config); ok {
+		return cfg
+	}
+	return defaultConfig()
+}
+
+// Run executes fn with a context limited by the timeout of the given stage. If the stage exceeds its timeout,
+// an *Error naming the stage is returned. Errors of stages running within fn are passed through unchanged.
+func Run(ctx context.Context, stage string, fn func(ctx context.Context) error) error {
+	timeout := FromContext(ctx).For(stage)
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	stageCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(stageCtx)
+	if err == nil {
+		return nil
+	}
+
+	var timeoutErr *Error
+	if errors.As(err, &timeoutErr) || ctx.Err() != nil || !errors.Is(stageCtx.Err(), context.DeadlineExceeded) {
+		// an inner stage timed out or the deadline of an outer stage is exceeded, which reports it on its own
+		return err
+	}
+
+	return &Error{
+		Stage:   stage,
+		Timeout: timeout,
+		Err:     err,
+	}
+}
+
+// RunWithConfig loads the "timeout" config and executes fn as StageRun, passing the config to all stages within
+func RunWithConfig(ctx context.Context, fn func(ctx context.Context) error) error {
+	cfg, err := NewConfig()
+	if err != nil {
+		return err
+	}
+
+	return Run(NewContext(ctx, cfg), StageRun, fn)
+}
@@ -0,0 +1,77 @@
+package validate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// mongoArchiveMagic starts every archive of mongodump
+	mongoArchiveMagic = 0x8199e26d
+	// bsonMinSize is the size of an empty BSON document: the int32 length followed by the terminating null byte
+	bsonMinSize = 5
+	// bsonMaxSize is the max size of BSON documents accepted by MongoDB, including some headroom
+	bsonMaxSize = 48 * 1024 * 1024
+)
+
+// MongoArchive checks the magic and the header of the given archive of mongodump
+func MongoArchive(r io.Reader) error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return errors.WithStack(fmt.Errorf("%w: archive is too short: %s", ErrInvalidDump, err))
+	}
+	if binary.LittleEndian.Uint32(magic) != mongoArchiveMagic {
+		return errors.WithStack(fmt.Errorf("%w: archive doesn't start with the magic of mongodump", ErrInvalidDump))
+	}
+
+	// the magic is followed by the header document
+	if _, err := readBSONDocument(r); err != nil {
+		return errors.WithStack(fmt.Errorf("%w: invalid archive header: %s", ErrInvalidDump, err))
+	}
+	return nil
+}
+
+// BSON checks that the given BSON file of mongodump consists of complete documents only
+func BSON(r io.Reader) error {
+	for count := 0; ; count++ {
+		_, err := readBSONDocument(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.WithStack(fmt.Errorf("%w: document %d: %s", ErrInvalidDump, count, err))
+		}
+	}
+}
+
+// readBSONDocument reads the next document and returns its size. io.EOF is only returned if there is no further document.
+func readBSONDocument(r io.Reader) (int64, error) {
+	sizeBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r, sizeBytes); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, fmt.Errorf("truncated document size")
+		}
+		return 0, err
+	}
+
+	size := int64(int32(binary.LittleEndian.Uint32(sizeBytes))) //nolint: gosec // negative sizes are rejected below
+	if size < bsonMinSize || size > bsonMaxSize {
+		return 0, fmt.Errorf("invalid document size %d", size)
+	}
+
+	// skip the body except the terminating null byte
+	if _, err := io.CopyN(io.Discard, r, size-bsonMinSize); err != nil {
+		return 0, fmt.Errorf("truncated document of size %d", size)
+	}
+	terminator := make([]byte, 1)
+	if _, err := io.ReadFull(r, terminator); err != nil {
+		return 0, fmt.Errorf("truncated document of size %d", size)
+	}
+	if terminator[0] != 0 {
+		return 0, fmt.Errorf("document of size %d isn't null terminated", size)
+	}
+	return size, nil
+}
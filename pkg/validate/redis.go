@@ -0,0 +1,67 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/mittwald/brudi/pkg/rdb"
+)
+
+// RedisRDB checks the magic, the EOF opcode and, if enabled, the CRC64 checksum of the given RDB file
+func RedisRDB(r io.Reader) error {
+	header := make([]byte, rdb.HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return errors.WithStack(fmt.Errorf("%w: rdb file is too short: %s", ErrInvalidDump, err))
+	}
+	if !bytes.HasPrefix(header, []byte(rdb.Magic)) {
+		return errors.WithStack(fmt.Errorf("%w: rdb file doesn't start with '%s'", ErrInvalidDump, rdb.Magic))
+	}
+	version, err := strconv.Atoi(string(header[len(rdb.Magic):]))
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%w: invalid rdb version '%s'", ErrInvalidDump, header[len(rdb.Magic):]))
+	}
+
+	trailerSize := 1
+	if version >= rdb.ChecksumVersion {
+		trailerSize += rdb.ChecksumSize
+	}
+
+	// the checksum covers everything but itself, thus the trailer is held back until the end is reached
+	checksum := rdb.CRC64(0, header)
+	pending := make([]byte, 0, 64*1024)
+	chunk := make([]byte, 32*1024)
+	for {
+		read, readErr := r.Read(chunk)
+		pending = append(pending, chunk[:read]...)
+		if len(pending) > trailerSize {
+			checksum = rdb.CRC64(checksum, pending[:len(pending)-trailerSize])
+			pending = append(pending[:0], pending[len(pending)-trailerSize:]...)
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return errors.WithStack(readErr)
+		}
+	}
+
+	if len(pending) < trailerSize || pending[0] != rdb.OpcodeEOF {
+		return errors.WithStack(fmt.Errorf("%w: rdb file has no EOF marker, the dump is probably incomplete", ErrInvalidDump))
+	}
+	if version < rdb.ChecksumVersion {
+		return nil
+	}
+
+	checksum = rdb.CRC64(checksum, pending[:1])
+	expected := binary.LittleEndian.Uint64(pending[1:])
+	// a checksum of zero means the checksum is disabled by "rdbchecksum no"
+	if expected != 0 && expected != checksum {
+		return errors.WithStack(fmt.Errorf("%w: rdb checksum mismatch, got %x, expected %x", ErrInvalidDump, checksum, expected))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package validate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+// ErrInvalidDump is wrapped by all errors about dumps which are incomplete or corrupted
+var ErrInvalidDump = fmt.Errorf("invalid dump")
+
+const (
+	// MySQLTrailer is written by mysqldump and mariadb-dump at the end of complete dumps, unless comments are skipped
+	MySQLTrailer = "-- Dump completed"
+	// PostgresTrailer is written by pg_dump at the end of complete plain-text dumps
+	PostgresTrailer = "-- PostgreSQL database dump complete"
+	// PostgresClusterTrailer is written by pg_dumpall at the end of complete dumps, including the ones of the globals
+	PostgresClusterTrailer = "-- PostgreSQL database cluster dump complete"
+	// SQLiteTrailer ends the transaction wrapping the SQL export of ".dump"
+	SQLiteTrailer = "COMMIT;"
+)
+
+// trailerWindow is the number of bytes at the end of a dump which are searched for its trailer
+const trailerWindow = 4096
+
+// MySQLDump checks that the given plain-text dump of mysqldump is complete
+func MySQLDump(r io.Reader) error {
+	return trailer(r, MySQLTrailer)
+}
+
+// PostgresPlainDump checks that the given plain-text dump of pg_dump is complete
+func PostgresPlainDump(r io.Reader) error {
+	return trailer(r, PostgresTrailer)
+}
+
+// PostgresClusterDump checks that the given dump of pg_dumpall is complete
+func PostgresClusterDump(r io.Reader) error {
+	return trailer(r, PostgresClusterTrailer)
+}
+
+// SQLiteDump checks that the given SQL export of the ".dump" command of sqlite3 is complete
+func SQLiteDump(r io.Reader) error {
+	return trailer(r, SQLiteTrailer)
+}
+
+// trailer checks that the given text occurs at the end of the reader
+func trailer(r io.Reader, text string) error {
+	end, err := tail(r, trailerWindow)
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(end, []byte(text)) {
+		return errors.WithStack(fmt.Errorf("%w: trailer '%s' is missing, the dump is probably incomplete", ErrInvalidDump, text))
+	}
+	return nil
+}
+
+// tail returns the last n bytes of the reader
+func tail(r io.Reader, n int) ([]byte, error) {
+	buf := make([]byte, 0, 2*n)
+	chunk := make([]byte, 32*1024)
+	for {
+		read, err := r.Read(chunk)
+		buf = append(buf, chunk[:read]...)
+		if len(buf) > n {
+			buf = append(buf[:0], buf[len(buf)-n:]...)
+		}
+		if errors.Is(err, io.EOF) {
+			return buf, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+}
+
+// File runs the given check on the plain content of the given dump, which is decrypted and uncompressed on the fly
+func File(ctx context.Context, fileName string, check func(r io.Reader) error) error {
+	reader, err := dumpfile.NewReader(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			log.WithError(closeErr).Errorf("failed to close dump %s", fileName)
+		}
+	}()
+
+	err = check(reader)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("validation of %s failed: %w", fileName, err))
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package testarchive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/sys/unix"
+
+	"github.com/mittwald/brudi/pkg/archive"
+)
+
+type ArchiveTestSuite struct {
+	suite.Suite
+	dir    string
+	source string
+	target string
+}
+
+func (archiveTestSuite *ArchiveTestSuite) SetupTest() {
+	archiveTestSuite.dir = archiveTestSuite.T().TempDir()
+	archiveTestSuite.source = filepath.Join(archiveTestSuite.dir, "source")
+	archiveTestSuite.target = filepath.Join(archiveTestSuite.dir, "target")
+	archiveTestSuite.Require().NoError(os.MkdirAll(filepath.Join(archiveTestSuite.source, "data", "cache"), 0o750))
+	archiveTestSuite.Require().NoError(os.MkdirAll(archiveTestSuite.target, 0o755))
+}
+
+func (archiveTestSuite *ArchiveTestSuite) writeFile(name, content string) string {
+	fsPath := filepath.Join(archiveTestSuite.source, name)
+	archiveTestSuite.Require().NoError(os.WriteFile(fsPath, []byte(content), 0o640))
+	return fsPath
+}
+
+// roundTrip archives the source directory relative to the test directory and extracts it into the target directory
+func (archiveTestSuite *ArchiveTestSuite) roundTrip(createOpts *archive.CreateOptions, extractOpts *archive.ExtractOptions) []byte {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if createOpts.Dir == "" {
+		createOpts.Dir = archiveTestSuite.dir
+	}
+	archiveTestSuite.Require().NoError(archive.Create(ctx, &buf, []string{"source"}, createOpts))
+
+	extractOpts.Target = archiveTestSuite.target
+	archiveTestSuite.Require().NoError(archive.Extract(ctx, bytes.NewReader(buf.Bytes()), extractOpts))
+	return buf.Bytes()
+}
+
+// TestMemberName tests that leading "/" and "../" are removed like GNU tar does it
+func (archiveTestSuite *ArchiveTestSuite) TestMemberName() {
+	archiveTestSuite.Equal("srv/data", archive.MemberName("/srv/data/"))
+	archiveTestSuite.Equal("testdata/file", archive.MemberName("../../testdata/file"))
+	archiveTestSuite.Equal("", archive.MemberName("."))
+	archiveTestSuite.Equal("", archive.MemberName("/"))
+}
+
+// TestExcluded tests that patterns match the whole name or any of its trailing parts
+func (archiveTestSuite *ArchiveTestSuite) TestExcluded() {
+	archiveTestSuite.True(archive.Excluded("srv/data/app.log", []string{"*.log"}))
+	archiveTestSuite.True(archive.Excluded("srv/data/cache/", []string{"data/cache"}))
+	archiveTestSuite.False(archive.Excluded("srv/data/app.log", []string{"srv"}))
+	archiveTestSuite.False(archive.Excluded("srv/catalog", []string{"cat"}))
+}
+
+// TestRoundTrip tests that content, modes, times, links and extended attributes are preserved
+func (archiveTestSuite *ArchiveTestSuite) TestRoundTrip() {
+	file := archiveTestSuite.writeFile("data/file.txt", "brudi")
+	archiveTestSuite.writeFile("data/cache/tmp.bin", "cached")
+	archiveTestSuite.writeFile("data/app.log", "log")
+	archiveTestSuite.Require().NoError(os.Link(file, filepath.Join(archiveTestSuite.source, "hardlink.txt")))
+	archiveTestSuite.Require().NoError(os.Symlink("data/file.txt", filepath.Join(archiveTestSuite.source, "symlink")))
+	xattrErr := unix.Lsetxattr(file, "user.brudi", []byte("mittwald"), 0)
+
+	archiveTestSuite.roundTrip(&archive.CreateOptions{Exclude: []string{"cache", "*.log"}}, &archive.ExtractOptions{})
+	restored := filepath.Join(archiveTestSuite.target, "source")
+
+	content, err := os.ReadFile(filepath.Join(restored, "data", "file.txt"))
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Equal("brudi", string(content))
+	archiveTestSuite.NoDirExists(filepath.Join(restored, "data", "cache"))
+	archiveTestSuite.NoFileExists(filepath.Join(restored, "data", "app.log"))
+
+	sourceInfo, err := os.Stat(file)
+	archiveTestSuite.Require().NoError(err)
+	info, err := os.Stat(filepath.Join(restored, "data", "file.txt"))
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Equal(os.FileMode(0o640), info.Mode().Perm())
+	archiveTestSuite.True(sourceInfo.ModTime().Equal(info.ModTime()))
+	archiveTestSuite.Equal(uint64(2), uint64(info.Sys().(*syscall.Stat_t).Nlink))
+
+	dirInfo, err := os.Stat(filepath.Join(restored, "data"))
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Equal(os.FileMode(0o750), dirInfo.Mode().Perm())
+
+	link, err := os.Readlink(filepath.Join(restored, "symlink"))
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Equal("data/file.txt", link)
+
+	if xattrErr == nil {
+		value := make([]byte, 64)
+		size, getErr := unix.Lgetxattr(filepath.Join(restored, "data", "file.txt"), "user.brudi", value)
+		archiveTestSuite.Require().NoError(getErr)
+		archiveTestSuite.Equal("mittwald", string(value[:size]))
+	}
+}
+
+// TestSparseFile tests that the holes of sparse files are neither archived nor written when extracting
+func (archiveTestSuite *ArchiveTestSuite) TestSparseFile() {
+	const size = 64 << 20
+	sparse, err := os.Create(filepath.Join(archiveTestSuite.source, "sparse.img"))
+	archiveTestSuite.Require().NoError(err)
+	_, err = sparse.WriteAt([]byte("begin"), 0)
+	archiveTestSuite.Require().NoError(err)
+	_, err = sparse.WriteAt([]byte("middle"), size/2)
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Require().NoError(sparse.Truncate(size))
+	archiveTestSuite.Require().NoError(sparse.Close())
+
+	data := archiveTestSuite.roundTrip(&archive.CreateOptions{}, &archive.ExtractOptions{})
+	archiveTestSuite.Less(len(data), 1<<20)
+
+	restored := filepath.Join(archiveTestSuite.target, "source", "sparse.img")
+	content, err := os.ReadFile(restored)
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Len(content, size)
+	archiveTestSuite.Equal("begin", string(content[:5]))
+	archiveTestSuite.Equal("middle", string(content[size/2:size/2+6]))
+
+	info, err := os.Stat(restored)
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Less(info.Sys().(*syscall.Stat_t).Blocks*512, int64(size/4))
+
+	// GNU tar reads the sparse format as well
+	if _, lookErr := exec.LookPath("tar"); lookErr == nil {
+		archivePath := filepath.Join(archiveTestSuite.dir, "sparse.tar")
+		archiveTestSuite.Require().NoError(os.WriteFile(archivePath, data, 0o600))
+		out, listErr := exec.Command("tar", "-tvf", archivePath).CombinedOutput()
+		archiveTestSuite.Require().NoError(listErr, string(out))
+		archiveTestSuite.Contains(string(out), "source/sparse.img")
+		archiveTestSuite.NotContains(string(out), "GNUSparseFile")
+	}
+}
+
+// TestStripComponentsAndMembers tests extracting selected members without their leading components
+func (archiveTestSuite *ArchiveTestSuite) TestStripComponentsAndMembers() {
+	archiveTestSuite.writeFile("data/file.txt", "brudi")
+	archiveTestSuite.writeFile("other.txt", "other")
+
+	archiveTestSuite.roundTrip(&archive.CreateOptions{}, &archive.ExtractOptions{
+		StripComponents: 2,
+		Members:         []string{"source/data"},
+	})
+
+	archiveTestSuite.FileExists(filepath.Join(archiveTestSuite.target, "file.txt"))
+	archiveTestSuite.NoFileExists(filepath.Join(archiveTestSuite.target, "other.txt"))
+	archiveTestSuite.NoDirExists(filepath.Join(archiveTestSuite.target, "source"))
+}
+
+// TestKeepOldFiles tests that existing files are replaced unless they are to be kept
+func (archiveTestSuite *ArchiveTestSuite) TestKeepOldFiles() {
+	archiveTestSuite.writeFile("file.txt", "archived")
+	existing := filepath.Join(archiveTestSuite.target, "source", "file.txt")
+	archiveTestSuite.Require().NoError(os.MkdirAll(filepath.Dir(existing), 0o755))
+	archiveTestSuite.Require().NoError(os.WriteFile(existing, []byte("existing"), 0o600))
+
+	archiveTestSuite.roundTrip(&archive.CreateOptions{}, &archive.ExtractOptions{KeepOldFiles: true})
+	content, err := os.ReadFile(existing)
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Equal("existing", string(content))
+
+	archiveTestSuite.roundTrip(&archive.CreateOptions{}, &archive.ExtractOptions{})
+	content, err = os.ReadFile(existing)
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Equal("archived", string(content))
+}
+
+// TestUnsafePaths tests that members outside of the target directory are refused
+func (archiveTestSuite *ArchiveTestSuite) TestUnsafePaths() {
+	outside := filepath.Join(archiveTestSuite.dir, "outside")
+	archiveTestSuite.Require().NoError(os.MkdirAll(outside, 0o755))
+
+	for _, headers := range [][]*tar.Header{
+		{{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}},
+		{{Name: "/tmp/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}},
+		{{Name: "data/../../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}},
+		{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside, Mode: 0o777},
+			{Name: "link/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		},
+		{{Name: "hardlink", Typeflag: tar.TypeLink, Linkname: "../outside/evil.txt"}},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range headers {
+			archiveTestSuite.Require().NoError(tw.WriteHeader(hdr))
+		}
+		archiveTestSuite.Require().NoError(tw.Close())
+
+		err := archive.Extract(context.Background(), &buf, &archive.ExtractOptions{Target: archiveTestSuite.target})
+		archiveTestSuite.ErrorIs(err, archive.ErrUnsafePath, headers[len(headers)-1].Name)
+	}
+
+	entries, err := os.ReadDir(outside)
+	archiveTestSuite.Require().NoError(err)
+	archiveTestSuite.Empty(entries)
+	archiveTestSuite.NoFileExists(filepath.Join(archiveTestSuite.dir, "evil.txt"))
+	archiveTestSuite.False(strings.HasPrefix(archiveTestSuite.target, outside))
+}
+
+// TestIncremental tests that incremental archives only hold changed files and remove deleted ones when extracted
+func (archiveTestSuite *ArchiveTestSuite) TestIncremental() {
+	ctx := context.Background()
+	archiveTestSuite.writeFile("data/unchanged.txt", "unchanged")
+	changed := archiveTestSuite.writeFile("data/changed.txt", "before")
+	deleted := archiveTestSuite.writeFile("data/cache/deleted.txt", "deleted")
+
+	state := map[string]archive.FileState{}
+	var full bytes.Buffer
+	archiveTestSuite.Require().NoError(archive.Create(ctx, &full, []string{"source"},
+		&archive.CreateOptions{Dir: archiveTestSuite.dir, State: state}))
+	archiveTestSuite.Contains(state, "source/data/cache/deleted.txt")
+
+	archiveTestSuite.Require().NoError(os.WriteFile(changed, []byte("after"), 0o640))
+	archiveTestSuite.Require().NoError(os.Remove(deleted))
+	archiveTestSuite.writeFile("data/added.txt", "added")
+
+	var incremental bytes.Buffer
+	archiveTestSuite.Require().NoError(archive.Create(ctx, &incremental, []string{"source"},
+		&archive.CreateOptions{Dir: archiveTestSuite.dir, Previous: state, State: map[string]archive.FileState{}}))
+
+	var members []string
+	tr := tar.NewReader(bytes.NewReader(incremental.Bytes()))
+	for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+		if hdr.Typeflag != tar.TypeDir && hdr.Typeflag != tar.TypeXGlobalHeader {
+			members = append(members, hdr.Name)
+		}
+	}
+	archiveTestSuite.ElementsMatch([]string{"source/data/changed.txt", "source/data/added.txt"}, members)
+
+	for _, buf := range []bytes.Buffer{full, incremental} {
+		archiveTestSuite.Require().NoError(archive.Extract(ctx, bytes.NewReader(buf.Bytes()),
+			&archive.ExtractOptions{Target: archiveTestSuite.target}))
+	}
+	for name, content := range map[string]string{"unchanged.txt": "unchanged", "changed.txt": "after", "added.txt": "added"} {
+		data, err := os.ReadFile(filepath.Join(archiveTestSuite.target, "source", "data", name))
+		archiveTestSuite.Require().NoError(err)
+		archiveTestSuite.Equal(content, string(data))
+	}
+	archiveTestSuite.NoFileExists(filepath.Join(archiveTestSuite.target, "source", "data", "cache", "deleted.txt"))
+}
+
+func TestArchiveTestSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveTestSuite))
+}
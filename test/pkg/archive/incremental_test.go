@@ -0,0 +1,59 @@
+package testarchive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mittwald/brudi/pkg/archive"
+)
+
+// TestIncrementalArchives tests that the archives of all chains are found in order, ignoring other files
+func TestIncrementalArchives(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "app.tar.gz")
+	assert.Equal(t, filepath.Join(dir, "app.20261019T080000Z.002.tar.gz"), archive.IncrementalName(fileName, "20261019T080000Z", 2))
+
+	for _, name := range []string{
+		archive.IncrementalName(fileName, "20261019T080000Z", 1),
+		archive.IncrementalName(fileName, "20261018T080000Z", 0),
+		archive.IncrementalName(fileName, "20261019T080000Z", 0),
+		"app.20261019T080000Z.000.tar.gz.sha256",
+		"app.latest.000.tar.gz",
+		"other.20261019T080000Z.000.tar.gz",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, filepath.Base(name)), nil, 0o600))
+	}
+
+	archives, err := archive.IncrementalArchives(fileName)
+	require.NoError(t, err)
+	require.Len(t, archives, 3)
+	assert.Equal(t, archive.IncrementalArchive{Path: filepath.Join(dir, "app.20261018T080000Z.000.tar.gz"), Chain: "20261018T080000Z", Level: 0}, archives[0])
+	assert.Equal(t, "20261019T080000Z", archives[1].Chain)
+	assert.Equal(t, 0, archives[1].Level)
+	assert.Equal(t, 1, archives[2].Level)
+}
+
+// TestSnapshot tests that snapshots are written and read again, a missing snapshot file is no error
+func TestSnapshot(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.tar.snar")
+	snapshot, err := archive.ReadSnapshot(fileName)
+	require.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	snapshot = archive.NewChain(time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+	assert.Equal(t, "20261019T080000Z", snapshot.Chain)
+	snapshot.Level = 0
+	snapshot.Files["data/file.txt"] = archive.FileState{ModTime: 1, Size: 5}
+	require.NoError(t, snapshot.Write(fileName))
+
+	read, err := archive.ReadSnapshot(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Chain, read.Chain)
+	assert.True(t, snapshot.Started.Equal(read.Started))
+	assert.Equal(t, snapshot.Files, read.Files)
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -97,6 +99,68 @@ func (cliTestSuite *CliTestSuite) TestGzipFile() {
 	cliTestSuite.Require().NoError(err)
 }
 
+// TestRunWithLineHandler checks if stdout is passed line by line while stderr is returned
+func (cliTestSuite *CliTestSuite) TestRunWithLineHandler() {
+	cmd := cli.CommandType{
+		Binary: "sh",
+		Args:   []string{"-c", "printf 'first\\nsecond\\n'; printf 'failure' >&2"},
+	}
+
+	var lines []string
+	out, err := cli.RunWithLineHandler(context.TODO(), cmd, func(line []byte) {
+		lines = append(lines, string(line))
+	})
+	cliTestSuite.Require().NoError(err)
+	cliTestSuite.Assert().Equal([]string{"first", "second"}, lines)
+	cliTestSuite.Assert().Equal("failure", string(out))
+
+	cmd.Args = []string{"-c", "exit 1"}
+	_, err = cli.RunWithLineHandler(context.TODO(), cmd, func(line []byte) {})
+	cliTestSuite.Require().Error(err)
+}
+
+// TestRunWithInputAndLineHandler checks if the input is passed to stdin and a failing input kills the command
+func (cliTestSuite *CliTestSuite) TestRunWithInputAndLineHandler() {
+	cmd := cli.CommandType{
+		Binary: "wc",
+		Args:   []string{"-l"},
+	}
+
+	var lines []string
+	_, err := cli.RunWithInputAndLineHandler(context.TODO(), cmd, func(w io.Writer) error {
+		_, writeErr := io.WriteString(w, "first\nsecond\n")
+		return writeErr
+	}, func(line []byte) {
+		lines = append(lines, strings.TrimSpace(string(line)))
+	})
+	cliTestSuite.Require().NoError(err)
+	cliTestSuite.Assert().Equal([]string{"2"}, lines)
+
+	lines = nil
+	inputErr := errors.New("source failed")
+	_, err = cli.RunWithInputAndLineHandler(context.TODO(), cmd, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "first\n")
+		return inputErr
+	}, func(line []byte) {
+		lines = append(lines, string(line))
+	})
+	cliTestSuite.Require().ErrorIs(err, inputErr)
+	cliTestSuite.Assert().Empty(lines)
+}
+
+// TestRunWithEnv checks if the environment of a command extends the inherited one
+func (cliTestSuite *CliTestSuite) TestRunWithEnv() {
+	cmd := cli.CommandType{
+		Binary: "sh",
+		Args:   []string{"-c", "printf '%s %s' \"$BRUDI_TEST\" \"${PATH:+inherited}\""},
+		Env:    []string{"BRUDI_TEST=value"},
+	}
+
+	out, err := cli.Run(context.TODO(), cmd)
+	cliTestSuite.Require().NoError(err)
+	cliTestSuite.Assert().Equal("value inherited", string(out))
+}
+
 func TestCliTestSuite(t *testing.T) {
 	suite.Run(t, new(CliTestSuite))
 }
@@ -0,0 +1,141 @@
+package testcompress
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/compress"
+)
+
+type CompressTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (compressTestSuite *CompressTestSuite) SetupTest() {
+	compressTestSuite.dir = compressTestSuite.T().TempDir()
+}
+
+// testContent is large enough to be split into several blocks in parallel mode
+func testContent() []byte {
+	return bytes.Repeat([]byte("brudi compresses backups\n"), 20000)
+}
+
+// compress writes the test content compressed with the given algorithm into fileName
+func (compressTestSuite *CompressTestSuite) compress(fileName string, algorithm compress.Algorithm, cfg *compress.Config) {
+	err := compress.WriteFileAtomic(fileName, func(w io.Writer) error {
+		compressWriter, err := compress.NewWriter(w, algorithm, "dump.sql", cfg)
+		if err != nil {
+			return err
+		}
+		if _, err = compressWriter.Write(testContent()); err != nil {
+			return err
+		}
+		return compressWriter.Close()
+	})
+	compressTestSuite.Require().NoError(err)
+}
+
+// TestRoundTrip checks that single-threaded and parallel compression create archives which are detected by their
+// content and restored, including the name stored in the header of gzip archives
+func (compressTestSuite *CompressTestSuite) TestRoundTrip() {
+	configs := map[string]*compress.Config{
+		"default":  compress.DefaultConfig(),
+		"parallel": {Level: 3, Concurrency: 4, BlockSize: 65536},
+	}
+
+	for name, cfg := range configs {
+		for _, algorithm := range []compress.Algorithm{compress.Gzip, compress.Zstd, compress.Xz} {
+			fileName := filepath.Join(compressTestSuite.dir, fmt.Sprintf("%s-%s", name, algorithm))
+			compressTestSuite.compress(fileName, algorithm, cfg)
+
+			archive, err := os.Open(fileName)
+			compressTestSuite.Require().NoError(err)
+			reader := bufio.NewReader(archive)
+			header, err := reader.Peek(compress.HeaderSize)
+			compressTestSuite.Require().NoError(err)
+			compressTestSuite.Assert().Equal(algorithm, compress.DetectAlgorithm(header), fileName)
+
+			decompressReader, headerName, err := compress.NewReader(reader, algorithm)
+			compressTestSuite.Require().NoError(err, fileName)
+			content, err := io.ReadAll(decompressReader)
+			compressTestSuite.Require().NoError(err, fileName)
+			compressTestSuite.Assert().Equal(testContent(), content, fileName)
+			if algorithm == compress.Gzip {
+				compressTestSuite.Assert().Equal("dump.sql", headerName, fileName)
+			}
+			compressTestSuite.Require().NoError(decompressReader.Close())
+			compressTestSuite.Require().NoError(archive.Close())
+		}
+	}
+}
+
+// TestContextReaderCanceled checks that reading stops once the context is done
+func (compressTestSuite *CompressTestSuite) TestContextReaderCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := io.ReadAll(compress.NewContextReader(ctx, bytes.NewReader(testContent())))
+	compressTestSuite.Assert().ErrorIs(err, context.Canceled)
+}
+
+// TestWriteFileAtomic checks that a failed write neither creates the target nor leaves temporary files behind
+func (compressTestSuite *CompressTestSuite) TestWriteFileAtomic() {
+	fileName := filepath.Join(compressTestSuite.dir, "dump.sql")
+
+	err := compress.WriteFileAtomic(fileName, func(w io.Writer) error {
+		_, writeErr := w.Write(testContent())
+		compressTestSuite.Require().NoError(writeErr)
+		return fmt.Errorf("dump failed")
+	})
+	compressTestSuite.Assert().EqualError(err, "dump failed")
+
+	entries, err := os.ReadDir(compressTestSuite.dir)
+	compressTestSuite.Require().NoError(err)
+	compressTestSuite.Assert().Empty(entries)
+
+	err = compress.WriteFileAtomic(fileName, func(w io.Writer) error {
+		_, writeErr := w.Write(testContent())
+		return writeErr
+	})
+	compressTestSuite.Require().NoError(err)
+
+	content, err := os.ReadFile(fileName)
+	compressTestSuite.Require().NoError(err)
+	compressTestSuite.Assert().Equal(testContent(), content)
+}
+
+// TestNewConfigLevel checks that a configured level is validated for the algorithm it is used with
+func (compressTestSuite *CompressTestSuite) TestNewConfigLevel() {
+	defer viper.Reset()
+
+	cfg, err := compress.NewConfig(compress.Zstd)
+	compressTestSuite.Require().NoError(err)
+	compressTestSuite.Assert().Equal(compress.DefaultConfig(), cfg)
+
+	for level, valid := range map[int]map[compress.Algorithm]bool{
+		-1: {compress.Gzip: true, compress.Zstd: false, compress.Xz: true},
+		9:  {compress.Gzip: true, compress.Zstd: true, compress.Xz: true},
+		19: {compress.Gzip: false, compress.Zstd: true, compress.Xz: true},
+		23: {compress.Gzip: false, compress.Zstd: false, compress.Xz: true},
+	} {
+		viper.Set("compression.level", level)
+		for algorithm, expected := range valid {
+			_, err = compress.NewConfig(algorithm)
+			compressTestSuite.Assert().Equal(expected, err == nil, "level %d for %s", level, algorithm)
+		}
+	}
+}
+
+func TestCompressTestSuite(t *testing.T) {
+	suite.Run(t, new(CompressTestSuite))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mittwald/brudi/pkg/config"
 
@@ -118,6 +119,32 @@ func (initializeFromViperTestSuite *InitializeFromViperTestSuite) TestInitialize
 	assert.Equal(initializeFromViperTestSuite.T(), shouldBeConfig, isConfig)
 }
 
+func (initializeFromViperTestSuite *InitializeFromViperTestSuite) TestInitializeStructFromViperDuration() {
+	assert.NoError(initializeFromViperTestSuite.T(), viper.ReadConfig(bytes.NewBuffer([]byte(`
+foo:
+  timeout: 1h30m
+`))))
+
+	os.Setenv("FOO_INTERVAL", "45s")
+	defer os.Unsetenv("FOO_INTERVAL")
+
+	isConfig := durationFooConfig{}
+	shouldBeConfig := durationFooConfig{
+		Timeout:  90 * time.Minute,
+		Interval: 45 * time.Second,
+	}
+
+	assert.NoError(
+		initializeFromViperTestSuite.T(),
+		config.InitializeStructFromViper(
+			"foo",
+			&isConfig,
+		),
+	)
+
+	assert.Equal(initializeFromViperTestSuite.T(), shouldBeConfig, isConfig)
+}
+
 func TestInitializeFromViperTestSuite(t *testing.T) {
 	suite.Run(t, new(InitializeFromViperTestSuite))
 }
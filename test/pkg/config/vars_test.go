@@ -1,6 +1,8 @@
 package testconfig
 
 import (
+	"time"
+
 	"github.com/go-playground/validator/v10"
 
 	"github.com/mittwald/brudi/internal"
@@ -32,6 +34,11 @@ type taggedBarConfig struct {
 	CustomBrudiNumber    int    `viper:"brudiNumber"`
 }
 
+type durationFooConfig struct {
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
 func fooConfigValidation(sl validator.StructLevel) {
 	c := sl.Current().Interface().(untaggedFooConfig)
 
@@ -0,0 +1,171 @@
+package testdatabases
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/databases"
+)
+
+type DatabasesTestSuite struct {
+	suite.Suite
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestSelect() {
+	names := []string{"shop", "shop_test", "blog", "wiki"}
+
+	databasesTestSuite.Equal(names, databases.Select(names, nil, nil))
+	databasesTestSuite.Equal([]string{"shop", "shop_test"}, databases.Select(names, []string{"shop*"}, nil))
+	databasesTestSuite.Equal([]string{"shop", "blog", "wiki"}, databases.Select(names, nil, []string{"*_test"}))
+	databasesTestSuite.Equal([]string{"shop"}, databases.Select(names, []string{"shop*"}, []string{"*_test"}))
+	databasesTestSuite.Empty(databases.Select(names, []string{"forum"}, nil))
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestValidatePatterns() {
+	databasesTestSuite.NoError(databases.ValidatePatterns([]string{"shop*", "blog_?"}))
+	databasesTestSuite.Error(databases.ValidatePatterns([]string{"shop*", "[blog"}))
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestRunConcurrency() {
+	var running, maxRunning int32
+	var mutex sync.Mutex
+	called := map[string]bool{}
+
+	err := databases.Run(context.Background(), 2, []string{"a", "b", "c", "d", "e"}, func(_ context.Context, database string) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if current > maxRunning {
+			maxRunning = current
+		}
+		called[database] = true
+		return nil
+	})
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Len(called, 5)
+	databasesTestSuite.LessOrEqual(maxRunning, int32(2))
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestRunError() {
+	err := databases.Run(context.Background(), 1, []string{"a", "b", "c"}, func(_ context.Context, database string) error {
+		if database == "b" {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+	databasesTestSuite.Require().Error(err)
+	databasesTestSuite.Contains(err.Error(), "database b")
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestDumps() {
+	dir := databasesTestSuite.T().TempDir()
+	for _, name := range []string{"shop.sql.gz", "blog.sql", "notes.txt", ".sql"} {
+		databasesTestSuite.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte("dump"), 0o600))
+	}
+	databasesTestSuite.Require().NoError(os.Mkdir(filepath.Join(dir, "wiki.sql"), 0o755))
+
+	dumps, err := databases.Dumps(dir, ".sql")
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Equal(map[string]string{
+		"shop": filepath.Join(dir, "shop.sql.gz"),
+		"blog": filepath.Join(dir, "blog.sql"),
+	}, dumps)
+	databasesTestSuite.Equal([]string{"blog", "shop"}, databases.Names(dumps))
+	databasesTestSuite.Equal(filepath.Join(dir, "shop.sql.gz"), databases.FileName(dir, "shop", ".sql.gz"))
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestReplaceDirectory() {
+	dir := filepath.Join(databasesTestSuite.T().TempDir(), "databases")
+	databasesTestSuite.Require().NoError(os.MkdirAll(dir, 0o755))
+	databasesTestSuite.Require().NoError(os.WriteFile(filepath.Join(dir, "dropped.sql"), []byte("dump"), 0o600))
+
+	err := databases.ReplaceDirectory(dir, func(staging string) error {
+		if writeErr := os.WriteFile(filepath.Join(staging, "shop.sql"), []byte("dump"), 0o600); writeErr != nil {
+			return writeErr
+		}
+		return fmt.Errorf("failed")
+	})
+	databasesTestSuite.Require().Error(err)
+	dumps, err := databases.Dumps(dir, ".sql")
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Equal([]string{"dropped"}, databases.Names(dumps))
+
+	databasesTestSuite.Require().NoError(databases.ReplaceDirectory(dir, func(staging string) error {
+		return os.WriteFile(filepath.Join(staging, "shop.sql"), []byte("dump"), 0o600)
+	}))
+	dumps, err = databases.Dumps(dir, ".sql")
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Equal([]string{"shop"}, databases.Names(dumps))
+
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Len(entries, 1)
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestFileNameEscaping() {
+	dir := databasesTestSuite.T().TempDir()
+	names := []string{"../shop", "a/b", "100%", "..", "wiki"}
+	for _, name := range names {
+		fileName := databases.FileName(dir, name, ".sql")
+		databasesTestSuite.Equal(dir, filepath.Dir(fileName), name)
+		databasesTestSuite.Require().NoError(os.WriteFile(fileName, []byte("dump"), 0o600))
+	}
+
+	dumps, err := databases.Dumps(dir, ".sql")
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.ElementsMatch(names, databases.Names(dumps))
+	databasesTestSuite.Equal(databases.FileName(dir, "a/b", ".sql"), dumps["a/b"])
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestPostgresDumpSuffix() {
+	databasesTestSuite.Equal(".sql", databases.PostgresDumpSuffix(""))
+	databasesTestSuite.Equal(".sql", databases.PostgresDumpSuffix("plain"))
+	databasesTestSuite.Equal(".dump", databases.PostgresDumpSuffix("c"))
+	databasesTestSuite.Equal(".tar", databases.PostgresDumpSuffix("tar"))
+	databasesTestSuite.Empty(databases.PostgresDumpSuffix("d"))
+}
+
+func (databasesTestSuite *DatabasesTestSuite) TestPostgresDumps() {
+	dir := databasesTestSuite.T().TempDir()
+	databasesDir := filepath.Join(dir, databases.PostgresDatabasesDirectory)
+	databasesTestSuite.Require().NoError(os.Mkdir(databasesDir, 0o755))
+	databasesTestSuite.Require().NoError(os.WriteFile(filepath.Join(dir, databases.PostgresGlobalsFile), []byte("globals"), 0o600))
+	for _, name := range []string{"shop.sql.gz", "blog.dump", "wiki.tar.zst"} {
+		databasesTestSuite.Require().NoError(os.WriteFile(filepath.Join(databasesDir, name), []byte("dump"), 0o600))
+	}
+
+	dumps, err := databases.PostgresDumps(dir)
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Equal(map[string]string{
+		"shop": filepath.Join(databasesDir, "shop.sql.gz"),
+		"blog": filepath.Join(databasesDir, "blog.dump"),
+		"wiki": filepath.Join(databasesDir, "wiki.tar.zst"),
+	}, dumps)
+
+	// names are escaped, thus a database can't be dumped outside of the databases directory
+	escaped := databases.FileName(databasesDir, "../shop/..", ".dump")
+	databasesTestSuite.Equal(databasesDir, filepath.Dir(escaped))
+	databasesTestSuite.Require().NoError(os.WriteFile(escaped, []byte("dump"), 0o600))
+	dumps, err = databases.PostgresDumps(dir)
+	databasesTestSuite.Require().NoError(err)
+	databasesTestSuite.Equal(escaped, dumps["../shop/.."])
+	databasesTestSuite.Require().NoError(os.Remove(escaped))
+
+	// a database must not be dumped in several formats
+	databasesTestSuite.Require().NoError(os.WriteFile(filepath.Join(databasesDir, "shop.dump"), []byte("dump"), 0o600))
+	_, err = databases.PostgresDumps(dir)
+	databasesTestSuite.Error(err)
+}
+
+func TestDatabasesTestSuite(t *testing.T) {
+	suite.Run(t, new(DatabasesTestSuite))
+}
@@ -0,0 +1,191 @@
+package testdumpfile
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/dumpfile"
+)
+
+type DumpFileTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (dumpFileTestSuite *DumpFileTestSuite) SetupTest() {
+	dumpFileTestSuite.dir = dumpFileTestSuite.T().TempDir()
+	viper.Reset()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+}
+
+func (dumpFileTestSuite *DumpFileTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+func testContent() []byte {
+	return bytes.Repeat([]byte("INSERT INTO secrets VALUES ('brudi');\n"), 10000)
+}
+
+// setupAge configures a new age key pair
+func (dumpFileTestSuite *DumpFileTestSuite) setupAge() {
+	identity, err := age.GenerateX25519Identity()
+	dumpFileTestSuite.Require().NoError(err)
+
+	identityFile := filepath.Join(dumpFileTestSuite.dir, "identity.txt")
+	dumpFileTestSuite.Require().NoError(os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0o600))
+
+	// viper returns lists read from config files as []interface{}
+	viper.Set("encryption.age.recipients", []interface{}{identity.Recipient().String()})
+	viper.Set("encryption.age.identityFile", identityFile)
+}
+
+// setupPGP configures a new OpenPGP key pair
+func (dumpFileTestSuite *DumpFileTestSuite) setupPGP() {
+	// keys without hash preference would require RIPEMD160, GnuPG always sets one
+	config := &packet.Config{DefaultHash: crypto.SHA256}
+	entity, err := openpgp.NewEntity("brudi", "test", "brudi@example.com", config)
+	dumpFileTestSuite.Require().NoError(err)
+	for _, identity := range entity.Identities {
+		err = identity.SelfSignature.SignUserId(identity.UserId.Id, entity.PrimaryKey, entity.PrivateKey, config)
+		dumpFileTestSuite.Require().NoError(err)
+	}
+
+	var publicKey, privateKey bytes.Buffer
+	dumpFileTestSuite.Require().NoError(entity.Serialize(&publicKey))
+	dumpFileTestSuite.Require().NoError(entity.SerializePrivate(&privateKey, nil))
+
+	publicKeyFile := filepath.Join(dumpFileTestSuite.dir, "public.gpg")
+	privateKeyFile := filepath.Join(dumpFileTestSuite.dir, "private.gpg")
+	dumpFileTestSuite.Require().NoError(os.WriteFile(publicKeyFile, publicKey.Bytes(), 0o600))
+	dumpFileTestSuite.Require().NoError(os.WriteFile(privateKeyFile, privateKey.Bytes(), 0o600))
+
+	viper.Set("encryption.pgp.publicKeyFile", publicKeyFile)
+	viper.Set("encryption.pgp.privateKeyFile", privateKeyFile)
+}
+
+// writeDump writes the test content to the given dump file
+func (dumpFileTestSuite *DumpFileTestSuite) writeDump(fileName string) {
+	err := dumpfile.Write(fileName, func(w io.Writer) error {
+		_, writeErr := w.Write(testContent())
+		return writeErr
+	})
+	dumpFileTestSuite.Require().NoError(err)
+}
+
+// TestRoundTrip checks that encrypted and compressed dumps are restored to their plain content in a temporary file,
+// which is removed afterwards
+func (dumpFileTestSuite *DumpFileTestSuite) TestRoundTrip() {
+	dumpFileTestSuite.setupAge()
+	dumpFileTestSuite.setupPGP()
+
+	for _, suffix := range []string{".age", ".gpg", ".gz.age", ".zst.gpg", ".xz"} {
+		fileName := filepath.Join(dumpFileTestSuite.dir, "dump.sql"+suffix)
+		dumpFileTestSuite.writeDump(fileName)
+
+		raw, err := os.ReadFile(fileName)
+		dumpFileTestSuite.Require().NoError(err)
+		dumpFileTestSuite.Assert().False(bytes.Contains(raw, []byte("brudi")), suffix)
+
+		plainName, removePlain, err := dumpfile.Open(context.Background(), fileName)
+		dumpFileTestSuite.Require().NoError(err, suffix)
+		dumpFileTestSuite.Assert().Equal(dumpFileTestSuite.dir, filepath.Dir(plainName), suffix)
+
+		content, err := os.ReadFile(plainName)
+		dumpFileTestSuite.Require().NoError(err)
+		dumpFileTestSuite.Assert().Equal(testContent(), content, suffix)
+		removePlain()
+		dumpFileTestSuite.Assert().NoFileExists(plainName, suffix)
+		dumpFileTestSuite.Assert().FileExists(fileName, suffix)
+	}
+}
+
+// TestWriteFile checks that a plain dump is encrypted and removed afterwards
+func (dumpFileTestSuite *DumpFileTestSuite) TestWriteFile() {
+	dumpFileTestSuite.setupAge()
+
+	plainFile := filepath.Join(dumpFileTestSuite.dir, "dump.rdb")
+	dumpFileTestSuite.Require().NoError(os.WriteFile(plainFile, testContent(), 0o600))
+
+	fileName := plainFile + ".zst.age"
+	dumpFileTestSuite.Require().Equal(plainFile, dumpfile.PlainName(fileName))
+	dumpFileTestSuite.Require().NoError(dumpfile.WriteFile(context.Background(), plainFile, fileName))
+	dumpFileTestSuite.Assert().NoFileExists(plainFile)
+
+	plainName, removePlain, err := dumpfile.Open(context.Background(), fileName)
+	dumpFileTestSuite.Require().NoError(err)
+	defer removePlain()
+	content, err := os.ReadFile(plainName)
+	dumpFileTestSuite.Require().NoError(err)
+	dumpFileTestSuite.Assert().Equal(testContent(), content)
+}
+
+// TestMissingRecipients checks that no dump is written if the keys for encryption are missing
+func (dumpFileTestSuite *DumpFileTestSuite) TestMissingRecipients() {
+	fileName := filepath.Join(dumpFileTestSuite.dir, "dump.sql.age")
+
+	err := dumpfile.Write(fileName, func(w io.Writer) error {
+		_, writeErr := w.Write(testContent())
+		return writeErr
+	})
+	dumpFileTestSuite.Assert().Error(err)
+	dumpFileTestSuite.Assert().NoFileExists(fileName)
+}
+
+// TestOpenPlainDump checks that plain dumps are passed through
+func (dumpFileTestSuite *DumpFileTestSuite) TestOpenPlainDump() {
+	fileName := filepath.Join(dumpFileTestSuite.dir, "dump.sql")
+	dumpFileTestSuite.Require().NoError(os.WriteFile(fileName, testContent(), 0o600))
+
+	plainName, removePlain, err := dumpfile.Open(context.Background(), fileName)
+	dumpFileTestSuite.Require().NoError(err)
+	dumpFileTestSuite.Assert().Equal(fileName, plainName)
+	removePlain()
+	dumpFileTestSuite.Assert().FileExists(fileName)
+}
+
+// TestOpenFromWorkspace checks that a compressed dump restored into a workspace is decompressed within the workspace,
+// although its header holds its original path
+func (dumpFileTestSuite *DumpFileTestSuite) TestOpenFromWorkspace() {
+	for _, suffix := range []string{".gz", ".zst", ".gz.age"} {
+		if strings.HasSuffix(suffix, ".age") {
+			dumpFileTestSuite.setupAge()
+		}
+		original := filepath.Join(dumpFileTestSuite.dir, "data", "dump.sql"+suffix)
+		dumpFileTestSuite.Require().NoError(os.MkdirAll(filepath.Dir(original), 0o755))
+		dumpFileTestSuite.writeDump(original)
+
+		restored := filepath.Join(dumpFileTestSuite.dir, "workspace", "data", "dump.sql"+suffix)
+		dumpFileTestSuite.Require().NoError(os.MkdirAll(filepath.Dir(restored), 0o755))
+		dumpFileTestSuite.Require().NoError(os.Rename(original, restored))
+
+		plainName, removePlain, err := dumpfile.Open(context.Background(), restored)
+		dumpFileTestSuite.Require().NoError(err, suffix)
+		dumpFileTestSuite.Assert().Equal(filepath.Dir(restored), filepath.Dir(plainName), suffix)
+		entries, err := os.ReadDir(filepath.Dir(original))
+		dumpFileTestSuite.Require().NoError(err, suffix)
+		dumpFileTestSuite.Assert().Empty(entries, suffix)
+
+		content, err := os.ReadFile(plainName)
+		dumpFileTestSuite.Require().NoError(err, suffix)
+		dumpFileTestSuite.Assert().Equal(testContent(), content, suffix)
+		removePlain()
+		dumpFileTestSuite.Require().NoError(os.RemoveAll(filepath.Join(dumpFileTestSuite.dir, "workspace")))
+	}
+}
+
+func TestDumpFileTestSuite(t *testing.T) {
+	suite.Run(t, new(DumpFileTestSuite))
+}
@@ -0,0 +1,68 @@
+package testmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/manifest"
+)
+
+type ManifestTestSuite struct {
+	suite.Suite
+	dumpPath string
+}
+
+func (manifestTestSuite *ManifestTestSuite) SetupTest() {
+	manifestTestSuite.dumpPath = filepath.Join(manifestTestSuite.T().TempDir(), "dump.sql.gz")
+	manifestTestSuite.Require().NoError(os.WriteFile(manifestTestSuite.dumpPath, []byte("brudi dump"), 0o600))
+}
+
+// TestWriteAndVerify checks that an untouched dump matches its manifest
+func (manifestTestSuite *ManifestTestSuite) TestWriteAndVerify() {
+	written, err := manifest.Write(manifestTestSuite.dumpPath, "mysqldump", "mysqldump  Ver 8.0", "db-host")
+	manifestTestSuite.Require().NoError(err)
+	manifestTestSuite.FileExists(manifest.FileName(manifestTestSuite.dumpPath))
+
+	verified, err := manifest.Verify(manifestTestSuite.dumpPath)
+	manifestTestSuite.Require().NoError(err)
+	manifestTestSuite.Equal("dump.sql.gz", verified.File)
+	manifestTestSuite.Equal(int64(len("brudi dump")), verified.Size)
+	manifestTestSuite.Equal(written.SHA256, verified.SHA256)
+	manifestTestSuite.Equal("mysqldump", verified.Kind)
+	manifestTestSuite.Equal("mysqldump  Ver 8.0", verified.ToolVersion)
+	manifestTestSuite.Equal("db-host", verified.SourceHost)
+}
+
+// TestVerifyTampered checks that modified dumps of the same size are detected
+func (manifestTestSuite *ManifestTestSuite) TestVerifyTampered() {
+	_, err := manifest.Write(manifestTestSuite.dumpPath, "mysqldump", "", "db-host")
+	manifestTestSuite.Require().NoError(err)
+	manifestTestSuite.Require().NoError(os.WriteFile(manifestTestSuite.dumpPath, []byte("evil dump!"), 0o600))
+
+	_, err = manifest.Verify(manifestTestSuite.dumpPath)
+	manifestTestSuite.True(errors.Is(err, manifest.ErrMismatch))
+}
+
+// TestVerifyTruncated checks that truncated dumps are detected
+func (manifestTestSuite *ManifestTestSuite) TestVerifyTruncated() {
+	_, err := manifest.Write(manifestTestSuite.dumpPath, "mysqldump", "", "db-host")
+	manifestTestSuite.Require().NoError(err)
+	manifestTestSuite.Require().NoError(os.Truncate(manifestTestSuite.dumpPath, 3))
+
+	_, err = manifest.Verify(manifestTestSuite.dumpPath)
+	manifestTestSuite.True(errors.Is(err, manifest.ErrMismatch))
+}
+
+// TestVerifyWithoutManifest checks that dumps without manifest are reported as such
+func (manifestTestSuite *ManifestTestSuite) TestVerifyWithoutManifest() {
+	_, err := manifest.Verify(manifestTestSuite.dumpPath)
+	manifestTestSuite.True(errors.Is(err, manifest.ErrNoManifest))
+}
+
+func TestManifestTestSuite(t *testing.T) {
+	suite.Run(t, new(ManifestTestSuite))
+}
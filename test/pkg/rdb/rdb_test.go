@@ -0,0 +1,176 @@
+package testrdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/rdb"
+	"github.com/mittwald/brudi/pkg/validate"
+)
+
+const (
+	version         = 11
+	futureExpiry    = int64(4102444800000)
+	pastExpiry      = int64(1000)
+	zset2Type       = 5
+	intsetType      = 11
+	expireTimeMSOpc = 0xFC
+)
+
+type RDBTestSuite struct {
+	suite.Suite
+}
+
+// rdbString encodes a string shorter than 64 bytes
+func rdbString(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+// testFile returns an rdb file with keys of different types, encodings, databases and expiries
+func testFile() []byte {
+	file := []byte("REDIS0011")
+	file = append(file, 0xFA)
+	file = append(file, rdbString("redis-ver")...)
+	file = append(file, rdbString("7.2.4")...)
+	file = append(file, 0xFE, 0x00, 0xFB, 0x03, 0x01)
+
+	// string "brudi" => "backup"
+	file = append(file, 0x00)
+	file = append(file, rdbString("brudi")...)
+	file = append(file, rdbString("backup")...)
+
+	// string with expiry
+	file = append(file, expireTimeMSOpc)
+	file = binary.LittleEndian.AppendUint64(file, uint64(futureExpiry))
+	file = append(file, 0x00)
+	file = append(file, rdbString("session")...)
+	file = append(file, rdbString("token")...)
+
+	// expired string
+	file = append(file, expireTimeMSOpc)
+	file = binary.LittleEndian.AppendUint64(file, uint64(pastExpiry))
+	file = append(file, 0x00)
+	file = append(file, rdbString("expired")...)
+	file = append(file, rdbString("x")...)
+
+	file = append(file, 0xFE, 0x01)
+
+	// intset with int8 encoded key "123"
+	file = append(file, intsetType, 0xC0, 0x7B)
+	file = append(file, rdbString("\x02\x00\x00\x00\x01\x00\x00\x00\x01\x00\x02\x00")...)
+
+	// lzf compressed key "abcabc" with int16 encoded value "12345"
+	file = append(file, 0x00, 0xC3, 0x06, 0x06, 0x02, 'a', 'b', 'c', 0x20, 0x02, 0xC1, 0x39, 0x30)
+
+	// sorted set with a single member
+	file = append(file, zset2Type)
+	file = append(file, rdbString("scores")...)
+	file = append(file, 0x01)
+	file = append(file, rdbString("member")...)
+	file = binary.LittleEndian.AppendUint64(file, 0x3FF0000000000000)
+
+	file = append(file, 0xFF)
+	return binary.LittleEndian.AppendUint64(file, rdb.CRC64(0, file))
+}
+
+// payload returns the DUMP serialization of the given type and encoded value
+func payload(valueType byte, value []byte) []byte {
+	p := append([]byte{valueType}, value...)
+	p = binary.LittleEndian.AppendUint16(p, version)
+	return binary.LittleEndian.AppendUint64(p, rdb.CRC64(0, p))
+}
+
+func (rdbTestSuite *RDBTestSuite) TestTestFileIsValid() {
+	rdbTestSuite.NoError(validate.RedisRDB(bytes.NewReader(testFile())))
+}
+
+func (rdbTestSuite *RDBTestSuite) TestReader() {
+	reader, err := rdb.NewReader(bytes.NewReader(testFile()))
+	rdbTestSuite.Require().NoError(err)
+	rdbTestSuite.Equal(version, reader.Version())
+
+	var entries []*rdb.Entry
+	for {
+		entry, nextErr := reader.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+		rdbTestSuite.Require().NoError(nextErr)
+		entries = append(entries, entry)
+	}
+	rdbTestSuite.Require().Len(entries, 6)
+
+	rdbTestSuite.Equal("brudi", string(entries[0].Key))
+	rdbTestSuite.Equal(0, entries[0].DB)
+	rdbTestSuite.Equal(int64(0), entries[0].ExpireAt)
+	rdbTestSuite.Equal(payload(0x00, rdbString("backup")), entries[0].Payload)
+
+	rdbTestSuite.Equal("session", string(entries[1].Key))
+	rdbTestSuite.Equal(futureExpiry, entries[1].ExpireAt)
+	rdbTestSuite.Equal(pastExpiry, entries[2].ExpireAt)
+
+	rdbTestSuite.Equal("123", string(entries[3].Key))
+	rdbTestSuite.Equal(1, entries[3].DB)
+	rdbTestSuite.Equal(int64(0), entries[3].ExpireAt)
+
+	rdbTestSuite.Equal("abcabc", string(entries[4].Key))
+	rdbTestSuite.Equal(payload(0x00, []byte{0xC1, 0x39, 0x30}), entries[4].Payload)
+
+	rdbTestSuite.Equal("scores", string(entries[5].Key))
+}
+
+func (rdbTestSuite *RDBTestSuite) TestReaderTruncated() {
+	file := testFile()
+	reader, err := rdb.NewReader(bytes.NewReader(file[:40]))
+	rdbTestSuite.Require().NoError(err)
+
+	for err == nil {
+		_, err = reader.Next()
+	}
+	rdbTestSuite.True(errors.Is(err, rdb.ErrInvalidFormat))
+}
+
+func (rdbTestSuite *RDBTestSuite) TestWriteRestoreCommands() {
+	reader, err := rdb.NewReader(bytes.NewReader(testFile()))
+	rdbTestSuite.Require().NoError(err)
+
+	var out bytes.Buffer
+	stats, err := rdb.WriteRestoreCommands(&out, reader, rdb.RestoreOptions{
+		Flush: true,
+		Now:   time.UnixMilli(pastExpiry + 1),
+	})
+	rdbTestSuite.Require().NoError(err)
+	rdbTestSuite.Equal(5, stats.Keys)
+	rdbTestSuite.Equal(1, stats.Expired)
+
+	commands := out.String()
+	rdbTestSuite.True(strings.HasPrefix(commands, "*1\r\n$8\r\nFLUSHALL\r\n*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n"))
+	brudiPayload := string(payload(0x00, rdbString("backup")))
+	rdbTestSuite.Contains(commands, "*6\r\n$7\r\nRESTORE\r\n$5\r\nbrudi\r\n$1\r\n0\r\n$18\r\n"+brudiPayload+"\r\n$7\r\nREPLACE\r\n$6\r\nABSTTL\r\n")
+	rdbTestSuite.Contains(commands, "$13\r\n4102444800000\r\n")
+	rdbTestSuite.NotContains(commands, "expired")
+	rdbTestSuite.Contains(commands, "*2\r\n$6\r\nSELECT\r\n$1\r\n1\r\n")
+}
+
+func (rdbTestSuite *RDBTestSuite) TestWriteRestoreCommandsSkipExisting() {
+	reader, err := rdb.NewReader(bytes.NewReader(testFile()))
+	rdbTestSuite.Require().NoError(err)
+
+	var out bytes.Buffer
+	_, err = rdb.WriteRestoreCommands(&out, reader, rdb.RestoreOptions{SkipExisting: true, Now: time.Now()})
+	rdbTestSuite.Require().NoError(err)
+	rdbTestSuite.NotContains(out.String(), "FLUSHALL")
+	rdbTestSuite.NotContains(out.String(), "REPLACE")
+	rdbTestSuite.Contains(out.String(), "\r\nEVAL\r\n")
+}
+
+func TestRDBTestSuite(t *testing.T) {
+	suite.Run(t, new(RDBTestSuite))
+}
@@ -0,0 +1,86 @@
+package testrestic
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+type ErrorsTestSuite struct {
+	suite.Suite
+}
+
+// exitWith runs a command failing with the given exit code and returns its error
+func (errorsTestSuite *ErrorsTestSuite) exitWith(code string) error {
+	_, err := cli.Run(context.TODO(), cli.CommandType{Binary: "sh", Args: []string{"-c", "exit " + code}})
+	errorsTestSuite.Require().Error(err)
+	return err
+}
+
+// TestClassifyByExitCode checks the classification by the exit codes of recent restic versions
+func (errorsTestSuite *ErrorsTestSuite) TestClassifyByExitCode() {
+	for code, class := range map[string]error{
+		"10": restic.ErrRepoNotExist,
+		"11": restic.ErrRepoLocked,
+		"12": restic.ErrWrongPassword,
+	} {
+		err := restic.ClassifyError(errorsTestSuite.exitWith(code), nil)
+		errorsTestSuite.Assert().ErrorIs(err, class)
+
+		var resticErr *restic.Error
+		errorsTestSuite.Require().True(errors.As(err, &resticErr))
+		errorsTestSuite.Assert().Equal(code, strconv.Itoa(resticErr.ExitCode))
+	}
+}
+
+// TestClassifyByJSONMessage checks the classification by the json error message printed before exiting
+func (errorsTestSuite *ErrorsTestSuite) TestClassifyByJSONMessage() {
+	out := []byte(`{"message_type":"exit_error","code":1,"message":"Fatal: unable to save snapshot: no space left on device"}`)
+
+	err := restic.ClassifyError(errorsTestSuite.exitWith("1"), out)
+	errorsTestSuite.Assert().ErrorIs(err, restic.ErrNoSpace)
+	errorsTestSuite.Assert().Equal(
+		"no space left on device (exit code 1): Fatal: unable to save snapshot: no space left on device", err.Error(),
+	)
+}
+
+// TestClassifyByOutput checks the classification by the plain text output of older restic versions
+func (errorsTestSuite *ErrorsTestSuite) TestClassifyByOutput() {
+	for out, class := range map[string]error{
+		"Fatal: wrong password or no key found": restic.ErrWrongPassword,
+		"Fatal: unable to open config file: <config/> does not exist\nIs there a repository at the following location?":           restic.ErrRepoNotExist,
+		"Fatal: unable to open config file: Head: dial tcp: connection refused\nIs there a repository at the following location?": restic.ErrBackend,
+		"unable to create lock in backend: repository is already locked by PID 42":                                                restic.ErrRepoLocked,
+	} {
+		err := restic.ClassifyError(errorsTestSuite.exitWith("1"), []byte(out))
+		errorsTestSuite.Assert().ErrorIs(err, class, out)
+	}
+
+	errorsTestSuite.Assert().True(restic.IsTransient(restic.ClassifyError(errorsTestSuite.exitWith("1"), []byte("502 Bad Gateway"))))
+	errorsTestSuite.Assert().False(restic.IsTransient(restic.ClassifyError(errorsTestSuite.exitWith("1"), []byte("Fatal: something"))))
+}
+
+// TestClassifyNil checks that successful commands aren't turned into errors
+func (errorsTestSuite *ErrorsTestSuite) TestClassifyNil() {
+	errorsTestSuite.Assert().NoError(restic.ClassifyError(nil, []byte("wrong password or no key found")))
+}
+
+// TestParseVersion checks the version parsing of "restic version", which decides about the classification by exit codes
+func (errorsTestSuite *ErrorsTestSuite) TestParseVersion() {
+	major, minor, ok := restic.ParseVersion([]byte("restic 0.17.3 compiled with go1.23.3 on linux/amd64\n"))
+	errorsTestSuite.Require().True(ok)
+	errorsTestSuite.Assert().Equal([]int{0, 17}, []int{major, minor})
+
+	_, _, ok = restic.ParseVersion([]byte("[]"))
+	errorsTestSuite.Assert().False(ok)
+}
+
+func TestErrorsTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
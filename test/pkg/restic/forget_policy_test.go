@@ -0,0 +1,87 @@
+package testrestic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+type ForgetPolicyTestSuite struct {
+	suite.Suite
+}
+
+func (forgetPolicyTestSuite *ForgetPolicyTestSuite) SetupTest() {
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+}
+
+func (forgetPolicyTestSuite *ForgetPolicyTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+var forgetPolicyConfig = []byte(`
+restic:
+  backup:
+    flags:
+      tags:
+        - daily
+        - brudi
+  forget:
+    flags:
+      keepLast: 48
+      keepDaily: 7
+mysqldump:
+  restic:
+    forget:
+      flags:
+        keepWeekly: 4
+`)
+
+// TestGlobalPolicyIsScoped checks that the global policy is used and scoped to host, paths and tags of the backup
+func (forgetPolicyTestSuite *ForgetPolicyTestSuite) TestGlobalPolicyIsScoped() {
+	forgetPolicyTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(forgetPolicyConfig)))
+
+	client, err := restic.NewResticClient(log.WithField("test", "forget"), "redisdump", "redis-host", "/tmp/redis.rdb")
+	forgetPolicyTestSuite.Require().NoError(err)
+
+	forgetPolicyTestSuite.Assert().Equal(
+		&restic.ForgetFlags{
+			KeepLast:  48,
+			KeepDaily: 7,
+			Host:      "redis-host",
+			Paths:     []string{"/tmp/redis.rdb"},
+			Tags:      []string{"daily,brudi"},
+		},
+		client.Config.Forget.Flags,
+	)
+}
+
+// TestKindPolicyReplacesGlobalPolicy checks that a policy declared for a kind replaces the global policy
+func (forgetPolicyTestSuite *ForgetPolicyTestSuite) TestKindPolicyReplacesGlobalPolicy() {
+	forgetPolicyTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(forgetPolicyConfig)))
+
+	client, err := restic.NewResticClient(log.WithField("test", "forget"), "mysqldump", "mysql-host", "/tmp/mysql.sql")
+	forgetPolicyTestSuite.Require().NoError(err)
+
+	forgetPolicyTestSuite.Assert().Equal(
+		&restic.ForgetFlags{
+			KeepWeekly: 4,
+			Host:       "mysql-host",
+			Paths:      []string{"/tmp/mysql.sql"},
+			Tags:       []string{"daily,brudi"},
+		},
+		client.Config.Forget.Flags,
+	)
+}
+
+func TestForgetPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(ForgetPolicyTestSuite))
+}
@@ -0,0 +1,77 @@
+package testrestic
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+// forgetOutput is a shortened output of "restic forget --json --compact --dry-run"
+const forgetOutput = `[{"tags":null,"host":"db","paths":["/tmp/test.sqldump"],
+"keep":[{"time":"2023-06-16T10:00:00Z","paths":["/tmp/test.sqldump"],"hostname":"db","id":"aaaa1111ffff","short_id":"aaaa1111"}],
+"remove":[{"time":"2023-06-15T10:00:00Z","paths":["/tmp/test.sqldump"],"hostname":"db","id":"bbbb2222ffff","short_id":"bbbb2222"}],
+"reasons":[{"snapshot":{"time":"2023-06-16T10:00:00Z","paths":["/tmp/test.sqldump"],"hostname":"db","id":"aaaa1111ffff","short_id":"aaaa1111"},
+"matches":["last snapshot","daily snapshot"],"counters":{"last":1,"daily":6}}]}]`
+
+type ForgetReportTestSuite struct {
+	suite.Suite
+}
+
+func (forgetReportTestSuite *ForgetReportTestSuite) newReport() *restic.ForgetReport {
+	var forgetTags []*restic.ForgetTag
+	forgetReportTestSuite.Require().NoError(json.Unmarshal([]byte(forgetOutput), &forgetTags))
+
+	report := restic.NewForgetReport(forgetTags, true)
+	report.ReclaimableSize = 3 * 1024 * 1024
+	return report
+}
+
+// TestNewForgetReport checks that snapshots are listed per group along with their reasons
+func (forgetReportTestSuite *ForgetReportTestSuite) TestNewForgetReport() {
+	report := forgetReportTestSuite.newReport()
+
+	forgetReportTestSuite.Require().Len(report.Groups, 1)
+	forgetReportTestSuite.Assert().Equal(
+		[]restic.ForgetReportSnapshot{
+			{ID: "aaaa1111", Time: "2023-06-16T10:00:00Z", Action: "keep", Reasons: []string{"last snapshot", "daily snapshot"}},
+			{ID: "bbbb2222", Time: "2023-06-15T10:00:00Z", Action: "remove"},
+		},
+		report.Groups[0].Snapshots,
+	)
+
+	kept, removed := report.Count()
+	forgetReportTestSuite.Assert().Equal(1, kept)
+	forgetReportTestSuite.Assert().Equal(1, removed)
+}
+
+// TestWriteTable checks the summary of the rendered table
+func (forgetReportTestSuite *ForgetReportTestSuite) TestWriteTable() {
+	var out bytes.Buffer
+	forgetReportTestSuite.Require().NoError(forgetReportTestSuite.newReport().WriteTable(&out))
+
+	forgetReportTestSuite.Assert().Contains(out.String(), "host: db, paths: /tmp/test.sqldump")
+	forgetReportTestSuite.Assert().Contains(out.String(), "last snapshot, daily snapshot")
+	forgetReportTestSuite.Assert().Contains(
+		out.String(), "1 snapshots kept, 1 snapshots would be removed, estimated reclaimable space: 3.0 MiB",
+	)
+}
+
+// TestWriteJSON checks that the rendered json can be read again
+func (forgetReportTestSuite *ForgetReportTestSuite) TestWriteJSON() {
+	report := forgetReportTestSuite.newReport()
+
+	var out bytes.Buffer
+	forgetReportTestSuite.Require().NoError(report.WriteJSON(&out))
+
+	var decoded restic.ForgetReport
+	forgetReportTestSuite.Require().NoError(json.Unmarshal(out.Bytes(), &decoded))
+	forgetReportTestSuite.Assert().Equal(*report, decoded)
+}
+
+func TestForgetReportTestSuite(t *testing.T) {
+	suite.Run(t, new(ForgetReportTestSuite))
+}
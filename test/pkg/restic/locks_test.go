@@ -0,0 +1,43 @@
+package testrestic
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+// deadPID is beyond the default pid_max of linux, thus no process can have it
+const deadPID = 4194305
+
+type LocksTestSuite struct {
+	suite.Suite
+}
+
+// TestOldLockIsStale checks that locks older than the max age are stale, regardless of their host
+func (locksTestSuite *LocksTestSuite) TestOldLockIsStale() {
+	now := time.Now()
+	lock := restic.Lock{Time: now.Add(-time.Hour), Hostname: "other-host", PID: os.Getpid()}
+
+	locksTestSuite.Assert().True(lock.IsStale(30*time.Minute, "this-host", now))
+	locksTestSuite.Assert().False(lock.IsStale(2*time.Hour, "this-host", now))
+}
+
+// TestLockOfDeadProcessIsStale checks that recent locks are only stale if they belong to a dead process on this host
+func (locksTestSuite *LocksTestSuite) TestLockOfDeadProcessIsStale() {
+	now := time.Now()
+
+	deadLock := restic.Lock{Time: now, Hostname: "this-host", PID: deadPID}
+	locksTestSuite.Assert().True(deadLock.IsStale(time.Hour, "this-host", now))
+	locksTestSuite.Assert().False(deadLock.IsStale(time.Hour, "other-host", now))
+
+	aliveLock := restic.Lock{Time: now, Hostname: "this-host", PID: os.Getpid()}
+	locksTestSuite.Assert().False(aliveLock.IsStale(time.Hour, "this-host", now))
+}
+
+func TestLocksTestSuite(t *testing.T) {
+	suite.Run(t, new(LocksTestSuite))
+}
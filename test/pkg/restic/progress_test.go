@@ -0,0 +1,50 @@
+package testrestic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+type ProgressTestSuite struct {
+	suite.Suite
+}
+
+// TestReportThrottled checks that status messages are only passed on once per interval
+func (progressTestSuite *ProgressTestSuite) TestReportThrottled() {
+	var reported []*restic.BackupStatus
+	reporter := restic.NewProgressReporter(time.Hour, func(status *restic.BackupStatus) {
+		reported = append(reported, status)
+	})
+
+	first := &restic.BackupStatus{PercentDone: 0.1}
+	progressTestSuite.Assert().True(reporter.Report(first))
+	progressTestSuite.Assert().False(reporter.Report(&restic.BackupStatus{PercentDone: 0.2}))
+	progressTestSuite.Assert().Equal([]*restic.BackupStatus{first}, reported)
+}
+
+// TestReportEveryInterval checks that status messages are passed on again after the interval has passed
+func (progressTestSuite *ProgressTestSuite) TestReportEveryInterval() {
+	var reported int
+	reporter := restic.NewProgressReporter(time.Millisecond, func(status *restic.BackupStatus) {
+		reported++
+	})
+
+	progressTestSuite.Assert().True(reporter.Report(&restic.BackupStatus{}))
+	time.Sleep(2 * time.Millisecond)
+	progressTestSuite.Assert().True(reporter.Report(&restic.BackupStatus{}))
+	progressTestSuite.Assert().Equal(2, reported)
+}
+
+// TestReportNil checks that a disabled reporter ignores status messages
+func (progressTestSuite *ProgressTestSuite) TestReportNil() {
+	var reporter *restic.ProgressReporter
+	progressTestSuite.Assert().False(reporter.Report(&restic.BackupStatus{}))
+}
+
+func TestProgressTestSuite(t *testing.T) {
+	suite.Run(t, new(ProgressTestSuite))
+}
@@ -0,0 +1,64 @@
+package testrestic
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/restic"
+)
+
+type RestoreTestSuite struct {
+	suite.Suite
+}
+
+func (restoreTestSuite *RestoreTestSuite) SetupTest() {
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+}
+
+func (restoreTestSuite *RestoreTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// TestRestoredPath checks that restored paths keep their absolute path below the target
+func (restoreTestSuite *RestoreTestSuite) TestRestoredPath() {
+	restored, err := restic.RestoredPath("/var/tmp/workspace", "/tmp/mysql.sql")
+	restoreTestSuite.Require().NoError(err)
+	restoreTestSuite.Equal("/var/tmp/workspace/tmp/mysql.sql", restored)
+
+	wd, err := os.Getwd()
+	restoreTestSuite.Require().NoError(err)
+	restored, err = restic.RestoredPath("/var/tmp/workspace", "mysql.sql")
+	restoreTestSuite.Require().NoError(err)
+	restoreTestSuite.Equal(filepath.Join("/var/tmp/workspace", wd, "mysql.sql"), restored)
+}
+
+// TestWorkspace checks that the workspace is loaded and the root directory is refused, since it is removed on cleanup
+func (restoreTestSuite *RestoreTestSuite) TestWorkspace() {
+	restoreTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBufferString(`
+restic:
+  restore:
+    id: latest
+    workspace: /var/tmp/workspace
+`)))
+	client, err := restic.NewResticClient(log.WithField("test", "restore"), "mysqlrestore", "mysql-host", "/tmp/mysql.sql")
+	restoreTestSuite.Require().NoError(err)
+	restoreTestSuite.Equal("/var/tmp/workspace", client.Config.Restore.Workspace)
+
+	viper.Set("restic.restore.workspace", "/")
+	_, err = restic.NewResticClient(log.WithField("test", "restore"), "mysqlrestore", "mysql-host", "/tmp/mysql.sql")
+	restoreTestSuite.Error(err)
+}
+
+func TestRestoreTestSuite(t *testing.T) {
+	suite.Run(t, new(RestoreTestSuite))
+}
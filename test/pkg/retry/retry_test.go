@@ -0,0 +1,130 @@
+package testretry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/retry"
+)
+
+var errTransient = fmt.Errorf("dial tcp 127.0.0.1:3306: connect: connection refused")
+var errPermanent = fmt.Errorf("access denied for user 'root'")
+
+type RetryTestSuite struct {
+	suite.Suite
+}
+
+func (retryTestSuite *RetryTestSuite) SetupTest() {
+	viper.Reset()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	viper.Set("retry.dump.maxAttempts", 3)
+	viper.Set("retry.dump.initialBackoff", "1ms")
+	viper.Set("retry.dump.maxBackoff", "2ms")
+	viper.Set("retry.dump.jitter", "0s")
+}
+
+func (retryTestSuite *RetryTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// TestRetriesTransientErrors checks that transient errors are retried and partial output is removed in between
+func (retryTestSuite *RetryTestSuite) TestRetriesTransientErrors() {
+	retrier, err := retry.NewRetrier()
+	retryTestSuite.Require().NoError(err)
+
+	calls, cleanups := 0, 0
+	err = retrier.Do(
+		context.Background(), log.WithField("test", "retry"), retry.StageDump, retry.IsTransientCommandError,
+		func() error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		},
+		func() { cleanups++ },
+	)
+
+	retryTestSuite.Require().NoError(err)
+	retryTestSuite.Assert().Equal(3, calls)
+	retryTestSuite.Assert().Equal(2, cleanups)
+	retryTestSuite.Assert().Equal(map[string]int{retry.StageDump: 3}, retrier.Attempts())
+}
+
+// TestGivesUpAfterMaxAttempts checks that the last error is returned once the max attempts are reached
+func (retryTestSuite *RetryTestSuite) TestGivesUpAfterMaxAttempts() {
+	retrier, err := retry.NewRetrier()
+	retryTestSuite.Require().NoError(err)
+
+	calls := 0
+	err = retrier.Do(
+		context.Background(), log.WithField("test", "retry"), retry.StageDump, retry.IsTransientCommandError,
+		func() error {
+			calls++
+			return errTransient
+		},
+		nil,
+	)
+
+	retryTestSuite.Assert().ErrorIs(err, errTransient)
+	retryTestSuite.Assert().Equal(3, calls)
+}
+
+// TestUnlimitedBackoff checks that the backoff is still doubled for every attempt if maxBackoff is 0
+func (retryTestSuite *RetryTestSuite) TestUnlimitedBackoff() {
+	viper.Set("retry.dump.initialBackoff", "20ms")
+	viper.Set("retry.dump.maxBackoff", "0s")
+	retrier, err := retry.NewRetrier()
+	retryTestSuite.Require().NoError(err)
+
+	start := time.Now()
+	err = retrier.Do(
+		context.Background(), log.WithField("test", "retry"), retry.StageDump, retry.IsTransientCommandError,
+		func() error {
+			return errTransient
+		},
+		nil,
+	)
+
+	retryTestSuite.Assert().ErrorIs(err, errTransient)
+	retryTestSuite.Assert().GreaterOrEqual(time.Since(start), 60*time.Millisecond)
+}
+
+// TestDoesNotRetryPermanentErrors checks that errors which aren't transient fail immediately
+func (retryTestSuite *RetryTestSuite) TestDoesNotRetryPermanentErrors() {
+	retrier, err := retry.NewRetrier()
+	retryTestSuite.Require().NoError(err)
+
+	calls := 0
+	err = retrier.Do(
+		context.Background(), log.WithField("test", "retry"), retry.StageDump, retry.IsTransientCommandError,
+		func() error {
+			calls++
+			return errPermanent
+		},
+		nil,
+	)
+
+	retryTestSuite.Assert().ErrorIs(err, errPermanent)
+	retryTestSuite.Assert().Equal(1, calls)
+}
+
+// TestInvalidPolicy checks that a policy without attempts is rejected
+func (retryTestSuite *RetryTestSuite) TestInvalidPolicy() {
+	viper.Set("retry.backup.maxAttempts", 0)
+
+	_, err := retry.NewRetrier()
+	retryTestSuite.Assert().Error(err)
+}
+
+func TestRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
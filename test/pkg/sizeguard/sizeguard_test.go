@@ -0,0 +1,93 @@
+package testsizeguard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/sizeguard"
+)
+
+type SizeGuardTestSuite struct {
+	suite.Suite
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TestDefaultConfig() {
+	cfg, err := sizeguard.NewConfig()
+	sizeGuardTestSuite.Require().NoError(err)
+	sizeGuardTestSuite.False(cfg.Enabled())
+	sizeGuardTestSuite.Equal(sizeguard.ActionWarn, cfg.Action)
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TestConfig() {
+	viper.Set(sizeguard.Kind, map[string]interface{}{
+		"minSize":      1024,
+		"maxDeviation": 50,
+		"action":       "fail",
+	})
+
+	cfg, err := sizeguard.NewConfig()
+	sizeGuardTestSuite.Require().NoError(err)
+	sizeGuardTestSuite.True(cfg.Enabled())
+	sizeGuardTestSuite.Equal(1024, cfg.MinSize)
+	sizeGuardTestSuite.Equal(50, cfg.MaxDeviation)
+	sizeGuardTestSuite.Equal(sizeguard.ActionFail, cfg.Action)
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TestConfigInvalidAction() {
+	viper.Set(sizeguard.Kind, map[string]interface{}{
+		"action": "ignore",
+	})
+
+	_, err := sizeguard.NewConfig()
+	sizeGuardTestSuite.Error(err)
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TestCheckMinSize() {
+	cfg := &sizeguard.Config{MinSize: 1024}
+	sizeGuardTestSuite.NoError(cfg.CheckMinSize(1024))
+	sizeGuardTestSuite.True(errors.Is(cfg.CheckMinSize(20), sizeguard.ErrAnomaly))
+
+	disabled := &sizeguard.Config{}
+	sizeGuardTestSuite.NoError(disabled.CheckMinSize(0))
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TestCheckDeviation() {
+	cfg := &sizeguard.Config{MaxDeviation: 50}
+	sizeGuardTestSuite.NoError(cfg.CheckDeviation(150, 100))
+	sizeGuardTestSuite.NoError(cfg.CheckDeviation(50, 100))
+	sizeGuardTestSuite.True(errors.Is(cfg.CheckDeviation(49, 100), sizeguard.ErrAnomaly))
+	sizeGuardTestSuite.True(errors.Is(cfg.CheckDeviation(151, 100), sizeguard.ErrAnomaly))
+	// an empty previous backup can't be compared against
+	sizeGuardTestSuite.NoError(cfg.CheckDeviation(100, 0))
+}
+
+func (sizeGuardTestSuite *SizeGuardTestSuite) TestPathSize() {
+	dir := sizeGuardTestSuite.T().TempDir()
+	sizeGuardTestSuite.Require().NoError(os.WriteFile(filepath.Join(dir, "a.bson"), make([]byte, 100), 0o600))
+	sizeGuardTestSuite.Require().NoError(os.Mkdir(filepath.Join(dir, "db"), 0o700))
+	sizeGuardTestSuite.Require().NoError(os.WriteFile(filepath.Join(dir, "db", "b.bson"), make([]byte, 50), 0o600))
+
+	size, err := sizeguard.PathSize(dir)
+	sizeGuardTestSuite.Require().NoError(err)
+	sizeGuardTestSuite.Equal(uint64(150), size)
+
+	size, err = sizeguard.PathSize(filepath.Join(dir, "a.bson"))
+	sizeGuardTestSuite.Require().NoError(err)
+	sizeGuardTestSuite.Equal(uint64(100), size)
+
+	_, err = sizeguard.PathSize(filepath.Join(dir, "missing"))
+	sizeGuardTestSuite.Error(err)
+}
+
+func TestSizeGuardTestSuite(t *testing.T) {
+	suite.Run(t, new(SizeGuardTestSuite))
+}
@@ -0,0 +1,156 @@
+package etcd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/etcdrestore"
+	"github.com/mittwald/brudi/pkg/source/etcdsnapshot"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+const memberName = "brudi"
+
+type EtcdTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (etcdTestSuite *EtcdTestSuite) SetupTest() {
+	for _, binary := range []string{"etcd", "etcdctl", "etcdutl"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			etcdTestSuite.T().Skipf("%s is not installed", binary)
+		}
+	}
+	commons.TestSetup()
+
+	etcdTestSuite.dir = etcdTestSuite.T().TempDir()
+}
+
+// TearDownTest resets viper after a test
+func (etcdTestSuite *EtcdTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// freeURL returns a local url with a free port
+func (etcdTestSuite *EtcdTestSuite) freeURL() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	etcdTestSuite.Require().NoError(err)
+	defer listener.Close()
+	return "http://" + listener.Addr().String()
+}
+
+// startEtcd starts a local single-node etcd on the given data directory and returns its client url
+func (etcdTestSuite *EtcdTestSuite) startEtcd(dataDir, peerURL string) string {
+	clientURL := etcdTestSuite.freeURL()
+
+	cmd := exec.Command("etcd",
+		"--name="+memberName,
+		"--data-dir="+dataDir,
+		"--listen-client-urls="+clientURL,
+		"--advertise-client-urls="+clientURL,
+		"--listen-peer-urls="+peerURL,
+		"--initial-advertise-peer-urls="+peerURL,
+		"--initial-cluster="+memberName+"="+peerURL,
+	)
+	etcdTestSuite.Require().NoError(cmd.Start())
+	etcdTestSuite.T().Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	for i := 0; i < 50; i++ {
+		if err := exec.Command("etcdctl", "--endpoints="+clientURL, "endpoint", "health").Run(); err == nil {
+			return clientURL
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	etcdTestSuite.FailNow("etcd didn't become healthy")
+	return ""
+}
+
+// etcdctl runs etcdctl against the given endpoint and returns its output
+func (etcdTestSuite *EtcdTestSuite) etcdctl(endpoint string, args ...string) string {
+	out, err := exec.Command("etcdctl", append([]string{"--endpoints=" + endpoint}, args...)...).CombinedOutput()
+	etcdTestSuite.Require().NoError(err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// TestSnapshotAndRestore tests that a compressed snapshot is restored into a new data directory
+func (etcdTestSuite *EtcdTestSuite) TestSnapshotAndRestore() {
+	ctx := context.Background()
+	endpoint := etcdTestSuite.startEtcd(filepath.Join(etcdTestSuite.dir, "data"), etcdTestSuite.freeURL())
+	etcdTestSuite.etcdctl(endpoint, "put", "brudi", "mittwald")
+	etcdTestSuite.etcdctl(endpoint, "put", "backup", "restic")
+
+	backupFile := filepath.Join(etcdTestSuite.dir, "snapshot.db.gz")
+	restoredDir := filepath.Join(etcdTestSuite.dir, "restored")
+	peerURL := etcdTestSuite.freeURL()
+
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    flags:
+      endpoints: %s
+    file: %s
+    minKeys: 2
+  hostName: etcdtest
+%s:
+  options:
+    flags:
+      dataDir: %s
+      name: %s
+      initialCluster: %s=%s
+      initialAdvertisePeerURLs: %s
+    sourceFile: %s
+  hostName: etcdtest
+`, etcdsnapshot.Kind, endpoint, backupFile,
+		etcdrestore.Kind, restoredDir, memberName, memberName, peerURL, peerURL, backupFile)))
+	etcdTestSuite.Require().NoError(err)
+
+	etcdTestSuite.Require().NoError(source.DoBackupForKind(ctx, etcdsnapshot.Kind, false, false, false, false))
+	etcdTestSuite.FileExists(backupFile)
+	etcdTestSuite.NoFileExists(filepath.Join(etcdTestSuite.dir, "snapshot.db"))
+
+	etcdTestSuite.Require().NoError(source.DoRestoreForKind(ctx, etcdrestore.Kind, false, false, false))
+	restoredEndpoint := etcdTestSuite.startEtcd(restoredDir, peerURL)
+	etcdTestSuite.Equal("mittwald", etcdTestSuite.etcdctl(restoredEndpoint, "get", "brudi", "--print-value-only"))
+
+	// restoring into an existing data directory is refused
+	etcdTestSuite.Error(source.DoRestoreForKind(ctx, etcdrestore.Kind, false, false, false))
+}
+
+// TestMinKeys tests that a snapshot with too few keys is rejected
+func (etcdTestSuite *EtcdTestSuite) TestMinKeys() {
+	endpoint := etcdTestSuite.startEtcd(filepath.Join(etcdTestSuite.dir, "data"), etcdTestSuite.freeURL())
+	backupFile := filepath.Join(etcdTestSuite.dir, "snapshot.db")
+
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    flags:
+      endpoints: %s
+    file: %s
+    minKeys: 1
+`, etcdsnapshot.Kind, endpoint, backupFile)))
+	etcdTestSuite.Require().NoError(err)
+
+	err = source.DoBackupForKind(context.Background(), etcdsnapshot.Kind, false, false, false, false)
+	etcdTestSuite.ErrorIs(err, etcdsnapshot.ErrInvalidSnapshot)
+	etcdTestSuite.NoFileExists(backupFile)
+}
+
+func TestEtcdTestSuite(t *testing.T) {
+	suite.Run(t, new(EtcdTestSuite))
+}
@@ -0,0 +1,40 @@
+package etcd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/source/etcdsnapshot"
+)
+
+type StatusTestSuite struct {
+	suite.Suite
+}
+
+// TestParseStatus tests parsing the output of "etcdutl snapshot status -w json"
+func (statusTestSuite *StatusTestSuite) TestParseStatus() {
+	status, err := etcdsnapshot.ParseStatus([]byte(
+		`{"hash":3761946352,"revision":4,"totalKey":3,"totalSize":20480,"version":"3.5.0"}`))
+	statusTestSuite.Require().NoError(err)
+
+	statusTestSuite.Equal(uint32(3761946352), status.Hash)
+	statusTestSuite.Equal(int64(4), status.Revision)
+	statusTestSuite.Equal(3, status.TotalKey)
+	statusTestSuite.NoError(status.Check(3))
+	statusTestSuite.ErrorIs(status.Check(4), etcdsnapshot.ErrInvalidSnapshot)
+}
+
+// TestInvalidStatus tests that unparsable and empty snapshots are rejected
+func (statusTestSuite *StatusTestSuite) TestInvalidStatus() {
+	_, err := etcdsnapshot.ParseStatus([]byte("Error: snapshot file integrity check failed"))
+	statusTestSuite.ErrorIs(err, etcdsnapshot.ErrInvalidSnapshot)
+
+	status, err := etcdsnapshot.ParseStatus([]byte(`{"hash":0,"revision":0,"totalKey":0,"totalSize":0}`))
+	statusTestSuite.Require().NoError(err)
+	statusTestSuite.ErrorIs(status.Check(0), etcdsnapshot.ErrInvalidSnapshot)
+}
+
+func TestStatusTestSuite(t *testing.T) {
+	suite.Run(t, new(StatusTestSuite))
+}
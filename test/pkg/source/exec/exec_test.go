@@ -0,0 +1,114 @@
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/exec"
+	"github.com/mittwald/brudi/pkg/source/execrestore"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+type ExecTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (execTestSuite *ExecTestSuite) SetupTest() {
+	commons.TestSetup()
+	execTestSuite.dir = execTestSuite.T().TempDir()
+}
+
+// TearDownTest resets viper after a test
+func (execTestSuite *ExecTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// TestBackupAndRestore tests that the output of a command is compressed and fed into the restore command
+func (execTestSuite *ExecTestSuite) TestBackupAndRestore() {
+	ctx := context.Background()
+	backupFile := filepath.Join(execTestSuite.dir, "export.txt.gz")
+	restoredFile := filepath.Join(execTestSuite.dir, "restored.txt")
+
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    command: sh
+    args:
+      - -c
+      - printf '%%s\n' "$EXPORT" brudi
+    env:
+      - EXPORT=mittwald
+    file: %s
+  hostName: exectest
+%s:
+  options:
+    command: sh
+    args:
+      - -c
+      - cat > "$TARGET"
+    env:
+      - TARGET=%s
+    sourceFile: %s
+  hostName: exectest
+`, exec.Kind, backupFile, execrestore.Kind, restoredFile, backupFile)))
+	execTestSuite.Require().NoError(err)
+
+	execTestSuite.Require().NoError(source.DoBackupForKind(ctx, exec.Kind, false, false, false, false))
+	execTestSuite.FileExists(backupFile)
+	execTestSuite.Require().NoError(source.DoRestoreForKind(ctx, execrestore.Kind, false, false, false))
+
+	restored, err := os.ReadFile(restoredFile)
+	execTestSuite.Require().NoError(err)
+	execTestSuite.Equal("mittwald\nbrudi\n", string(restored))
+}
+
+// TestFailingCommand tests that a non-zero exit code fails the backup without leaving a partial file
+func (execTestSuite *ExecTestSuite) TestFailingCommand() {
+	backupFile := filepath.Join(execTestSuite.dir, "export.txt")
+
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    command: sh
+    args:
+      - -c
+      - echo partial; exit 3
+    file: %s
+`, exec.Kind, backupFile)))
+	execTestSuite.Require().NoError(err)
+
+	execTestSuite.Error(source.DoBackupForKind(context.Background(), exec.Kind, false, false, false, false))
+	execTestSuite.NoFileExists(backupFile)
+}
+
+// TestCleanup tests that the backup is removed after the run if requested
+func (execTestSuite *ExecTestSuite) TestCleanup() {
+	backupFile := filepath.Join(execTestSuite.dir, "export.txt")
+
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    command: echo
+    args:
+      - brudi
+    file: %s
+`, exec.Kind, backupFile)))
+	execTestSuite.Require().NoError(err)
+
+	execTestSuite.Require().NoError(source.DoBackupForKind(context.Background(), exec.Kind, true, false, false, false))
+	execTestSuite.NoFileExists(backupFile)
+	execTestSuite.NoFileExists(backupFile + ".manifest.json")
+}
+
+func TestExecTestSuite(t *testing.T) {
+	suite.Run(t, new(ExecTestSuite))
+}
@@ -0,0 +1,99 @@
+package fsbackup_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/cli"
+	"github.com/mittwald/brudi/pkg/restic"
+	"github.com/mittwald/brudi/pkg/source/fsbackup"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+type FSBackupOptionsSuite struct {
+	suite.Suite
+}
+
+func (s *FSBackupOptionsSuite) SetupTest() {
+	commons.TestSetup()
+}
+
+func (s *FSBackupOptionsSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// TestResticBackupFlags tests that path and paths are combined and the rules are mapped onto "restic backup"
+func (s *FSBackupOptionsSuite) TestResticBackupFlags() {
+	first, second := s.T().TempDir(), s.T().TempDir()
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+fsbackup:
+  options:
+    path: %s
+    paths:
+      - %s
+    exclude:
+      - "*.log"
+      - cache
+    include:
+      - important.log
+    excludeCaches: true
+    oneFileSystem: true
+    excludeLargerThan: 1048576
+  hostName: fsbackup-host
+`, first, second))))
+
+	backend, err := fsbackup.NewConfigBasedBackend()
+	s.Require().NoError(err)
+	s.Equal([]string{first, second}, backend.GetBackupPaths())
+	s.Equal(first, backend.GetBackupPath())
+	s.Require().NoError(backend.CreateBackup(context.Background()))
+
+	flags := &restic.BackupFlags{Exclude: []string{"*.tmp"}}
+	backend.ConfigureResticBackup(flags)
+	s.Equal([]string{"*.tmp", "*.log", "cache", "!important.log"}, flags.Exclude)
+	s.Equal(
+		[]string{
+			"-e", "*.tmp", "-e", "*.log", "-e", "cache", "-e", "!important.log",
+			"--exclude-larger-than", "1048576", "--exclude-caches", "-x",
+		},
+		cli.StructToCLI(flags),
+	)
+}
+
+// TestValidation tests that paths are required, invalid patterns are refused and missing paths fail the backup
+func (s *FSBackupOptionsSuite) TestValidation() {
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(`
+fsbackup:
+  options:
+    exclude:
+      - "[cache"
+  hostName: fsbackup-host
+`)))
+	_, err := fsbackup.NewConfigBasedBackend()
+	s.Require().Error(err)
+	s.Contains(err.Error(), "pathOrPathsRequired")
+	s.Contains(err.Error(), "invalidPattern")
+
+	viper.Reset()
+	commons.TestSetup()
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+fsbackup:
+  options:
+    paths:
+      - %s
+  hostName: fsbackup-host
+`, filepath.Join(s.T().TempDir(), "missing")))))
+	backend, err := fsbackup.NewConfigBasedBackend()
+	s.Require().NoError(err)
+	s.Error(backend.CreateBackup(context.Background()))
+}
+
+func TestFSBackupOptionsSuite(t *testing.T) {
+	suite.Run(t, new(FSBackupOptionsSuite))
+}
@@ -65,7 +65,7 @@ func (s *FSRestoreSuite) TestResticRestoreDirectory() {
 	err = viper.ReadConfig(bytes.NewBuffer(restoreConfig))
 	s.Require().NoError(err)
 
-	err = source.DoRestoreForKind(ctx, fsrestore.Kind, false, true)
+	err = source.DoRestoreForKind(ctx, fsrestore.Kind, false, true, false)
 	s.Require().NoError(err)
 
 	relativeSourcePath := strings.TrimPrefix(sourceDir, string(os.PathSeparator))
@@ -76,6 +76,56 @@ func (s *FSRestoreSuite) TestResticRestoreDirectory() {
 	s.Equal(fileContent, restoredContent)
 }
 
+// TestResticRestoreIntoTarget tests that the snapshot is restored into the target directory,
+// that files which don't exist in the snapshot are removed in mirror mode and that the result is verified
+func (s *FSRestoreSuite) TestResticRestoreIntoTarget() {
+	ctx := context.Background()
+
+	resticContainer, err := commons.NewTestContainerSetup(ctx, &commons.ResticReq, commons.ResticPort)
+	s.Require().NoError(err)
+	defer func() {
+		resticErr := resticContainer.Container.Terminate(ctx)
+		if resticErr != nil {
+			s.T().Logf("failed to terminate restic container: %v", resticErr)
+		}
+	}()
+
+	sourceDir := s.T().TempDir()
+	host := "fsrestore-target-host"
+	s.Require().NoError(os.MkdirAll(filepath.Join(sourceDir, "nested"), 0o750))
+	s.Require().NoError(os.WriteFile(filepath.Join(sourceDir, "nested", "sample.txt"), []byte("restored"), 0o640))
+
+	err = viper.ReadConfig(bytes.NewBuffer(createFSBackupConfig(host, sourceDir, resticContainer.Address, resticContainer.Port)))
+	s.Require().NoError(err)
+	err = source.DoBackupForKind(ctx, fsbackup.Kind, false, true, false, false)
+	s.Require().NoError(err)
+
+	target := filepath.Join(s.T().TempDir(), "target")
+	s.Require().NoError(os.MkdirAll(filepath.Join(target, "nested"), 0o755))
+	s.Require().NoError(os.WriteFile(filepath.Join(target, "nested", "sample.txt"), []byte("outdated"), 0o644))
+	s.Require().NoError(os.WriteFile(filepath.Join(target, "stale.txt"), []byte("stale"), 0o644))
+
+	viper.Reset()
+	commons.TestSetup()
+	restoreConfig := createFSRestoreTargetConfig(host, sourceDir, target, resticContainer.Address, resticContainer.Port)
+	s.Require().NoError(viper.ReadConfig(bytes.NewBuffer(restoreConfig)))
+
+	err = source.DoRestoreForKind(ctx, fsrestore.Kind, false, true, false)
+	s.Require().NoError(err)
+
+	restoredContent, err := os.ReadFile(filepath.Join(target, "nested", "sample.txt"))
+	s.Require().NoError(err)
+	s.Equal("restored", string(restoredContent))
+	info, err := os.Stat(filepath.Join(target, "nested", "sample.txt"))
+	s.Require().NoError(err)
+	s.Equal(os.FileMode(0o640), info.Mode().Perm())
+	s.NoFileExists(filepath.Join(target, "stale.txt"))
+
+	entries, err := os.ReadDir(filepath.Dir(target))
+	s.Require().NoError(err)
+	s.Len(entries, 1, "staging directory has to be removed")
+}
+
 func TestFSRestoreSuite(t *testing.T) {
 	suite.Run(t, new(FSRestoreSuite))
 }
@@ -118,3 +168,22 @@ restic:
 `, path, host, resticIP, resticPort, target,
 	))
 }
+
+func createFSRestoreTargetConfig(host, path, target, resticIP, resticPort string) []byte {
+	return []byte(fmt.Sprintf(
+		`
+fsrestore:
+  options:
+    path: %s
+    target: %s
+    mirror: true
+  hostName: %s
+restic:
+  global:
+    flags:
+      repo: rest:http://%s:%s/
+  restore:
+    id: "latest"
+`, path, target, host, resticIP, resticPort,
+	))
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResticScript is a restic binary which keeps its state in FAKE_RESTIC_DIR.
+// Every call is recorded in the file calls, "restic backup" prints a status and a summary message and every other
+// command succeeds with an empty list. "restic init" always fails, "restic cat config" exits with 10 once if the file
+// uninitialized exists and "restic backup" exits with 11 once if the file locked exists. Locks are read from the
+// repository, "restic unlock" must not be used for it. "restic restore" creates the included path within the target
+// with a toc.dat, like a dump of pg_dump in directory format.
+const fakeResticScript = `#!/bin/sh
+echo "$@" >> "$FAKE_RESTIC_DIR/calls"
+case " $* " in
+  *" restore "*)
+    include=""
+    target=""
+    prev=""
+    for arg in "$@"; do
+      case "$prev" in
+        -i) include="$arg";;
+        -t) target="$arg";;
+      esac
+      prev="$arg"
+    done
+    mkdir -p "$target$include" && touch "$target$include/toc.dat"
+    exit $?;;
+esac
+lock=""
+for arg in "$@"; do
+  if [ -n "$lock" ]; then
+    cat "$FAKE_RESTIC_DIR/repo/locks/$arg" || exit 1
+    exit 0
+  fi
+  case "$arg" in
+    version)
+      echo "restic 0.17.3 compiled with go1.23.3 on linux/amd64"
+      exit 0;;
+    config)
+      if [ -e "$FAKE_RESTIC_DIR/uninitialized" ]; then
+        rm "$FAKE_RESTIC_DIR/uninitialized"
+        exit 10
+      fi
+      exit 0;;
+    init)
+      echo '{"message_type":"exit_error","code":1,"message":"Fatal: create repository failed: unexpected error"}'
+      exit 1;;
+    lock)
+      lock=1;;
+    locks)
+      ls "$FAKE_RESTIC_DIR/repo/locks"
+      exit 0;;
+    unlock)
+      echo "unexpected unlock" >&2
+      exit 1;;
+    backup)
+      if [ -e "$FAKE_RESTIC_DIR/locked" ]; then
+        rm "$FAKE_RESTIC_DIR/locked"
+        exit 11
+      fi
+      echo '{"message_type":"status","percent_done":0.5,"total_files":2,"files_done":1,"total_bytes":20,"bytes_done":10}'
+      echo '{"message_type":"summary","files_new":2,"data_added":20,"total_files_processed":2,"total_bytes_processed":20,"snapshot_id":"f00ba7"}'
+      exit 0;;
+  esac
+done
+echo '[]'
+`
+
+// FakeRestic is a fake restic binary in front of PATH together with a directory holding the data to back up
+// and the repository, e.g. to test how brudi handles the output of restic without a real repository
+type FakeRestic struct {
+	Dir  string
+	Repo string
+}
+
+// NewFakeRestic puts a fake restic binary in front of PATH for the duration of the test
+func NewFakeRestic(t *testing.T) *FakeRestic {
+	t.Helper()
+	dir := t.TempDir()
+	fake := &FakeRestic{Dir: dir, Repo: filepath.Join(dir, "repo")}
+	if err := os.MkdirAll(filepath.Join(fake.Repo, "locks"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.txt"), []byte("fake restic"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_RESTIC_DIR", dir)
+	FakeBinary(t, "restic", fakeResticScript)
+	return fake
+}
+
+// Config returns a configuration which backs up data.txt with tar into the repository of the fake binary,
+// resticConfig is appended to the restic section and has to be indented by two spaces
+func (f *FakeRestic) Config(resticConfig string) string {
+	return fmt.Sprintf(`
+tar:
+  options:
+    flags:
+      file: %[1]s/data.tar.gz
+      target: %[1]s
+    paths:
+      - data.txt
+  hostName: fake-restic
+restic:
+  global:
+    flags:
+      repo: %[2]s
+%[3]s`, f.Dir, f.Repo, resticConfig)
+}
+
+// Calls returns the arguments of every call of the fake binary so far
+func (f *FakeRestic) Calls(t *testing.T) [][]string {
+	t.Helper()
+	out, err := os.ReadFile(filepath.Join(f.Dir, "calls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls [][]string
+	for _, call := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		calls = append(calls, strings.Fields(call))
+	}
+	return calls
+}
+
+// InitializeConcurrently lets the next check for the repository fail as if it didn't exist yet,
+// while "restic init" fails as if another job had initialized it in the meantime
+func (f *FakeRestic) InitializeConcurrently(t *testing.T) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(f.Dir, "uninitialized"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WriteLock writes a lock of another host with the given age to the repository and lets the next backup fail
+// as if the repository was locked
+func (f *FakeRestic) WriteLock(t *testing.T, id string, age time.Duration, exclusive bool) {
+	t.Helper()
+	lock := fmt.Sprintf(
+		`{"time":%q,"exclusive":%t,"hostname":"other-host","username":"brudi","pid":1}`,
+		time.Now().Add(-age).Format(time.RFC3339Nano), exclusive,
+	)
+	if err := os.WriteFile(filepath.Join(f.Repo, "locks", id), []byte(lock), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(f.Dir, "locked"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"testing"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -127,6 +128,17 @@ func TestSetup() {
 	os.Setenv("RESTIC_PASSWORD", ResticPassword)
 }
 
+// FakeBinary puts an executable shell script with the given name in front of PATH for the duration of the test,
+// e.g. to run restic without a repository
+func FakeBinary(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
 // DoResticRestore pulls the given backup from the given restic repo
 func DoResticRestore(ctx context.Context, resticContainer TestContainerSetup, dataDir string) error {
 	cmd := exec.CommandContext(
@@ -195,7 +195,7 @@ func mongoDoRestore(
 	}
 
 	// use `mongorestore` to restore backed up data to new container
-	err = source.DoRestoreForKind(ctx, restoreKind, false, useRestic)
+	err = source.DoRestoreForKind(ctx, restoreKind, false, useRestic, false)
 	if err != nil {
 		return []interface{}{}, err
 	}
@@ -310,7 +310,7 @@ func mySQLDoRestore(
 	time.Sleep(10 * time.Second)
 
 	// restore server from mysqldump
-	doRestoreErr := source.DoRestoreForKind(ctx, restoreKind, false, useRestic)
+	doRestoreErr := source.DoRestoreForKind(ctx, restoreKind, false, useRestic, false)
 	if doRestoreErr != nil {
 		return []TestStruct{}, errors.Wrap(doRestoreErr, "failed to restore mysql backup container")
 	}
@@ -0,0 +1,131 @@
+package mysql_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// escapedDatabase needs escaping in the name of its dump
+const escapedDatabase = "my shop"
+
+// staleDatabase is dumped by the first run only, its dump must not be restored
+const staleDatabase = "stale"
+
+// TestPerDatabaseDumpAndRestore dumps several databases into separate files twice, dropping a database in between,
+// and restores the dumps of the latest run into another server
+func (mySQLDumpAndRestoreTestSuite *MySQLDumpAndRestoreTestSuite) TestPerDatabaseDumpAndRestore() {
+	ctx := context.Background()
+	dir := mySQLDumpAndRestoreTestSuite.T().TempDir()
+	directory := filepath.Join(dir, "databases")
+
+	backupTarget, err := commons.NewTestContainerSetup(ctx, &mySQLRequest, sqlPort)
+	mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	defer func() {
+		if terminateErr := backupTarget.Container.Terminate(ctx); terminateErr != nil {
+			log.WithError(terminateErr).Error("failed to terminate mysql backup container")
+		}
+	}()
+	time.Sleep(10 * time.Second)
+
+	db := openMySQL(mySQLDumpAndRestoreTestSuite, backupTarget, "")
+	defer db.Close()
+	for _, database := range []string{mySQLDatabase, escapedDatabase, staleDatabase} {
+		_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database))
+		mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+		_, err = db.Exec(fmt.Sprintf("CREATE TABLE `%s`.%s(id INT NOT NULL, name VARCHAR(100) NOT NULL, PRIMARY KEY (id))", database, tableName))
+		mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+		_, err = db.Exec(fmt.Sprintf("INSERT INTO `%s`.%s VALUES (1, '%s')", database, tableName, database))
+		mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	}
+
+	mySQLDumpAndRestoreTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createPerDatabaseConfig(backupTarget, directory))))
+	mySQLDumpAndRestoreTestSuite.Require().NoError(source.DoBackupForKind(ctx, dumpKind, false, false, false, false))
+	_, err = db.Exec(fmt.Sprintf("DROP DATABASE `%s`", staleDatabase))
+	mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	mySQLDumpAndRestoreTestSuite.Require().NoError(source.DoBackupForKind(ctx, dumpKind, false, false, false, false))
+
+	entries, err := os.ReadDir(directory)
+	mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	var dumps []string
+	for _, entry := range entries {
+		dumps = append(dumps, entry.Name())
+	}
+	mySQLDumpAndRestoreTestSuite.ElementsMatch([]string{mySQLDatabase + ".sql.gz", "my%20shop.sql.gz"}, dumps)
+
+	restoreTarget, err := commons.NewTestContainerSetup(ctx, &mySQLRequest, sqlPort)
+	mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	defer func() {
+		if terminateErr := restoreTarget.Container.Terminate(ctx); terminateErr != nil {
+			log.WithError(terminateErr).Error("failed to terminate mysql restore container")
+		}
+	}()
+	time.Sleep(10 * time.Second)
+
+	commons.TestSetup()
+	mySQLDumpAndRestoreTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createPerDatabaseConfig(restoreTarget, directory))))
+	mySQLDumpAndRestoreTestSuite.Require().NoError(source.DoRestoreForKind(ctx, restoreKind, false, false, false))
+
+	restored := openMySQL(mySQLDumpAndRestoreTestSuite, restoreTarget, "")
+	defer restored.Close()
+	for _, database := range []string{mySQLDatabase, escapedDatabase} {
+		var name string
+		err = restored.QueryRow(fmt.Sprintf("SELECT name FROM `%s`.%s WHERE id = 1", database, tableName)).Scan(&name)
+		mySQLDumpAndRestoreTestSuite.Require().NoError(err, database)
+		mySQLDumpAndRestoreTestSuite.Equal(database, name)
+	}
+	var count int
+	err = restored.QueryRow("SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name = ?", staleDatabase).Scan(&count)
+	mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	mySQLDumpAndRestoreTestSuite.Zero(count)
+}
+
+// openMySQL connects to the given database of the container as root
+func openMySQL(mySQLDumpAndRestoreTestSuite *MySQLDumpAndRestoreTestSuite, container commons.TestContainerSetup, database string) *sql.DB {
+	db, err := sql.Open(dbDriver, fmt.Sprintf(
+		"%s:%s@tcp(%s:%s)/%s?tls=false", mySQLRoot, mySQLRootPW, container.Address, container.Port, database,
+	))
+	mySQLDumpAndRestoreTestSuite.Require().NoError(err)
+	return db
+}
+
+// createPerDatabaseConfig creates a brudi config dumping and restoring the databases of the container one by one
+func createPerDatabaseConfig(container commons.TestContainerSetup, directory string) []byte {
+	return []byte(fmt.Sprintf(`
+mysqldump:
+  options:
+    flags:
+      host: %[1]s
+      port: %[2]s
+      password: %[3]s
+      user: %[4]s
+      opt: true
+      skipSsl: true
+    perDatabase:
+      enabled: true
+      directory: %[5]s
+      suffix: .gz
+      concurrency: 2
+mysqlrestore:
+  options:
+    flags:
+      host: %[1]s
+      port: %[2]s
+      password: %[3]s
+      user: %[4]s
+      skipSsl: true
+    perDatabase:
+      enabled: true
+      directory: %[5]s
+`, hostName, container.Port, mySQLRootPW, mySQLRoot, directory))
+}
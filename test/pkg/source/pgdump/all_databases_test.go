@@ -0,0 +1,94 @@
+package pgdump_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+
+	"github.com/mittwald/brudi/pkg/databases"
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// fakePsqlScript lists the databases of the file databases within FAKE_PG_DIR
+const fakePsqlScript = `#!/bin/sh
+case "$*" in *"SELECT datname"*) cat "$FAKE_PG_DIR/databases"; exit 0;; esac
+echo "unexpected query: $*" >&2
+exit 1
+`
+
+// fakePgDumpScript writes a plain-text dump of the database, it fails for the database of the file failing within
+// FAKE_PG_DIR
+const fakePgDumpScript = `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    --dbname=*)
+      if [ "${arg#--dbname=}" = "$(cat "$FAKE_PG_DIR/failing" 2>/dev/null)" ]; then
+        echo "connection failed" >&2
+        exit 1
+      fi;;
+  esac
+done
+printf -- "-- dump of %s\n--\n-- PostgreSQL database dump complete\n--\n" "$*"
+`
+
+const fakePgDumpallScript = `#!/bin/sh
+printf -- "CREATE ROLE app;\n--\n-- PostgreSQL database cluster dump complete\n--\n\n"
+`
+
+// TestAllDatabasesReplacesDumps tests that the directory only holds the dumps of the latest successful run
+func (s *PGOptionsSuite) TestAllDatabasesReplacesDumps() {
+	fakeDir := s.T().TempDir()
+	s.T().Setenv("FAKE_PG_DIR", fakeDir)
+	commons.FakeBinary(s.T(), "psql", fakePsqlScript)
+	commons.FakeBinary(s.T(), "pg_dump", fakePgDumpScript)
+	commons.FakeBinary(s.T(), "pg_dumpall", fakePgDumpallScript)
+	setDatabases := func(names ...string) {
+		var list bytes.Buffer
+		for _, name := range names {
+			fmt.Fprintln(&list, name)
+		}
+		s.Require().NoError(os.WriteFile(filepath.Join(fakeDir, "databases"), list.Bytes(), 0o600))
+	}
+
+	directory := filepath.Join(s.T().TempDir(), "dumps")
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+pgdump:
+  options:
+    flags:
+      host: 127.0.0.1
+      username: postgres
+    allDatabases:
+      enabled: true
+      directory: %s
+      concurrency: 2
+`, directory))))
+	dumpedDatabases := func() []string {
+		dumps, err := databases.PostgresDumps(directory)
+		s.Require().NoError(err)
+		return databases.Names(dumps)
+	}
+
+	setDatabases("app", "my shop", "wiki")
+	s.Require().NoError(source.DoBackupForKind(context.Background(), "pgdump", false, false, false, false))
+	s.Equal([]string{"app", "my shop", "wiki"}, dumpedDatabases())
+
+	setDatabases("app", "blog")
+	s.Require().NoError(os.WriteFile(filepath.Join(fakeDir, "failing"), []byte("blog"), 0o600))
+	s.Require().Error(source.DoBackupForKind(context.Background(), "pgdump", false, false, false, false))
+	s.Equal([]string{"app", "my shop", "wiki"}, dumpedDatabases())
+	s.FileExists(filepath.Join(directory, databases.PostgresGlobalsFile))
+
+	s.Require().NoError(os.Remove(filepath.Join(fakeDir, "failing")))
+	s.Require().NoError(source.DoBackupForKind(context.Background(), "pgdump", false, false, false, false))
+	s.Equal([]string{"app", "blog"}, dumpedDatabases())
+	s.FileExists(filepath.Join(directory, databases.PostgresGlobalsFile))
+
+	entries, err := os.ReadDir(filepath.Dir(directory))
+	s.Require().NoError(err)
+	s.Len(entries, 1)
+}
@@ -0,0 +1,119 @@
+package pgdump_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/source/pgdump"
+	"github.com/mittwald/brudi/pkg/source/pgrestore"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+type PGOptionsSuite struct {
+	suite.Suite
+}
+
+func (s *PGOptionsSuite) SetupTest() {
+	commons.TestSetup()
+}
+
+func (s *PGOptionsSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// TestDirectoryFormatJobs tests that parallel jobs are accepted for the directory format only
+func (s *PGOptionsSuite) TestDirectoryFormatJobs() {
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(`
+pgdump:
+  options:
+    flags:
+      host: 127.0.0.1
+      dbName: app
+      format: directory
+      jobs: 4
+      file: /tmp/app
+`)))
+	backend, err := pgdump.NewConfigBasedBackend()
+	s.Require().NoError(err)
+	s.Equal("/tmp/app", backend.GetBackupPath())
+
+	viper.Set("pgdump.options.flags.format", "custom")
+	_, err = pgdump.NewConfigBasedBackend()
+	s.Error(err)
+}
+
+// TestDirectoryFormatSuffix tests that the directory format refuses compression and encryption suffixes
+func (s *PGOptionsSuite) TestDirectoryFormatSuffix() {
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(`
+pgdump:
+  options:
+    flags:
+      host: 127.0.0.1
+      format: d
+      file: /tmp/app.gz
+`)))
+	_, err := pgdump.NewConfigBasedBackend()
+	s.Error(err)
+}
+
+// TestAllDatabases tests the options of the all-databases mode
+func (s *PGOptionsSuite) TestAllDatabases() {
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(`
+pgdump:
+  options:
+    flags:
+      host: 127.0.0.1
+      format: custom
+    allDatabases:
+      enabled: true
+      directory: /tmp/postgres
+      exclude: ["*_test"]
+`)))
+	backend, err := pgdump.NewConfigBasedBackend()
+	s.Require().NoError(err)
+	s.Equal("/tmp/postgres", backend.GetBackupPath())
+
+	viper.Set("pgdump.options.flags.format", "directory")
+	_, err = pgdump.NewConfigBasedBackend()
+	s.Error(err)
+
+	viper.Set("pgdump.options.flags.format", "plain")
+	viper.Set("pgdump.options.allDatabases.exclude", []interface{}{"[app"})
+	_, err = pgdump.NewConfigBasedBackend()
+	s.Error(err)
+}
+
+// TestRestoreOptions tests the validation of the jobs and the all-databases mode of pgrestore
+func (s *PGOptionsSuite) TestRestoreOptions() {
+	s.Require().NoError(viper.ReadConfig(bytes.NewBufferString(`
+pgrestore:
+  options:
+    flags:
+      host: 127.0.0.1
+      dbname: app
+      format: directory
+      jobs: 4
+    sourcefile: /tmp/app
+`)))
+	backend, err := pgrestore.NewConfigBasedBackend()
+	s.Require().NoError(err)
+	s.True(backend.RestoresFromRestic())
+
+	viper.Set("pgrestore.options.flags.format", "tar")
+	_, err = pgrestore.NewConfigBasedBackend()
+	s.Error(err)
+
+	viper.Set("pgrestore.options.flags.format", "")
+	viper.Set("pgrestore.options.flags.create", true)
+	viper.Set("pgrestore.options.allDatabases.enabled", true)
+	viper.Set("pgrestore.options.allDatabases.directory", "/tmp/postgres")
+	_, err = pgrestore.NewConfigBasedBackend()
+	s.Error(err)
+}
+
+func TestPGOptionsSuite(t *testing.T) {
+	suite.Run(t, new(PGOptionsSuite))
+}
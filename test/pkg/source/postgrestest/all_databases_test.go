@@ -0,0 +1,129 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/pgrestore"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// allDatabasesRole is part of the globals, the dumps of the databases don't contain it
+const allDatabasesRole = "reporting"
+
+// allDatabases are dumped into separate files, "my/shop" has to be escaped in the name of its dump
+var allDatabases = []string{"app", "my/shop"}
+
+// TestAllDatabasesDumpAndRestore dumps the globals and several databases into separate files and restores them into
+// another server
+func (pgDumpAndRestoreTestSuite *PGDumpAndRestoreTestSuite) TestAllDatabasesDumpAndRestore() {
+	ctx := context.Background()
+	directory := filepath.Join(pgDumpAndRestoreTestSuite.T().TempDir(), "dumps")
+
+	backupTarget, err := commons.NewTestContainerSetup(ctx, &pgRequest, pgPort)
+	pgDumpAndRestoreTestSuite.Require().NoError(err)
+	defer func() {
+		if terminateErr := backupTarget.Container.Terminate(ctx); terminateErr != nil {
+			log.WithError(terminateErr).Error("failed to terminate pgdump backup container")
+		}
+	}()
+
+	db := openPG(pgDumpAndRestoreTestSuite, backupTarget, postgresDB)
+	defer db.Close()
+	_, err = db.Exec(fmt.Sprintf("CREATE ROLE %s NOLOGIN", allDatabasesRole))
+	pgDumpAndRestoreTestSuite.Require().NoError(err)
+	for _, database := range allDatabases {
+		_, err = db.Exec(fmt.Sprintf("CREATE DATABASE %q", database))
+		pgDumpAndRestoreTestSuite.Require().NoError(err)
+		databaseDB := openPG(pgDumpAndRestoreTestSuite, backupTarget, database)
+		_, err = databaseDB.Exec(fmt.Sprintf("CREATE TABLE %s(id serial PRIMARY KEY, name VARCHAR(100) NOT NULL)", tableName))
+		pgDumpAndRestoreTestSuite.Require().NoError(err)
+		_, err = databaseDB.Exec(fmt.Sprintf("INSERT INTO %s (name) VALUES ($1)", tableName), database)
+		pgDumpAndRestoreTestSuite.Require().NoError(err)
+		_, err = databaseDB.Exec(fmt.Sprintf("GRANT SELECT ON %s TO %s", tableName, allDatabasesRole))
+		pgDumpAndRestoreTestSuite.Require().NoError(err)
+		pgDumpAndRestoreTestSuite.Require().NoError(databaseDB.Close())
+	}
+
+	pgDumpAndRestoreTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createAllDatabasesConfig(backupTarget, directory))))
+	pgDumpAndRestoreTestSuite.Require().NoError(source.DoBackupForKind(ctx, dumpKind, false, false, false, false))
+
+	restoreTarget, err := commons.NewTestContainerSetup(ctx, &pgRequest, pgPort)
+	pgDumpAndRestoreTestSuite.Require().NoError(err)
+	defer func() {
+		if terminateErr := restoreTarget.Container.Terminate(ctx); terminateErr != nil {
+			log.WithError(terminateErr).Error("failed to terminate pgdump restore container")
+		}
+	}()
+	restored := openPG(pgDumpAndRestoreTestSuite, restoreTarget, postgresDB)
+	defer restored.Close()
+
+	commons.TestSetup()
+	pgDumpAndRestoreTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createAllDatabasesConfig(restoreTarget, directory))))
+	pgDumpAndRestoreTestSuite.Require().NoError(source.DoRestoreForKind(ctx, pgrestore.Kind, false, false, false))
+
+	var roles int
+	err = restored.QueryRow("SELECT count(*) FROM pg_roles WHERE rolname = $1", allDatabasesRole).Scan(&roles)
+	pgDumpAndRestoreTestSuite.Require().NoError(err)
+	pgDumpAndRestoreTestSuite.Equal(1, roles)
+	for _, database := range allDatabases {
+		databaseDB := openPG(pgDumpAndRestoreTestSuite, restoreTarget, database)
+		var name string
+		err = databaseDB.QueryRow(fmt.Sprintf("SELECT name FROM %s", tableName)).Scan(&name)
+		pgDumpAndRestoreTestSuite.Require().NoError(err, database)
+		pgDumpAndRestoreTestSuite.Equal(database, name)
+		pgDumpAndRestoreTestSuite.Require().NoError(databaseDB.Close())
+	}
+}
+
+// openPG connects to the given database of the container and waits until it accepts connections
+func openPG(pgDumpAndRestoreTestSuite *PGDumpAndRestoreTestSuite, target commons.TestContainerSetup, database string) *sql.DB {
+	db, err := sql.Open(dbDriver, fmt.Sprintf(
+		"user=%s password=%s host=%s port=%s database='%s' sslmode=disable", postgresUser,
+		postgresPW, target.Address, target.Port, database,
+	))
+	pgDumpAndRestoreTestSuite.Require().NoError(err)
+	for ok := true; ok; ok = db.Ping() != nil {
+		time.Sleep(1 * time.Second)
+	}
+	return db
+}
+
+// createAllDatabasesConfig creates a brudi config dumping and restoring the globals and the test databases
+func createAllDatabasesConfig(container commons.TestContainerSetup, directory string) []byte {
+	return []byte(fmt.Sprintf(`
+pgdump:
+  options:
+    flags:
+      host: %[1]s
+      port: %[2]s
+      password: %[3]s
+      username: %[4]s
+      format: custom
+    allDatabases:
+      enabled: true
+      directory: %[5]s
+      suffix: .gz
+      include: [app, my/shop]
+      concurrency: 2
+pgrestore:
+  options:
+    flags:
+      host: %[1]s
+      port: %[2]s
+      password: %[3]s
+      username: %[4]s
+    allDatabases:
+      enabled: true
+      directory: %[5]s
+      globals: true
+`, hostName, container.Port, postgresPW, postgresUser, directory))
+}
@@ -469,12 +469,12 @@ func pgDoRestore(
 
 	// use correct restoration function based on backup format
 	if format == "plain" {
-		psqlErr := source.DoRestoreForKind(ctx, psql.Kind, false, useRestic)
+		psqlErr := source.DoRestoreForKind(ctx, psql.Kind, false, useRestic, false)
 		if psqlErr != nil {
 			return []testStruct{}, psqlErr
 		}
 	} else {
-		pgErr := source.DoRestoreForKind(ctx, pgrestore.Kind, false, useRestic)
+		pgErr := source.DoRestoreForKind(ctx, pgrestore.Kind, false, useRestic, false)
 		if pgErr != nil {
 			return []testStruct{}, pgErr
 		}
@@ -512,6 +512,12 @@ func createPGConfig(
 	useRestic bool,
 	resticIP, resticPort, format, path string,
 ) []byte {
+	// dumps in directory format are created and restored with parallel jobs
+	var jobsConfig string
+	if format == "d" {
+		jobsConfig = "jobs: 2"
+	}
+
 	var restoreConfig string
 	if format != plainKind {
 		restoreConfig = fmt.Sprintf(
@@ -523,9 +529,11 @@ func createPGConfig(
       password: %s
       username: %s
       dbname: %s
+      format: %s
+      %s
     additionalArgs: []
     sourcefile: %s
-`, hostName, container.Port, postgresPW, postgresUser, postgresDB, path,
+`, hostName, container.Port, postgresPW, postgresUser, postgresDB, format, jobsConfig, path,
 		)
 	} else {
 		restoreConfig = fmt.Sprintf(
@@ -578,11 +586,12 @@ pgdump:
       dbName: %s
       file: %s
       format: %s
+      %s
     additionalArgs: []
 %s
 %s
 
-`, hostName, container.Port, postgresPW, postgresUser, postgresDB, path, format, restoreConfig, resticConfig,
+`, hostName, container.Port, postgresPW, postgresUser, postgresDB, path, format, jobsConfig, restoreConfig, resticConfig,
 	))
 	return result
 }
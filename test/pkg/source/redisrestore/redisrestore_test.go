@@ -0,0 +1,132 @@
+package redisrestore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/dumpfile"
+	"github.com/mittwald/brudi/pkg/rdb"
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/redisrestore"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+type RedisRestoreTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (redisRestoreTestSuite *RedisRestoreTestSuite) SetupTest() {
+	commons.TestSetup()
+	redisRestoreTestSuite.dir = redisRestoreTestSuite.T().TempDir()
+}
+
+// TearDownTest resets viper after a test
+func (redisRestoreTestSuite *RedisRestoreTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// rdbFile returns an rdb file with a single string key
+func rdbFile() []byte {
+	file := []byte("REDIS0011\xfe\x00\x00\x05brudi\x06backup\xff")
+	return binary.LittleEndian.AppendUint64(file, rdb.CRC64(0, file))
+}
+
+// writeSource writes the given content gzip compressed to the source file of the restore
+func (redisRestoreTestSuite *RedisRestoreTestSuite) writeSource(content []byte) string {
+	sourceFile := filepath.Join(redisRestoreTestSuite.dir, "redisdump.rdb.gz")
+	err := dumpfile.Write(sourceFile, func(w io.Writer) error {
+		_, writeErr := w.Write(content)
+		return writeErr
+	})
+	redisRestoreTestSuite.Require().NoError(err)
+	return sourceFile
+}
+
+func (redisRestoreTestSuite *RedisRestoreTestSuite) readConfig(sourceFile, dataDir string) {
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    sourceFile: %s
+    mode: file
+    dataDir: %s
+    owner: "%d:%d"
+`, redisrestore.Kind, sourceFile, dataDir, os.Getuid(), os.Getgid())))
+	redisRestoreTestSuite.Require().NoError(err)
+}
+
+// TestRestoreFile tests that compressed dumps are placed uncompressed into the data directory
+func (redisRestoreTestSuite *RedisRestoreTestSuite) TestRestoreFile() {
+	dataDir := filepath.Join(redisRestoreTestSuite.dir, "data")
+	redisRestoreTestSuite.Require().NoError(os.Mkdir(dataDir, 0o700))
+	redisRestoreTestSuite.readConfig(redisRestoreTestSuite.writeSource(rdbFile()), dataDir)
+
+	err := source.DoRestoreForKind(context.Background(), redisrestore.Kind, false, false, false)
+	redisRestoreTestSuite.Require().NoError(err)
+
+	restored, err := os.ReadFile(filepath.Join(dataDir, "dump.rdb"))
+	redisRestoreTestSuite.Require().NoError(err)
+	redisRestoreTestSuite.Equal(rdbFile(), restored)
+}
+
+// TestRestoreFileCorrupted tests that corrupted dumps aren't placed into the data directory
+func (redisRestoreTestSuite *RedisRestoreTestSuite) TestRestoreFileCorrupted() {
+	corrupted := rdbFile()
+	corrupted[15] = 'B'
+	dataDir := filepath.Join(redisRestoreTestSuite.dir, "data")
+	redisRestoreTestSuite.Require().NoError(os.Mkdir(dataDir, 0o700))
+	redisRestoreTestSuite.readConfig(redisRestoreTestSuite.writeSource(corrupted), dataDir)
+
+	err := source.DoRestoreForKind(context.Background(), redisrestore.Kind, false, false, false)
+	redisRestoreTestSuite.Error(err)
+	redisRestoreTestSuite.NoFileExists(filepath.Join(dataDir, "dump.rdb"))
+}
+
+// TestReplayUnsupportedNotFlushed tests that the server isn't flushed if a key of the dump can't be replayed
+func (redisRestoreTestSuite *RedisRestoreTestSuite) TestReplayUnsupportedNotFlushed() {
+	received := filepath.Join(redisRestoreTestSuite.dir, "received")
+	commons.FakeBinary(redisRestoreTestSuite.T(), "redis-cli", fmt.Sprintf("#!/bin/sh\ncat > %s\n", received))
+
+	// a valid key followed by a key of an unknown value type
+	file := []byte("REDIS0011\xfe\x00\x00\x05brudi\x06backup\x63\x03key\x05value\xff")
+	file = binary.LittleEndian.AppendUint64(file, rdb.CRC64(0, file))
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    flags:
+      host: 127.0.0.1
+    sourceFile: %s
+    flush: true
+`, redisrestore.Kind, redisRestoreTestSuite.writeSource(file))))
+	redisRestoreTestSuite.Require().NoError(err)
+
+	err = source.DoRestoreForKind(context.Background(), redisrestore.Kind, false, false, false)
+	redisRestoreTestSuite.ErrorIs(err, rdb.ErrUnsupported)
+	redisRestoreTestSuite.NoFileExists(received)
+}
+
+// TestReplayRequiresHost tests that replaying requires a connection
+func (redisRestoreTestSuite *RedisRestoreTestSuite) TestReplayRequiresHost() {
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    sourceFile: /tmp/redisdump.rdb
+`, redisrestore.Kind)))
+	redisRestoreTestSuite.Require().NoError(err)
+
+	_, err = redisrestore.NewConfigBasedBackend()
+	redisRestoreTestSuite.Error(err)
+}
+
+func TestRedisRestoreTestSuite(t *testing.T) {
+	suite.Run(t, new(RedisRestoreTestSuite))
+}
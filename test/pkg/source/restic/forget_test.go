@@ -0,0 +1,41 @@
+package restic_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// TestForgetConfiguredDryRun tests that a dry-run configured for forget isn't overridden by the command line
+func TestForgetConfiguredDryRun(t *testing.T) {
+	fake := commons.NewFakeRestic(t)
+
+	commons.TestSetup()
+	defer viper.Reset()
+	require.NoError(t, viper.ReadConfig(bytes.NewBufferString(fake.Config(`
+  forget:
+    flags:
+      keepLast: 1
+      dryRun: true
+      prune: true
+`))))
+
+	var report bytes.Buffer
+	require.NoError(t, source.DoForgetForKind(context.Background(), "tar", false, false, source.ReportFormatJSON, &report))
+
+	var forget []string
+	for _, args := range fake.Calls(t) {
+		require.NotContains(t, args, "prune")
+		if slices.Contains(args, "forget") {
+			forget = args
+		}
+	}
+	require.Contains(t, forget, "-n")
+}
@@ -0,0 +1,29 @@
+package restic_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// TestInitRace tests that a repository initialized by another job is detected by the exit code of restic
+func TestInitRace(t *testing.T) {
+	fake := commons.NewFakeRestic(t)
+	fake.InitializeConcurrently(t)
+
+	commons.TestSetup()
+	defer viper.Reset()
+	require.NoError(t, viper.ReadConfig(bytes.NewBufferString(fake.Config(""))))
+
+	require.NoError(t, source.DoBackupForKind(context.Background(), "tar", false, true, false, false))
+	require.True(t, slices.ContainsFunc(fake.Calls(t), func(args []string) bool {
+		return slices.Contains(args, "init")
+	}))
+}
@@ -0,0 +1,35 @@
+package restic_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// TestRemoveStaleLocks tests that only the stale locks of a local repository are removed, by their ID
+func TestRemoveStaleLocks(t *testing.T) {
+	fake := commons.NewFakeRestic(t)
+	fake.WriteLock(t, "stale", 2*time.Hour, true)
+	fake.WriteLock(t, "active", time.Minute, false)
+
+	commons.TestSetup()
+	defer viper.Reset()
+	require.NoError(t, viper.ReadConfig(bytes.NewBufferString(fake.Config(`
+  locks:
+    maxAge: 1h
+    pollInterval: 10ms
+    waitTimeout: 1s
+`))))
+
+	require.NoError(t, source.DoBackupForKind(context.Background(), "tar", false, true, false, false))
+	require.NoFileExists(t, filepath.Join(fake.Repo, "locks", "stale"))
+	require.FileExists(t, filepath.Join(fake.Repo, "locks", "active"))
+}
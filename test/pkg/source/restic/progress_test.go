@@ -0,0 +1,45 @@
+package restic_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mittwald/brudi/pkg/restic"
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// TestProgressHandlers tests that registered handlers receive the parsed status and summary of "restic backup"
+func TestProgressHandlers(t *testing.T) {
+	fake := commons.NewFakeRestic(t)
+
+	commons.TestSetup()
+	defer viper.Reset()
+	require.NoError(t, viper.ReadConfig(bytes.NewBufferString(fake.Config(`
+  progress:
+    interval: 1s
+`))))
+
+	var statuses []*restic.BackupStatus
+	var summaries []*restic.BackupSummary
+	source.RegisterProgressHandler(func(status *restic.BackupStatus) {
+		statuses = append(statuses, status)
+	})
+	source.RegisterSummaryHandler(func(summary *restic.BackupSummary) {
+		summaries = append(summaries, summary)
+	})
+	defer source.ResetHandlers()
+
+	require.NoError(t, source.DoBackupForKind(context.Background(), "tar", false, true, false, false))
+
+	require.Len(t, statuses, 1)
+	require.InDelta(t, 0.5, statuses[0].PercentDone, 0.001)
+	require.Equal(t, uint64(10), statuses[0].BytesDone)
+	require.Len(t, summaries, 1)
+	require.Equal(t, "f00ba7", summaries[0].SnapshotID)
+	require.Equal(t, uint64(20), summaries[0].DataAdded)
+}
@@ -0,0 +1,41 @@
+package restic_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mittwald/brudi/pkg/source"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+// TestCleanUpWorkspaceOfResticRestorer tests that --cleanup removes the workspace, although the backend restored
+// from restic itself, like pgrestore does for dumps in directory format
+func TestCleanUpWorkspaceOfResticRestorer(t *testing.T) {
+	fake := commons.NewFakeRestic(t)
+	commons.FakeBinary(t, "pg_restore", "#!/bin/sh\nexit 0\n")
+	workspace := filepath.Join(fake.Dir, "workspace")
+
+	commons.TestSetup()
+	defer viper.Reset()
+	require.NoError(t, viper.ReadConfig(bytes.NewBufferString(fake.Config(fmt.Sprintf(`
+  restore:
+    workspace: %s
+pgrestore:
+  options:
+    flags:
+      host: 127.0.0.1
+      dbname: app
+      format: d
+    sourceFile: %s
+  hostName: fake-restic
+`, workspace, filepath.Join(fake.Dir, "dump"))))))
+
+	require.NoError(t, source.DoRestoreForKind(context.Background(), "pgrestore", true, true, false))
+	require.NoDirExists(t, workspace)
+}
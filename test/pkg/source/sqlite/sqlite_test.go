@@ -0,0 +1,131 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/sqlitedump"
+	"github.com/mittwald/brudi/pkg/source/sqliterestore"
+	commons "github.com/mittwald/brudi/test/pkg/source/internal"
+)
+
+type SQLiteTestSuite struct {
+	suite.Suite
+	dir      string
+	database string
+}
+
+func (sqliteTestSuite *SQLiteTestSuite) SetupTest() {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		sqliteTestSuite.T().Skip("sqlite3 is not installed")
+	}
+	commons.TestSetup()
+
+	sqliteTestSuite.dir = sqliteTestSuite.T().TempDir()
+	sqliteTestSuite.database = filepath.Join(sqliteTestSuite.dir, "app.db")
+	sqliteTestSuite.sqlite(sqliteTestSuite.database,
+		"PRAGMA journal_mode=WAL;"+
+			"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);"+
+			"INSERT INTO users (name) VALUES ('brudi'), ('mittwald'), ('it''s quoted');",
+	)
+}
+
+// TearDownTest resets viper after a test
+func (sqliteTestSuite *SQLiteTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// sqlite runs the given statements on the given database and returns the output
+func (sqliteTestSuite *SQLiteTestSuite) sqlite(database, statements string) string {
+	out, err := exec.Command("sqlite3", database, statements).CombinedOutput()
+	sqliteTestSuite.Require().NoError(err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// backupAndRestore backs up the test database in the given mode and restores it into the given database
+func (sqliteTestSuite *SQLiteTestSuite) backupAndRestore(mode, backupFile, restoreDatabase string) {
+	ctx := context.Background()
+	backupFile = filepath.Join(sqliteTestSuite.dir, backupFile)
+
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    database: %s
+    file: %s
+    mode: %s
+  hostName: sqlitetest
+%s:
+  options:
+    file: %s
+    database: %s
+  hostName: sqlitetest
+`, sqlitedump.Kind, sqliteTestSuite.database, backupFile, mode,
+		sqliterestore.Kind, backupFile, restoreDatabase)))
+	sqliteTestSuite.Require().NoError(err)
+
+	sqliteTestSuite.Require().NoError(source.DoBackupForKind(ctx, sqlitedump.Kind, false, false, false, false))
+	sqliteTestSuite.FileExists(backupFile)
+	sqliteTestSuite.Require().NoError(source.DoRestoreForKind(ctx, sqliterestore.Kind, false, false, false))
+}
+
+// TestBackupAPI tests a gzip compressed copy created with the online backup API
+func (sqliteTestSuite *SQLiteTestSuite) TestBackupAPI() {
+	restored := filepath.Join(sqliteTestSuite.dir, "restored.db")
+	sqliteTestSuite.backupAndRestore(sqlitedump.ModeBackup, "app.db.gz", restored)
+
+	sqliteTestSuite.NoFileExists(filepath.Join(sqliteTestSuite.dir, "app.db.gz.tmp"))
+	sqliteTestSuite.Equal("3", sqliteTestSuite.sqlite(restored, "SELECT count(*) FROM users;"))
+}
+
+// TestVacuumInto tests a plain copy created with "VACUUM INTO"
+func (sqliteTestSuite *SQLiteTestSuite) TestVacuumInto() {
+	restored := filepath.Join(sqliteTestSuite.dir, "restored.db")
+	sqliteTestSuite.backupAndRestore(sqlitedump.ModeVacuum, "copy.db", restored)
+
+	sqliteTestSuite.Equal("it's quoted", sqliteTestSuite.sqlite(restored, "SELECT name FROM users WHERE id = 3;"))
+}
+
+// TestDumpReplacesDatabase tests that a compressed SQL export replaces an existing database
+func (sqliteTestSuite *SQLiteTestSuite) TestDumpReplacesDatabase() {
+	restored := filepath.Join(sqliteTestSuite.dir, "restored.db")
+	sqliteTestSuite.sqlite(restored, "CREATE TABLE stale (id INTEGER);")
+
+	sqliteTestSuite.backupAndRestore(sqlitedump.ModeDump, "app.sql.zst", restored)
+
+	sqliteTestSuite.Equal("3", sqliteTestSuite.sqlite(restored, "SELECT count(*) FROM users;"))
+	sqliteTestSuite.Equal("users", sqliteTestSuite.sqlite(restored, "SELECT name FROM sqlite_master WHERE type = 'table';"))
+	sqliteTestSuite.NoFileExists(restored + ".import.tmp")
+
+	// the uncompressed export is removed right after the restore
+	plain, err := filepath.Glob(filepath.Join(sqliteTestSuite.dir, ".app.sql.*"))
+	sqliteTestSuite.Require().NoError(err)
+	sqliteTestSuite.Empty(plain)
+	sqliteTestSuite.NoFileExists(filepath.Join(sqliteTestSuite.dir, "app.sql"))
+}
+
+// TestFileMustDifferFromDatabase tests that the database can't be overwritten by its backup
+func (sqliteTestSuite *SQLiteTestSuite) TestFileMustDifferFromDatabase() {
+	err := viper.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+%s:
+  options:
+    database: %s
+    file: %s
+`, sqlitedump.Kind, sqliteTestSuite.database, sqliteTestSuite.database)))
+	sqliteTestSuite.Require().NoError(err)
+
+	_, err = sqlitedump.NewConfigBasedBackend()
+	sqliteTestSuite.Error(err)
+}
+
+func TestSQLiteTestSuite(t *testing.T) {
+	suite.Run(t, new(SQLiteTestSuite))
+}
@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/mittwald/brudi/pkg/archive"
 	"github.com/mittwald/brudi/pkg/source"
+	"github.com/mittwald/brudi/pkg/source/tar"
+	"github.com/mittwald/brudi/pkg/source/tarrestore"
 	commons "github.com/mittwald/brudi/test/pkg/source/internal"
 
 	"github.com/pkg/errors"
@@ -69,6 +73,117 @@ func (tarTestSuite *TarTestSuite) TestBasicTarDump() {
 	assert.Equal(tarTestSuite.T(), initialHash, restoredHash)
 }
 
+// TestIncrementalChain tests that chains are rolled over and restored up to the chosen level
+func (tarTestSuite *TarTestSuite) TestIncrementalChain() {
+	ctx := context.Background()
+	dir := tarTestSuite.T().TempDir()
+	dataFile := filepath.Join(dir, "source", "data.txt")
+	tarTestSuite.Require().NoError(os.MkdirAll(filepath.Dir(dataFile), 0o755))
+
+	for _, content := range []string{"full", "incremental", "next chain"} {
+		tarTestSuite.Require().NoError(os.WriteFile(dataFile, []byte(content), 0o644))
+		commons.TestSetup()
+		tarTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createIncrementalConfig(dir, "", -1))))
+		tarTestSuite.Require().NoError(source.DoBackupForKind(ctx, "tar", false, false, false, false))
+	}
+
+	archives, err := archive.IncrementalArchives(filepath.Join(dir, "data.tar.gz"))
+	tarTestSuite.Require().NoError(err)
+	tarTestSuite.Require().Len(archives, 3)
+	tarTestSuite.Equal([]int{0, 1, 0}, []int{archives[0].Level, archives[1].Level, archives[2].Level})
+	tarTestSuite.Equal(archives[0].Chain, archives[1].Chain)
+	tarTestSuite.NotEqual(archives[0].Chain, archives[2].Chain)
+	tarTestSuite.FileExists(filepath.Join(dir, "data.tar.gz.snar"))
+
+	for _, tc := range []struct {
+		chain    string
+		level    int
+		expected string
+	}{
+		{chain: archives[0].Chain, level: 0, expected: "full"},
+		{chain: archives[0].Chain, level: -1, expected: "incremental"},
+		{chain: "", level: -1, expected: "next chain"},
+	} {
+		commons.TestSetup()
+		tarTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createIncrementalConfig(dir, tc.chain, tc.level))))
+		tarTestSuite.Require().NoError(source.DoRestoreForKind(ctx, "tarrestore", false, false, false))
+
+		restored, readErr := os.ReadFile(filepath.Join(dir, "restored", "source", "data.txt"))
+		tarTestSuite.Require().NoError(readErr)
+		tarTestSuite.Equal(tc.expected, string(restored))
+		tarTestSuite.Require().NoError(os.RemoveAll(filepath.Join(dir, "restored")))
+	}
+
+	commons.TestSetup()
+	tarTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createIncrementalConfig(dir, archives[2].Chain, 1))))
+	tarTestSuite.Error(source.DoRestoreForKind(ctx, "tarrestore", false, false, false))
+}
+
+// TestIncrementalChainStaysComplete tests that the snapshot is only advanced once the run succeeded
+// and that chains can't be shipped with restic or removed by cleanup
+func (tarTestSuite *TarTestSuite) TestIncrementalChainStaysComplete() {
+	ctx := context.Background()
+	dir := tarTestSuite.T().TempDir()
+	dataFile := filepath.Join(dir, "source", "data.txt")
+	tarTestSuite.Require().NoError(os.MkdirAll(filepath.Dir(dataFile), 0o755))
+	tarTestSuite.Require().NoError(os.WriteFile(dataFile, []byte("full"), 0o644))
+	snapshotFile := filepath.Join(dir, "data.tar.gz.snar")
+
+	commons.TestSetup()
+	tarTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createIncrementalConfig(dir, "", -1))))
+	backend, err := tar.NewConfigBasedBackend()
+	tarTestSuite.Require().NoError(err)
+	tarTestSuite.Require().NoError(backend.CreateBackup(ctx))
+	tarTestSuite.FileExists(backend.GetBackupPath())
+	tarTestSuite.NoFileExists(snapshotFile)
+	tarTestSuite.Require().NoError(backend.FinishBackup())
+	tarTestSuite.FileExists(snapshotFile)
+
+	tarTestSuite.ErrorContains(source.DoBackupForKind(ctx, "tar", true, false, false, false), "--cleanup")
+	tarTestSuite.ErrorContains(source.DoBackupForKind(ctx, "tar", false, true, false, false), "--restic")
+	tarTestSuite.ErrorContains(source.DoRestoreForKind(ctx, "tarrestore", false, true, false), "aren't stored in restic")
+	archives, err := archive.IncrementalArchives(filepath.Join(dir, "data.tar.gz"))
+	tarTestSuite.Require().NoError(err)
+	tarTestSuite.Len(archives, 1)
+}
+
+// TestRemovedOptions tests that options of the tar binary are mapped onto the native archiver or rejected by name
+func (tarTestSuite *TarTestSuite) TestRemovedOptions() {
+	for _, tc := range []struct {
+		name    string
+		config  string
+		removed string
+	}{
+		{name: "create", config: "tar:\n  options:\n    flags:\n      create: true\n      gzip: true"},
+		{name: "empty additionalArgs", config: "tar:\n  options:\n    additionalArgs: []"},
+		{name: "additionalArgs", config: "tar:\n  options:\n    additionalArgs: [--acls]", removed: "tar.options.additionalArgs"},
+		{name: "extract", config: "tar:\n  options:\n    flags:\n      extract: true", removed: "tar.options.flags.extract"},
+		{name: "restore extract", config: "tarrestore:\n  options:\n    flags:\n      extract: true\n      gzip: true\n      overwrite: true"},
+		{name: "restore create", config: "tarrestore:\n  options:\n    flags:\n      create: true", removed: "tarrestore.options.flags.create"},
+		{name: "restore noOverwriteDir", config: "tarrestore:\n  options:\n    flags:\n      noOverwriteDir: true", removed: "tarrestore.options.flags.noOverwriteDir"},
+		{
+			name:    "restore overwrite and keepOldFiles",
+			config:  "tarrestore:\n  options:\n    flags:\n      overwrite: true\n      keepOldFiles: true",
+			removed: "tarrestore.options.flags.overwrite",
+		},
+	} {
+		commons.TestSetup()
+		tarTestSuite.Require().NoError(viper.ReadConfig(bytes.NewBuffer(createTarConfig())))
+		tarTestSuite.Require().NoError(viper.MergeConfig(bytes.NewBufferString(tc.config)), tc.name)
+
+		_, err := tar.NewConfigBasedBackend()
+		if err == nil {
+			_, err = tarrestore.NewConfigBasedBackend()
+		}
+		if tc.removed == "" {
+			tarTestSuite.NoError(err, tc.name)
+			continue
+		}
+		tarTestSuite.Require().Error(err, tc.name)
+		tarTestSuite.Contains(err.Error(), tc.removed, tc.name)
+	}
+}
+
 func TestTarTestSuite(t *testing.T) {
 	suite.Run(t, new(TarTestSuite))
 }
@@ -86,7 +201,7 @@ func tarDoBackup(ctx context.Context) (string, error) {
 
 // tarDoRestore uses brudi to restore a backup from tar and returns its md5 checksum for verification
 func tarDoRestore(ctx context.Context) (string, error) {
-	err := source.DoRestoreForKind(ctx, "tarrestore", false, false)
+	err := source.DoRestoreForKind(ctx, "tarrestore", false, false, false)
 	if err != nil {
 		return "", err
 	}
@@ -126,22 +241,45 @@ func createTarConfig() []byte {
 tar:
   options:
     flags:
-      create: true
-      gzip: true
       file: %s
-    additionalArgs: []
     paths: 
       - %s
-    hostName: autoGeneratedIfEmpty
+  hostName: autoGeneratedIfEmpty
 tarrestore:
   options:
     flags:
-      extract: true
-      gzip: true
       file: %s
       target: "/tmp"
-    additionalArgs: []
   hostName: autoGeneratedIfEmpty
 `, targetPath, backupPath, targetPath,
 	))
 }
+
+// createIncrementalConfig creates a brudi config for incremental chains of at most one incremental archive
+func createIncrementalConfig(dir, chain string, level int) []byte {
+	return []byte(fmt.Sprintf(
+		`
+tar:
+  options:
+    flags:
+      file: %[1]s/data.tar.gz
+      target: %[1]s
+    paths:
+      - source
+    incremental:
+      enabled: true
+      maxIncrementals: 1
+  hostName: autoGeneratedIfEmpty
+tarrestore:
+  options:
+    flags:
+      file: %[1]s/data.tar.gz
+      target: %[1]s/restored
+    incremental:
+      enabled: true
+      chain: "%[2]s"
+      level: %[3]d
+  hostName: autoGeneratedIfEmpty
+`, dir, chain, level,
+	))
+}
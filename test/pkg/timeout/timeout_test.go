@@ -0,0 +1,106 @@
+package testtimeout
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/retry"
+	"github.com/mittwald/brudi/pkg/timeout"
+)
+
+type TimeoutTestSuite struct {
+	suite.Suite
+}
+
+func (timeoutTestSuite *TimeoutTestSuite) SetupTest() {
+	viper.Reset()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+}
+
+func (timeoutTestSuite *TimeoutTestSuite) TearDownTest() {
+	viper.Reset()
+}
+
+// waitForContext blocks until the given context is done, like a hanging command
+func waitForContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestStageTimeoutIsNamed checks that a stage exceeding its timeout returns an error naming the stage
+func (timeoutTestSuite *TimeoutTestSuite) TestStageTimeoutIsNamed() {
+	viper.Set("timeout.dump", "10ms")
+
+	err := timeout.RunWithConfig(context.Background(), func(ctx context.Context) error {
+		return timeout.Run(ctx, retry.StageDump, waitForContext)
+	})
+
+	var timeoutErr *timeout.Error
+	timeoutTestSuite.Require().True(errors.As(err, &timeoutErr))
+	timeoutTestSuite.Assert().ErrorIs(err, timeout.ErrTimeout)
+	timeoutTestSuite.Assert().Equal(retry.StageDump, timeoutErr.Stage)
+	timeoutTestSuite.Assert().Equal(10*time.Millisecond, timeoutErr.Timeout)
+	timeoutTestSuite.Assert().Contains(err.Error(), "stage 'dump' exceeded its timeout of 10ms")
+}
+
+// TestInnerStageTimeout checks that the timeout of a nested stage isn't attributed to the outer stage
+func (timeoutTestSuite *TimeoutTestSuite) TestInnerStageTimeout() {
+	viper.Set("timeout.compress", "10ms")
+
+	err := timeout.RunWithConfig(context.Background(), func(ctx context.Context) error {
+		return timeout.Run(ctx, retry.StageDump, func(ctx context.Context) error {
+			return timeout.Run(ctx, timeout.StageCompress, waitForContext)
+		})
+	})
+
+	var timeoutErr *timeout.Error
+	timeoutTestSuite.Require().True(errors.As(err, &timeoutErr))
+	timeoutTestSuite.Assert().Equal(timeout.StageCompress, timeoutErr.Stage)
+}
+
+// TestRunDeadline checks that the overall run deadline is reported if it is exceeded before the stage's timeout
+func (timeoutTestSuite *TimeoutTestSuite) TestRunDeadline() {
+	viper.Set("timeout.run", "10ms")
+
+	err := timeout.RunWithConfig(context.Background(), func(ctx context.Context) error {
+		return timeout.Run(ctx, retry.StageBackup, waitForContext)
+	})
+
+	var timeoutErr *timeout.Error
+	timeoutTestSuite.Require().True(errors.As(err, &timeoutErr))
+	timeoutTestSuite.Assert().Equal(timeout.StageRun, timeoutErr.Stage)
+}
+
+// TestErrorsArePassedThrough checks that errors of stages within their timeout are returned unchanged
+func (timeoutTestSuite *TimeoutTestSuite) TestErrorsArePassedThrough() {
+	stageErr := errors.New("access denied")
+
+	err := timeout.RunWithConfig(context.Background(), func(ctx context.Context) error {
+		return timeout.Run(ctx, retry.StageDump, func(ctx context.Context) error {
+			return stageErr
+		})
+	})
+
+	timeoutTestSuite.Assert().Equal(stageErr, err)
+}
+
+// TestDefaults checks that every stage but the run itself is limited to six hours by default
+func (timeoutTestSuite *TimeoutTestSuite) TestDefaults() {
+	cfg, err := timeout.NewConfig()
+	timeoutTestSuite.Require().NoError(err)
+
+	timeoutTestSuite.Assert().Equal(time.Duration(0), cfg.For(timeout.StageRun))
+	timeoutTestSuite.Assert().Equal(6*time.Hour, cfg.For(retry.StageBackup))
+	timeoutTestSuite.Assert().Equal(6*time.Hour, cfg.For(timeout.StageCompress))
+}
+
+func TestTimeoutTestSuite(t *testing.T) {
+	suite.Run(t, new(TimeoutTestSuite))
+}
@@ -0,0 +1,126 @@
+package testvalidate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/mittwald/brudi/pkg/validate"
+)
+
+type ValidateTestSuite struct {
+	suite.Suite
+}
+
+// crc64Jones is a bitwise implementation of the CRC64 used by redis to compute expected checksums independently
+func crc64Jones(p []byte) uint64 {
+	var crc uint64
+	for _, b := range p {
+		crc ^= uint64(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ 0x95ac9329ac4bc9b5
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// rdb returns an rdb file of the given version with a single string key, terminated with the given checksum
+func rdb(version string, checksum func(content []byte) uint64) []byte {
+	content := []byte("REDIS" + version)
+	// select db 0, followed by key "brudi" with value "backup"
+	content = append(content, 0xFE, 0x00, 0x00, 0x05)
+	content = append(content, "brudi"...)
+	content = append(content, 0x06)
+	content = append(content, "backup"...)
+	content = append(content, 0xFF)
+	if checksum == nil {
+		return content
+	}
+	return binary.LittleEndian.AppendUint64(content, checksum(content))
+}
+
+// bsonDocument returns a BSON document with a single string field
+func bsonDocument(value string) []byte {
+	body := []byte{0x02}
+	body = append(body, "name\x00"...)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(value)+1))
+	body = append(body, value...)
+	body = append(body, 0x00, 0x00)
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(body)+4)), body...)
+}
+
+func (validateTestSuite *ValidateTestSuite) TestCRC64TestVector() {
+	validateTestSuite.Equal(uint64(0xe9c6d914c4b8d9ca), crc64Jones([]byte("123456789")))
+}
+
+func (validateTestSuite *ValidateTestSuite) TestMySQLDump() {
+	complete := "-- MariaDB dump\nINSERT INTO test VALUES (1);\n-- Dump completed on 2026-10-19  8:00:00\n"
+	validateTestSuite.NoError(validate.MySQLDump(bytes.NewBufferString(complete)))
+
+	truncated := "-- MariaDB dump\n" + string(bytes.Repeat([]byte("INSERT INTO test VALUES (1);\n"), 1000))
+	err := validate.MySQLDump(bytes.NewBufferString(truncated))
+	validateTestSuite.True(errors.Is(err, validate.ErrInvalidDump))
+}
+
+func (validateTestSuite *ValidateTestSuite) TestPostgresPlainDump() {
+	validateTestSuite.NoError(validate.PostgresPlainDump(bytes.NewBufferString("CREATE TABLE test ();\n--\n-- PostgreSQL database dump complete\n--\n")))
+	validateTestSuite.Error(validate.PostgresPlainDump(bytes.NewBufferString("CREATE TABLE test ();\n")))
+}
+
+func (validateTestSuite *ValidateTestSuite) TestPostgresClusterDump() {
+	validateTestSuite.NoError(validate.PostgresClusterDump(bytes.NewBufferString("CREATE ROLE test;\n--\n-- PostgreSQL database cluster dump complete\n--\n\n")))
+	validateTestSuite.Error(validate.PostgresClusterDump(bytes.NewBufferString("CREATE ROLE test;\n--\n-- PostgreSQL database dump complete\n--\n")))
+}
+
+func (validateTestSuite *ValidateTestSuite) TestRedisRDB() {
+	validateTestSuite.NoError(validate.RedisRDB(bytes.NewReader(rdb("0011", crc64Jones))))
+	// checksums are disabled with "rdbchecksum no"
+	validateTestSuite.NoError(validate.RedisRDB(bytes.NewReader(rdb("0011", func([]byte) uint64 { return 0 }))))
+	// versions before 5 have no checksum
+	validateTestSuite.NoError(validate.RedisRDB(bytes.NewReader(rdb("0004", nil))))
+}
+
+func (validateTestSuite *ValidateTestSuite) TestRedisRDBInvalid() {
+	corrupted := rdb("0011", crc64Jones)
+	corrupted[15] = 'B'
+	truncated := rdb("0011", crc64Jones)[:15]
+
+	for name, content := range map[string][]byte{
+		"magic":     append([]byte("RADIS"), rdb("0011", crc64Jones)[5:]...),
+		"corrupted": corrupted,
+		"truncated": truncated,
+		"empty":     {},
+	} {
+		err := validate.RedisRDB(bytes.NewReader(content))
+		validateTestSuite.True(errors.Is(err, validate.ErrInvalidDump), name)
+	}
+}
+
+func (validateTestSuite *ValidateTestSuite) TestBSON() {
+	content := append(bsonDocument("brudi"), bsonDocument("mittwald")...)
+	validateTestSuite.NoError(validate.BSON(bytes.NewReader(content)))
+	validateTestSuite.NoError(validate.BSON(bytes.NewReader(nil)))
+
+	err := validate.BSON(bytes.NewReader(content[:len(content)-3]))
+	validateTestSuite.True(errors.Is(err, validate.ErrInvalidDump))
+}
+
+func (validateTestSuite *ValidateTestSuite) TestMongoArchive() {
+	archive := binary.LittleEndian.AppendUint32(nil, 0x8199e26d)
+	archive = append(archive, bsonDocument("header")...)
+	validateTestSuite.NoError(validate.MongoArchive(bytes.NewReader(archive)))
+
+	err := validate.MongoArchive(bytes.NewReader(bsonDocument("header")))
+	validateTestSuite.True(errors.Is(err, validate.ErrInvalidDump))
+}
+
+func TestValidateTestSuite(t *testing.T) {
+	suite.Run(t, new(ValidateTestSuite))
+}